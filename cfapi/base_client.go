@@ -13,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"golang.org/x/net/http2"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -145,7 +146,9 @@ func parseResponseBody(result *response, data interface{}) error {
 	return nil
 }
 
-func fetchExhaustively[T any](requestFn func(int) (*http.Response, error)) ([]*T, error) {
+// fetchExhaustively fetches every page of a listing. If concurrency is above 1, once the first page tells how many
+// results there are, up to concurrency of the remaining pages are fetched at once. Results keep the order of the pages.
+func fetchExhaustively[T any](requestFn func(int) (*http.Response, error), concurrency int) ([]*T, error) {
 	page := 0
 	var fullResponse []*T
 
@@ -162,10 +165,44 @@ func fetchExhaustively[T any](requestFn func(int) (*http.Response, error)) ([]*T
 			break
 		}
 
+		if lastPage := envelope.Pagination.lastPage(); concurrency > 1 && lastPage > page {
+			remaining, err := fetchPages[T](requestFn, page+1, lastPage, concurrency)
+			if err != nil {
+				return nil, err
+			}
+			return append(fullResponse, remaining...), nil
+		}
 	}
 	return fullResponse, nil
 }
 
+// fetchPages fetches the pages from first to last included, up to concurrency at a time.
+func fetchPages[T any](requestFn func(int) (*http.Response, error), first, last, concurrency int) ([]*T, error) {
+	pages := make([][]*T, last-first+1)
+	var errGroup errgroup.Group
+	errGroup.SetLimit(concurrency)
+	for page := first; page <= last; page++ {
+		page := page
+		errGroup.Go(func() error {
+			_, parsedBody, err := fetchPage[T](requestFn, page)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("Error Parsing page %d", page))
+			}
+			pages[page-first] = parsedBody
+			return nil
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+
+	var results []*T
+	for _, parsedBody := range pages {
+		results = append(results, parsedBody...)
+	}
+	return results, nil
+}
+
 func fetchPage[T any](requestFn func(int) (*http.Response, error), page int) (*response, []*T, error) {
 	pageResp, err := requestFn(page)
 	if err != nil {
@@ -199,6 +236,14 @@ type Pagination struct {
 	TotalCount int `json:"total_count,omitempty"`
 }
 
+// lastPage returns the number of the last page of results, or 0 if it's unknown.
+func (p Pagination) lastPage() int {
+	if p.PerPage <= 0 {
+		return 0
+	}
+	return (p.TotalCount + p.PerPage - 1) / p.PerPage
+}
+
 func (r *response) checkErrors() error {
 	if len(r.Errors) == 0 {
 		return nil
@@ -0,0 +1,62 @@
+package cfapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	ID int `json:"id"`
+}
+
+// pagedResults serves totalCount items, perPage at a time
+func pagedResults(totalCount, perPage int, requests *int32) func(int) (*http.Response, error) {
+	return func(page int) (*http.Response, error) {
+		atomic.AddInt32(requests, 1)
+		var items []string
+		for id := (page-1)*perPage + 1; id <= page*perPage && id <= totalCount; id++ {
+			items = append(items, fmt.Sprintf(`{"id":%d}`, id))
+		}
+		body := fmt.Sprintf(`{"success":true,"result":[%s],"result_info":{"count":%d,"page":%d,"per_page":%d,"total_count":%d}}`,
+			strings.Join(items, ","), len(items), page, perPage, totalCount)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+}
+
+func TestFetchExhaustively(t *testing.T) {
+	for _, concurrency := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			var requests int32
+			items, err := fetchExhaustively[testItem](pagedResults(23, 5, &requests), concurrency)
+			require.NoError(t, err)
+			require.Len(t, items, 23)
+			for i, item := range items {
+				assert.Equal(t, i+1, item.ID)
+			}
+			assert.Equal(t, int32(5), requests)
+		})
+	}
+}
+
+func TestFetchExhaustivelyPageError(t *testing.T) {
+	var requests int32
+	results := pagedResults(23, 5, &requests)
+	requestFn := func(page int) (*http.Response, error) {
+		if page == 3 {
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody}, nil
+		}
+		return results(page)
+	}
+	_, err := fetchExhaustively[testItem](requestFn, 4)
+	assert.ErrorContains(t, err, "page 3")
+}
@@ -141,8 +141,7 @@ type GetRouteByIpParams struct {
 func (r *RESTClient) ListRoutes(filter *IpRouteFilter) ([]*DetailedRoute, error) {
 	fetchFn := func(page int) (*http.Response, error) {
 		endpoint := r.baseEndpoints.accountRoutes
-		filter.Page(page)
-		endpoint.RawQuery = filter.Encode()
+		endpoint.RawQuery = filter.encodePage(page)
 		rsp, err := r.sendRequest("GET", endpoint, nil)
 
 		if err != nil {
@@ -154,7 +153,7 @@ func (r *RESTClient) ListRoutes(filter *IpRouteFilter) ([]*DetailedRoute, error)
 		}
 		return rsp, nil
 	}
-	return fetchExhaustively[DetailedRoute](fetchFn)
+	return fetchExhaustively[DetailedRoute](fetchFn, filter.fetchConcurrency)
 }
 
 // AddRoute calls the Tunnelstore POST endpoint for a given route.
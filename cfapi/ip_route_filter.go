@@ -53,7 +53,8 @@ var (
 
 // IpRouteFilter which routes get queried.
 type IpRouteFilter struct {
-	queryParams url.Values
+	queryParams      url.Values
+	fetchConcurrency int
 }
 
 // NewIpRouteFilterFromCLI parses CLI flags to discover which filters should get applied.
@@ -102,6 +103,9 @@ func NewIpRouteFilterFromCLI(c *cli.Context) (*IpRouteFilter, error) {
 	if maxFetch := c.Int("max-fetch-size"); maxFetch > 0 {
 		f.MaxFetchSize(uint(maxFetch))
 	}
+	if fetchConcurrency := c.Int("fetch-concurrency"); fetchConcurrency > 0 {
+		f.FetchConcurrency(uint(fetchConcurrency))
+	}
 
 	return f, nil
 }
@@ -167,6 +171,11 @@ func (f *IpRouteFilter) MaxFetchSize(max uint) {
 	f.queryParams.Set("per_page", strconv.Itoa(int(max)))
 }
 
+// FetchConcurrency sets how many pages of results can be fetched at once.
+func (f *IpRouteFilter) FetchConcurrency(concurrency uint) {
+	f.fetchConcurrency = int(concurrency)
+}
+
 func (f *IpRouteFilter) Page(page int) {
 	f.queryParams.Set("page", strconv.Itoa(page))
 }
@@ -174,3 +183,13 @@ func (f *IpRouteFilter) Page(page int) {
 func (f IpRouteFilter) Encode() string {
 	return f.queryParams.Encode()
 }
+
+// encodePage encodes the query for the given page without changing the filter, so pages can be fetched concurrently.
+func (f IpRouteFilter) encodePage(page int) string {
+	queryParams := make(url.Values, len(f.queryParams)+1)
+	for key, values := range f.queryParams {
+		queryParams[key] = values
+	}
+	queryParams.Set("page", strconv.Itoa(page))
+	return queryParams.Encode()
+}
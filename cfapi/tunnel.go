@@ -179,8 +179,7 @@ func (r *RESTClient) DeleteTunnel(tunnelID uuid.UUID, cascade bool) error {
 func (r *RESTClient) ListTunnels(filter *TunnelFilter) ([]*Tunnel, error) {
 	fetchFn := func(page int) (*http.Response, error) {
 		endpoint := r.baseEndpoints.accountLevel
-		filter.Page(page)
-		endpoint.RawQuery = filter.encode()
+		endpoint.RawQuery = filter.encodePage(page)
 		rsp, err := r.sendRequest("GET", endpoint, nil)
 		if err != nil {
 			return nil, errors.Wrap(err, "REST request failed")
@@ -192,7 +191,7 @@ func (r *RESTClient) ListTunnels(filter *TunnelFilter) ([]*Tunnel, error) {
 		return rsp, nil
 	}
 
-	return fetchExhaustively[Tunnel](fetchFn)
+	return fetchExhaustively[Tunnel](fetchFn, filter.fetchConcurrency)
 }
 
 func (r *RESTClient) ListActiveClients(tunnelID uuid.UUID) ([]*ActiveClient, error) {
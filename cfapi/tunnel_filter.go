@@ -13,7 +13,8 @@ const (
 )
 
 type TunnelFilter struct {
-	queryParams url.Values
+	queryParams      url.Values
+	fetchConcurrency int
 }
 
 func NewTunnelFilter() *TunnelFilter {
@@ -50,6 +51,11 @@ func (f *TunnelFilter) MaxFetchSize(max uint) {
 	f.queryParams.Set("per_page", strconv.Itoa(int(max)))
 }
 
+// FetchConcurrency sets how many pages of results can be fetched at once.
+func (f *TunnelFilter) FetchConcurrency(concurrency uint) {
+	f.fetchConcurrency = int(concurrency)
+}
+
 func (f *TunnelFilter) Page(page int) {
 	f.queryParams.Set("page", strconv.Itoa(page))
 }
@@ -57,3 +63,13 @@ func (f *TunnelFilter) Page(page int) {
 func (f TunnelFilter) encode() string {
 	return f.queryParams.Encode()
 }
+
+// encodePage encodes the query for the given page without changing the filter, so pages can be fetched concurrently.
+func (f TunnelFilter) encodePage(page int) string {
+	queryParams := make(url.Values, len(f.queryParams)+1)
+	for key, values := range f.queryParams {
+		queryParams[key] = values
+	}
+	queryParams.Set("page", strconv.Itoa(page))
+	return queryParams.Encode()
+}
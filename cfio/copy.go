@@ -7,10 +7,20 @@ import (
 
 const defaultBufferSize = 16 * 1024
 
-var bufferPool = sync.Pool{
-	New: func() interface{} {
-		return make([]byte, defaultBufferSize)
-	},
+var bufferPool = newBufferPool(defaultBufferSize)
+
+func newBufferPool(size int) *sync.Pool {
+	return &sync.Pool{
+		New: func() interface{} {
+			return make([]byte, size)
+		},
+	}
+}
+
+// SetBufferSize changes the size of the buffers used by Copy. It is not safe to call while copies are in progress, so
+// it should only be called at startup.
+func SetBufferSize(size int) {
+	bufferPool = newBufferPool(size)
 }
 
 func Copy(dst io.Writer, src io.Reader) (written int64, err error) {
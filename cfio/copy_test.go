@@ -0,0 +1,45 @@
+package cfio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// plainReader hides the WriterTo of the underlying reader, so that Copy uses its buffer.
+type plainReader struct {
+	io.Reader
+}
+
+// chunkWriter records the size of every write it gets.
+type chunkWriter struct {
+	writes []int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return len(p), nil
+}
+
+func TestCopyBufferSize(t *testing.T) {
+	defer SetBufferSize(defaultBufferSize)
+
+	data := bytes.Repeat([]byte("a"), 10*1024)
+	for _, size := range []int{1024, defaultBufferSize} {
+		SetBufferSize(size)
+		dst := &chunkWriter{}
+		written, err := Copy(dst, plainReader{bytes.NewReader(data)})
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), written)
+		for _, n := range dst.writes {
+			require.LessOrEqual(t, n, size)
+		}
+		if size < len(data) {
+			require.Len(t, dst.writes, len(data)/size)
+		} else {
+			require.Len(t, dst.writes, 1)
+		}
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"text/template"
 	"time"
 
-	"github.com/getsentry/sentry-go"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli/v2"
@@ -28,6 +27,7 @@ import (
 const (
 	appURLFlag         = "app"
 	loginQuietFlag     = "quiet"
+	tokenLoginFlag     = "login"
 	sshHostnameFlag    = "hostname"
 	sshDestinationFlag = "destination"
 	sshURLFlag         = "url"
@@ -82,6 +82,9 @@ func Commands() []*cli.Command {
 			per-user and by application. With Cloudflare Access, only authenticated users with the required permissions are
 			able to reach sensitive resources. The commands provided here allow you to interact with Access protected
 			applications from the command line.`,
+			Flags: []cli.Flag{
+				cliutil.NewDisableTelemetryFlag(false),
+			},
 			Subcommands: []*cli.Command{
 				{
 					Name:      "login",
@@ -118,15 +121,23 @@ func Commands() []*cli.Command {
 					SkipFlagParsing: true,
 				},
 				{
-					Name:        "token",
-					Action:      cliutil.Action(generateToken),
-					Usage:       "token <url of access application>",
-					ArgsUsage:   "url of Access application",
-					Description: `The token subcommand produces a JWT which can be used to authenticate requests.`,
+					Name:      "token",
+					Action:    cliutil.Action(generateToken),
+					Usage:     "token <url of access application>",
+					ArgsUsage: "url of Access application",
+					Description: `The token subcommand produces a JWT which can be used to authenticate requests.
+					By default it only prints the token stored by a previous login. With --login, the stored token is
+					first checked against the application and, if there is none or it is no longer valid, the login flow
+					is started to fetch a new one. A valid stored token is reused without any interaction, which makes
+					it suitable for scripts, e.g. curl -H "cf-access-token: $(cloudflared access token --login --app <url>)".`,
 					Flags: []cli.Flag{
 						&cli.StringFlag{
 							Name: appURLFlag,
 						},
+						&cli.BoolFlag{
+							Name:  tokenLoginFlag,
+							Usage: "log in to fetch a new token if there is no valid token stored for the application",
+						},
 					},
 				},
 				{
@@ -231,10 +242,7 @@ func Commands() []*cli.Command {
 
 // login pops up the browser window to do the actual login and JWT generation
 func login(c *cli.Context) error {
-	err := sentry.Init(sentry.ClientOptions{
-		Dsn:     sentryDSN,
-		Release: c.App.Version,
-	})
+	err := cliutil.InitSentry(c, sentryDSN)
 	if err != nil {
 		return err
 	}
@@ -283,10 +291,7 @@ func login(c *cli.Context) error {
 
 // curl provides a wrapper around curl, passing Access JWT along in request
 func curl(c *cli.Context) error {
-	err := sentry.Init(sentry.ClientOptions{
-		Dsn:     sentryDSN,
-		Release: c.App.Version,
-	})
+	err := cliutil.InitSentry(c, sentryDSN)
 	if err != nil {
 		return err
 	}
@@ -368,10 +373,7 @@ func getAppURLFromArgs(c *cli.Context) (*url.URL, error) {
 
 // token dumps provided token to stdout
 func generateToken(c *cli.Context) error {
-	err := sentry.Init(sentry.ClientOptions{
-		Dsn:     sentryDSN,
-		Release: c.App.Version,
-	})
+	err := cliutil.InitSentry(c, sentryDSN)
 	if err != nil {
 		return err
 	}
@@ -385,6 +387,14 @@ func generateToken(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if c.Bool(tokenLoginFlag) {
+		// Reuses the stored token if the application accepts it, otherwise starts the login flow
+		log := logger.CreateLoggerFromContext(c, logger.EnableTerminalLog)
+		if err := verifyTokenAtEdge(appURL, appInfo, c, log); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to fetch a token for provided application.")
+			return err
+		}
+	}
 	tok, err := token.GetAppTokenIfExists(appInfo)
 	if err != nil || tok == "" {
 		fmt.Fprintln(os.Stderr, "Unable to find token for provided application. Please run login command to generate token.")
@@ -29,6 +29,13 @@ func ConfigureLoggingFlags(shouldHide bool) []cli.Flag {
 			EnvVars: []string{"TUNNEL_PROTO_LOGLEVEL", "TUNNEL_TRANSPORT_LOGLEVEL"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    logger.LogFormatFlag,
+			Value:   logger.LogFormatDefault,
+			Usage:   "Format of the logs written to the terminal {default, json, logfmt}. Log files are always written as JSON.",
+			EnvVars: []string{"TUNNEL_LOG_FORMAT"},
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    logger.LogFileFlag,
 			Usage:   "Save application log to this file for reporting issues.",
@@ -41,6 +48,12 @@ func ConfigureLoggingFlags(shouldHide bool) []cli.Flag {
 			EnvVars: []string{"TUNNEL_LOGDIRECTORY"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    logger.AuditLogFlag,
+			Usage:   "Save access-denied decisions to this file, apart from the application log, for auditing purposes.",
+			EnvVars: []string{"TUNNEL_AUDIT_LOG"},
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    "trace-output",
 			Usage:   "Name of trace output file, generated when cloudflared stops.",
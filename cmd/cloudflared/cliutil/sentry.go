@@ -0,0 +1,32 @@
+package cliutil
+
+import (
+	"github.com/getsentry/sentry-go"
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+)
+
+// DisableTelemetryFlag stops cloudflared from sending error reports to Sentry.
+const DisableTelemetryFlag = "disable-telemetry"
+
+// NewDisableTelemetryFlag returns the flag that disables error reporting.
+func NewDisableTelemetryFlag(shouldHide bool) cli.Flag {
+	return altsrc.NewBoolFlag(&cli.BoolFlag{
+		Name:    DisableTelemetryFlag,
+		Usage:   "Never send crash and error reports to Cloudflare (Sentry).",
+		EnvVars: []string{"TUNNEL_DISABLE_TELEMETRY"},
+		Hidden:  shouldHide,
+	})
+}
+
+// InitSentry configures Sentry to send error reports to dsn, unless --disable-telemetry is set. Sentry is only ever
+// configured here: without a client, every capture through the sentry package is a no-op, so nothing is sent.
+func InitSentry(c *cli.Context, dsn string) error {
+	if c.Bool(DisableTelemetryFlag) {
+		return nil
+	}
+	return sentry.Init(sentry.ClientOptions{
+		Dsn:     dsn,
+		Release: c.App.Version,
+	})
+}
@@ -0,0 +1,20 @@
+package cliutil
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+func TestInitSentryDisabled(t *testing.T) {
+	flagSet := flag.NewFlagSet(t.Name(), flag.PanicOnError)
+	flagSet.Bool(DisableTelemetryFlag, false, "")
+	require.NoError(t, flagSet.Parse([]string{"--" + DisableTelemetryFlag}))
+	c := cli.NewContext(cli.NewApp(), flagSet, nil)
+
+	require.NoError(t, InitSentry(c, "https://key@sentry.example.com/1"))
+	require.Nil(t, sentry.CurrentHub().Client(), "no Sentry client should be configured")
+}
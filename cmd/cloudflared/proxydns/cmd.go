@@ -47,6 +47,11 @@ func Command(hidden bool) *cli.Command {
 				Value:   cli.NewStringSlice("https://1.1.1.1/dns-query", "https://1.0.0.1/dns-query"),
 				EnvVars: []string{"TUNNEL_DNS_UPSTREAM"},
 			},
+			&cli.StringSliceFlag{
+				Name:    "upstream-header",
+				Usage:   "Header to add to every request to the upstreams, in the \"Name: Value\" form, e.g. to authenticate with a private DNS over HTTPS resolver. You can specify multiple headers.",
+				EnvVars: []string{"TUNNEL_DNS_UPSTREAM_HEADER"},
+			},
 			&cli.StringSliceFlag{
 				Name:    "bootstrap",
 				Usage:   "bootstrap endpoint URL, you can specify multiple endpoints for redundancy.",
@@ -59,6 +64,21 @@ func Command(hidden bool) *cli.Command {
 				Value:   tunneldns.MaxUpstreamConnsDefault,
 				EnvVars: []string{"TUNNEL_DNS_MAX_UPSTREAM_CONNS"},
 			},
+			&cli.IntFlag{
+				Name:    "max-qps",
+				Usage:   "Maximum DNS queries per second accepted from each client IP, queries over the limit are refused. Setting to 0 means unlimited.",
+				EnvVars: []string{"TUNNEL_DNS_MAX_QPS"},
+			},
+			&cli.IntFlag{
+				Name:    "max-qps-burst",
+				Usage:   "Maximum burst of DNS queries accepted from each client IP when max-qps is set. Defaults to max-qps.",
+				EnvVars: []string{"TUNNEL_DNS_MAX_QPS_BURST"},
+			},
+			&cli.DurationFlag{
+				Name:    "serve-stale",
+				Usage:   "When none of the upstreams can be reached, answer with cached answers that expired up to this long ago instead of failing. Setting to 0 disables it.",
+				EnvVars: []string{"TUNNEL_DNS_SERVE_STALE"},
+			},
 		},
 		ArgsUsage: " ", // can't be the empty string or we get the default output
 		Hidden:    hidden,
@@ -76,15 +96,24 @@ func Run(c *cli.Context) error {
 
 	go metrics.ServeMetrics(metricsListener, context.Background(), metrics.Config{}, log)
 
-	listener, err := tunneldns.CreateListener(
-		c.String("address"),
+	upstreamHeaders, err := tunneldns.ParseUpstreamHeaders(c.StringSlice("upstream-header"))
+	if err != nil {
+		log.Err(err).Msg("Invalid upstream-header")
+		return err
+	}
+
+	listener, err := tunneldns.CreateListener(tunneldns.ListenerConfig{
+		Address: c.String("address"),
 		// Note TUN-3758 , we use Int because UInt is not supported with altsrc
-		uint16(c.Int("port")),
-		c.StringSlice("upstream"),
-		c.StringSlice("bootstrap"),
-		c.Int("max-upstream-conns"),
-		log,
-	)
+		Port:                   uint16(c.Int("port")),
+		Upstreams:              c.StringSlice("upstream"),
+		Bootstraps:             c.StringSlice("bootstrap"),
+		UpstreamHeaders:        upstreamHeaders,
+		MaxUpstreamConnections: c.Int("max-upstream-conns"),
+		MaxQPS:                 c.Int("max-qps"),
+		MaxQPSBurst:            c.Int("max-qps-burst"),
+		ServeStale:             c.Duration("serve-stale"),
+	}, log)
 
 	if err != nil {
 		log.Err(err).Msg("Failed to create the listeners")
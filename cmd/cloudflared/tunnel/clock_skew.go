@@ -0,0 +1,56 @@
+package tunnel
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// maxClockSkew is how far the host clock may drift from Cloudflare's before token validation and TLS start to fail.
+const maxClockSkew = time.Minute
+
+// checkClockSkew compares the host clock against the Date header of a request to Cloudflare and warns when they are
+// too far apart. The check is best-effort: if Cloudflare can't be reached, it is only logged at debug level.
+func checkClockSkew(ctx context.Context, url string, log *zerolog.Logger) {
+	skew, err := clockSkew(ctx, url)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to check the host clock against Cloudflare")
+		return
+	}
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		log.Warn().Msgf("The host clock is off by %s compared to Cloudflare. This will cause TLS handshakes and "+
+			"token validation to fail, please sync the host clock (e.g. with NTP)", skew.Round(time.Second))
+	}
+}
+
+// clockSkew returns how far the host clock is ahead of the server behind url. The server time is compared to the
+// midpoint of the request to account for the round trip.
+func clockSkew(ctx context.Context, url string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to build clock skew request")
+	}
+	sent := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to request the server time")
+	}
+	defer resp.Body.Close()
+	received := time.Now()
+
+	date := resp.Header.Get("Date")
+	if date == "" {
+		return 0, errors.New("response has no Date header")
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse Date header %q", date)
+	}
+	hostTime := sent.Add(received.Sub(sent) / 2)
+	return hostTime.Sub(serverTime), nil
+}
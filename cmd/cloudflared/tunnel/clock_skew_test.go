@@ -0,0 +1,40 @@
+package tunnel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockSkew(t *testing.T) {
+	offset := time.Hour
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-offset).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	skew, err := clockSkew(context.Background(), server.URL)
+	require.NoError(t, err)
+	// The Date header only has a one second precision
+	assert.InDelta(t, offset.Seconds(), skew.Seconds(), 2)
+
+	offset = 0
+	skew, err = clockSkew(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Less(t, skew.Abs(), 2*time.Second)
+}
+
+func TestClockSkewWithoutDate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+	}))
+	defer server.Close()
+
+	_, err := clockSkew(context.Background(), server.URL)
+	assert.Error(t, err)
+}
@@ -7,14 +7,15 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"runtime/trace"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/coreos/go-systemd/v22/daemon"
 	"github.com/facebookgo/grace/gracenet"
-	"github.com/getsentry/sentry-go"
 	"github.com/google/uuid"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
@@ -23,6 +24,7 @@ import (
 	"github.com/urfave/cli/v2/altsrc"
 
 	"github.com/cloudflare/cloudflared/cfapi"
+	"github.com/cloudflare/cloudflared/cfio"
 	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
 	"github.com/cloudflare/cloudflared/cmd/cloudflared/proxydns"
 	"github.com/cloudflare/cloudflared/cmd/cloudflared/updater"
@@ -37,6 +39,9 @@ import (
 	"github.com/cloudflare/cloudflared/management"
 	"github.com/cloudflare/cloudflared/metrics"
 	"github.com/cloudflare/cloudflared/orchestration"
+	"github.com/cloudflare/cloudflared/proxy"
+	quicpogs "github.com/cloudflare/cloudflared/quic"
+	v3 "github.com/cloudflare/cloudflared/quic/v3"
 	"github.com/cloudflare/cloudflared/signal"
 	"github.com/cloudflare/cloudflared/supervisor"
 	"github.com/cloudflare/cloudflared/tlsconfig"
@@ -50,6 +55,11 @@ const (
 
 	// ha-Connections specifies how many connections to make to the edge
 	haConnectionsFlag = "ha-connections"
+	// haConnectionsIntervalFlag is the delay between starting each connection after the first one
+	haConnectionsIntervalFlag = "ha-connections-interval"
+
+	// maxProcsFlag overrides the GOMAXPROCS detected from the CPU quota
+	maxProcsFlag = "max-procs"
 
 	// sshPortFlag is the port on localhost the cloudflared ssh server will run on
 	sshPortFlag = "local-ssh-port"
@@ -87,11 +97,41 @@ const (
 	// writeStreamTimeout sets if we should have a timeout when writing data to a stream towards the destination (edge/origin).
 	writeStreamTimeout = "write-stream-timeout"
 
+	// slowRequestThreshold is the duration above which proxied requests are logged as slow.
+	slowRequestThreshold = "slow-request-threshold"
+
+	// streamCopyBufferSize sets the size of the buffers used to copy stream data between the edge and the origin.
+	streamCopyBufferSize = "stream-copy-buffer-size"
+
+	// exitAfterRequestsFlag makes cloudflared shut down gracefully after proxying this number of requests.
+	exitAfterRequestsFlag = "exit-after-requests"
+
+	// onceFlag is a shorthand for --exit-after-requests 1.
+	onceFlag = "once"
+
+	// metricsNamespaceFlag prefixes the names of the metrics exported on the metrics server.
+	metricsNamespaceFlag = "metrics-namespace"
+
+	// connectionMaxLifetime sets how long an edge connection may live before it is recycled.
+	connectionMaxLifetime = "connection-max-lifetime"
+
 	// quicDisablePathMTUDiscovery sets if QUIC should not perform PTMU discovery and use a smaller (safe) packet size.
 	// Packets will then be at most 1252 (IPv4) / 1232 (IPv6) bytes in size.
 	// Note that this may result in packet drops for UDP proxying, since we expect being able to send at least 1280 bytes of inner packets.
 	quicDisablePathMTUDiscovery = "quic-disable-pmtu-discovery"
 
+	// quicInitialMTU sets the path MTU that QUIC assumes before PMTU discovery, if any, finds a larger one.
+	// The initial packet size is derived from it by removing the IP and UDP header sizes.
+	quicInitialMTU = "quic-initial-mtu"
+
+	// quicMaxUDPPayloadSize limits the size of the origin UDP payloads proxied to the edge in QUIC datagrams.
+	// Larger payloads are dropped and counted in cloudflared_udp_dropped_datagrams_total{reason="payload_too_large"}.
+	quicMaxUDPPayloadSize = "quic-max-udp-payload-size"
+
+	// quicMaxIdleTimeout sets how long a QUIC connection may go without receiving anything before it is considered dead.
+	// Keepalives are sent independently of it, so it only matters when they are lost.
+	quicMaxIdleTimeout = "quic-max-idle-timeout"
+
 	// quicConnLevelFlowControlLimit controls the max flow control limit allocated for a QUIC connection. This controls how much data is the
 	// receiver willing to buffer. Once the limit is reached, the sender will send a DATA_BLOCKED frame to indicate it has more data to write,
 	// but it's blocked by flow control
@@ -135,7 +175,12 @@ var (
 		"autoupdate-freq",
 		"no-autoupdate",
 		"metrics",
+		"metrics-namespace",
 		"pidfile",
+		"status-file",
+		"ready-file",
+		"max-procs",
+		"disable-telemetry",
 		"url",
 		"hello-world",
 		"socks5",
@@ -169,14 +214,19 @@ var (
 		"proxy-port",
 		"loglevel",
 		"transport-loglevel",
+		"log-format",
 		"logfile",
 		"log-directory",
+		"audit-log",
 		"trace-output",
 		"proxy-dns",
 		"proxy-dns-port",
 		"proxy-dns-address",
 		"proxy-dns-upstream",
 		"proxy-dns-max-upstream-conns",
+		"proxy-dns-max-qps",
+		"proxy-dns-max-qps-burst",
+		"proxy-dns-serve-stale",
 		"proxy-dns-bootstrap",
 		"is-autoupdated",
 		"edge",
@@ -184,6 +234,7 @@ var (
 		"edge-ip-version",
 		"edge-bind-address",
 		"cacert",
+		"edge-insecure-skip-verify",
 		"hostname",
 		"id",
 		"lb-pool",
@@ -194,10 +245,20 @@ var (
 		"heartbeat-count",
 		"max-edge-addr-retries",
 		"retries",
+		"initial-connect-retries",
 		"ha-connections",
+		"ha-connections-interval",
 		"rpc-timeout",
 		"write-stream-timeout",
+		"slow-request-threshold",
+		"stream-copy-buffer-size",
+		"exit-after-requests",
+		"once",
+		"connection-max-lifetime",
 		"quic-disable-pmtu-discovery",
+		"quic-initial-mtu",
+		"quic-max-udp-payload-size",
+		"quic-max-idle-timeout",
 		"quic-connection-level-flow-control-limit",
 		"quic-stream-level-flow-control-limit",
 		"label",
@@ -210,9 +271,11 @@ var (
 		"ui",
 		"quick-service",
 		"max-fetch-size",
+		"fetch-concurrency",
 		"post-quantum",
 		"management-diagnostics",
 		"protocol",
+		"require-protocol",
 		"overwrite-dns",
 		"help",
 	}
@@ -246,6 +309,7 @@ func Commands() []*cli.Command {
 		buildTunnelCommand(subcommands),
 		// for compatibility, allow following as top-level subcommands
 		buildLoginSubcommand(true),
+		buildFeaturesCommand(),
 		cliutil.RemovedCommand("db-connect"),
 	}
 }
@@ -391,10 +455,7 @@ func StartServer(
 	namedTunnel *connection.TunnelProperties,
 	log *zerolog.Logger,
 ) error {
-	err := sentry.Init(sentry.ClientOptions{
-		Dsn:     sentryDSN,
-		Release: c.App.Version,
-	})
+	err := cliutil.InitSentry(c, sentryDSN)
 	if err != nil {
 		return err
 	}
@@ -407,6 +468,14 @@ func StartServer(
 		log.Info().Msg(config.ErrNoConfigFile.Error())
 	}
 
+	if maxProcs := c.Int(maxProcsFlag); maxProcs < 0 {
+		return fmt.Errorf("--%s must not be negative", maxProcsFlag)
+	} else if maxProcs > 0 {
+		// Applied after automaxprocs, which set GOMAXPROCS at startup
+		previous := runtime.GOMAXPROCS(maxProcs)
+		log.Info().Msgf("GOMAXPROCS set to %d instead of %d", maxProcs, previous)
+	}
+
 	if c.IsSet("trace-output") {
 		tmpTraceFile, err := os.CreateTemp("", "trace")
 		if err != nil {
@@ -447,7 +516,15 @@ func StartServer(
 	ctx, cancel := context.WithCancel(c.Context)
 	defer cancel()
 
-	go waitForSignal(graceShutdownC, log)
+	var requestsDoneC <-chan struct{}
+	maxRequests := exitAfterRequests(c)
+	if maxRequests < 0 {
+		return fmt.Errorf("--%s must not be negative, got %d", exitAfterRequestsFlag, maxRequests)
+	}
+	if maxRequests > 0 {
+		requestsDoneC = proxy.NotifyAfterRequests(int64(maxRequests))
+	}
+	go waitForSignal(graceShutdownC, requestsDoneC, log)
 
 	if c.IsSet("proxy-dns") {
 		dnsReadySignal := make(chan struct{})
@@ -465,6 +542,17 @@ func StartServer(
 	if c.IsSet("pidfile") {
 		go writePidFile(connectedSignal, c.String("pidfile"), log)
 	}
+	if path := c.String("ready-file"); path != "" {
+		expandedPath, err := homedir.Expand(path)
+		if err != nil {
+			return errors.Wrap(err, "Unable to expand the path, try to use absolute path in --ready-file")
+		}
+		readyFile := newReadyFile(expandedPath, log)
+		defer readyFile.remove()
+		go readyFile.writeWhenReady(connectedSignal, ctx.Done())
+	}
+
+	go checkClockSkew(ctx, c.String("api-url"), log)
 
 	// update needs to be after DNS proxy is up to resolve equinox server address
 	wg.Add(1)
@@ -480,11 +568,17 @@ func StartServer(
 	if dnsProxyStandAlone(c, namedTunnel) {
 		connectedSignal.Notify()
 		// no grace period, handle SIGINT/SIGTERM immediately
-		return waitToShutdown(&wg, cancel, errC, graceShutdownC, 0, log)
+		return waitToShutdown(&wg, cancel, errC, graceShutdownC, requestsDoneC, 0, log)
 	}
 
 	logTransport := logger.CreateTransportLoggerFromContext(c, logger.EnableTerminalLog)
 
+	if auditLogPath := c.String(logger.AuditLogFlag); auditLogPath != "" {
+		if err := logger.InitAuditLogger(auditLogPath); err != nil {
+			return errors.Wrap(err, "Error creating the audit log")
+		}
+	}
+
 	observer := connection.NewObserver(log, logTransport)
 
 	// Send Quick Tunnel URL to UI if applicable
@@ -510,6 +604,14 @@ func StartServer(
 		}
 	}
 
+	if statusFile := c.String("status-file"); statusFile != "" {
+		expandedPath, err := homedir.Expand(statusFile)
+		if err != nil {
+			return errors.Wrap(err, "Unable to expand the path, try to use absolute path in --status-file")
+		}
+		observer.RegisterSink(newStatusFileWriter(expandedPath, tunnelConfig.NamedTunnel.Credentials.TunnelID, clientID, log))
+	}
+
 	// Disable ICMP packet routing for quick tunnels
 	if quickTunnelURL != "" {
 		tunnelConfig.ICMPRouterServer = nil
@@ -529,7 +631,7 @@ func StartServer(
 			c.Bool("management-diagnostics"),
 			serviceIP,
 			clientID,
-			c.String(connectorLabelFlag),
+			expandConnectorLabel(c.String(connectorLabelFlag)),
 			logger.ManagementLogger.Log,
 			logger.ManagementLogger,
 		)
@@ -540,6 +642,18 @@ func StartServer(
 		return err
 	}
 
+	bufferSize := c.Int(streamCopyBufferSize)
+	if bufferSize <= 0 {
+		return fmt.Errorf("--%s must be greater than 0, got %d", streamCopyBufferSize, bufferSize)
+	}
+	cfio.SetBufferSize(bufferSize)
+
+	if namespace := c.String(metricsNamespaceFlag); namespace != "" {
+		if err := metrics.ValidateNamespace(namespace); err != nil {
+			return err
+		}
+	}
+
 	metricsListener, err := metrics.CreateMetricsListener(&listeners, c.String("metrics"))
 	if err != nil {
 		log.Err(err).Msg("Error opening metrics server listener")
@@ -553,6 +667,7 @@ func StartServer(
 		defer wg.Done()
 		tracker := tunnelstate.NewConnTracker(log)
 		observer.RegisterSink(tracker)
+		go logSnapshotOnSignal(ctx, tracker, log)
 
 		ipv4, ipv6, err := determineICMPSources(c, log)
 		sources := make([]string, 0)
@@ -561,7 +676,7 @@ func StartServer(
 			sources = append(sources, ipv6.String())
 		}
 
-		readinessServer := metrics.NewReadyServer(clientID, tracker)
+		readinessServer := metrics.NewReadyServer(clientID, tracker, orchestrator)
 		cliFlags := nonSecretCliFlags(log, c, nonSecretFlagsList)
 		diagnosticHandler := diagnostic.NewDiagnosticHandler(
 			log,
@@ -578,6 +693,7 @@ func StartServer(
 			DiagnosticHandler:   diagnosticHandler,
 			QuickTunnelHostname: quickTunnelURL,
 			Orchestrator:        orchestrator,
+			Namespace:           c.String(metricsNamespaceFlag),
 		}
 		errC <- metrics.ServeMetrics(metricsListener, ctx, metricsConfig, log)
 	}()
@@ -601,31 +717,67 @@ func StartServer(
 	if err != nil {
 		return err
 	}
-	return waitToShutdown(&wg, cancel, errC, graceShutdownC, gracePeriod, log)
+	return waitToShutdown(&wg, cancel, errC, graceShutdownC, requestsDoneC, gracePeriod, log)
+}
+
+const (
+	shutdownTriggerError    = "error"
+	shutdownTriggerSignal   = "signal"
+	shutdownTriggerRequests = "exit_after_requests"
+
+	drainResultComplete           = "complete"
+	drainResultGracePeriodExpired = "grace_period_expired"
+	drainResultSkipped            = "skipped"
+)
+
+// exitAfterRequests returns the number of requests after which cloudflared should shut down, or 0 to keep running.
+func exitAfterRequests(c *cli.Context) int {
+	if c.Bool(onceFlag) {
+		return 1
+	}
+	return c.Int(exitAfterRequestsFlag)
 }
 
 func waitToShutdown(wg *sync.WaitGroup,
 	cancelServerContext func(),
 	errC <-chan error,
 	graceShutdownC <-chan struct{},
+	requestsDoneC <-chan struct{},
 	gracePeriod time.Duration,
 	log *zerolog.Logger,
 ) error {
 	var err error
+	trigger := shutdownTriggerError
+	drainResult := drainResultSkipped
+	var drainDuration time.Duration
 	select {
 	case err = <-errC:
 		log.Error().Err(err).Msg("Initiating shutdown")
 	case <-graceShutdownC:
+		// waitForSignal only closes graceShutdownC for --exit-after-requests once requestsDoneC is closed
+		select {
+		case <-requestsDoneC:
+			trigger = shutdownTriggerRequests
+		default:
+			trigger = shutdownTriggerSignal
+		}
 		log.Debug().Msg("Graceful shutdown signalled")
 		if gracePeriod > 0 {
+			drainStart := time.Now()
 			// wait for either grace period or service termination
 			select {
 			case <-time.Tick(gracePeriod):
+				drainResult = drainResultGracePeriodExpired
 			case <-errC:
+				drainResult = drainResultComplete
 			}
+			drainDuration = time.Since(drainStart)
 		}
 	}
 
+	// requests still in flight at this point are aborted
+	activeRequests := proxy.ActiveRequests()
+
 	// stop server context
 	cancelServerContext()
 
@@ -643,6 +795,15 @@ func waitToShutdown(wg *sync.WaitGroup,
 	wg.Wait()
 	close(stopDiscarding)
 
+	log.Info().
+		Err(err).
+		Str("trigger", trigger).
+		Str("drain", drainResult).
+		Dur("gracePeriod", gracePeriod).
+		Dur("drainDuration", drainDuration).
+		Int64("abortedRequests", activeRequests).
+		Msg("Shutdown complete")
+
 	return err
 }
 
@@ -692,6 +853,20 @@ func addPortIfMissing(uri *url.URL, port int) string {
 	return fmt.Sprintf("%s:%d", uri.Hostname(), port)
 }
 
+// expandConnectorLabel replaces the {hostname} and {pid} placeholders of the connector label, so that a single
+// configuration deployed on several hosts still yields distinguishable labels.
+func expandConnectorLabel(label string) string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		// Leave the placeholder in, it's more helpful than an empty label
+		hostname = "{hostname}"
+	}
+	return strings.NewReplacer(
+		"{hostname}", hostname,
+		"{pid}", strconv.Itoa(os.Getpid()),
+	).Replace(label)
+}
+
 func tunnelFlags(shouldHide bool) []cli.Flag {
 	flags := configureCloudflaredFlags(shouldHide)
 	flags = append(flags, configureProxyFlags(shouldHide)...)
@@ -730,9 +905,18 @@ func tunnelFlags(shouldHide bool) []cli.Flag {
 			Hidden:  false,
 		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
-			Name:    tlsconfig.CaCertFlag,
-			Usage:   "Certificate Authority authenticating connections with Cloudflare's edge network.",
+			Name: tlsconfig.CaCertFlag,
+			Usage: "Path to a PEM bundle of the Certificate Authorities trusted for connections to Cloudflare's edge, " +
+				"instead of the system and Cloudflare ones. Only needed to connect to a non-production edge.",
 			EnvVars: []string{"TUNNEL_CACERT"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name: tlsconfig.EdgeInsecureSkipVerifyFlag,
+			Usage: "INSECURE, for testing only. Disables the verification of the certificate of Cloudflare's edge, " +
+				"which lets anyone on the network path impersonate it and read or alter all the traffic of the tunnel, " +
+				"including its credentials. Use --" + tlsconfig.CaCertFlag + " to trust a custom edge CA instead.",
+			EnvVars: []string{"TUNNEL_EDGE_INSECURE_SKIP_VERIFY"},
 			Hidden:  true,
 		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
@@ -818,16 +1002,37 @@ func tunnelFlags(shouldHide bool) []cli.Flag {
 			EnvVars: []string{"TUNNEL_RETRIES"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    "initial-connect-retries",
+			Value:   0,
+			Usage:   "Maximum number of retries for the first connection before it ever registers with Cloudflare's edge. cloudflared exits with an error once they are used up. Default is 0 which keeps retrying.",
+			EnvVars: []string{"TUNNEL_INITIAL_CONNECT_RETRIES"},
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewIntFlag(&cli.IntFlag{
 			Name:   haConnectionsFlag,
 			Value:  4,
 			Hidden: true,
 		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name: haConnectionsIntervalFlag,
+			Usage: "Delay between starting each connection to Cloudflare's edge once the first one is established. " +
+				"Increase it to bring the connections up gradually on slow or constrained links.",
+			EnvVars: []string{"TUNNEL_HA_CONNECTIONS_INTERVAL"},
+			Value:   time.Second,
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewDurationFlag(&cli.DurationFlag{
 			Name:   rpcTimeout,
 			Value:  5 * time.Second,
 			Hidden: true,
 		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:    slowRequestThreshold,
+			EnvVars: []string{"TUNNEL_SLOW_REQUEST_THRESHOLD"},
+			Usage:   "Log a warning with the method, host, path and duration of every request that takes longer than this to be proxied, e.g. 5s. Default is 0 which disables it.",
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewDurationFlag(&cli.DurationFlag{
 			Name:    writeStreamTimeout,
 			EnvVars: []string{"TUNNEL_STREAM_WRITE_TIMEOUT"},
@@ -835,6 +1040,20 @@ func tunnelFlags(shouldHide bool) []cli.Flag {
 			Value:   0 * time.Second,
 			Hidden:  true,
 		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    streamCopyBufferSize,
+			EnvVars: []string{"TUNNEL_STREAM_COPY_BUFFER_SIZE"},
+			Usage:   "Size in bytes of the buffers used to copy stream data (websocket, TCP and other streams) between the edge and the origin. Larger buffers can improve throughput of bulk transfers at the cost of memory per stream. Buffers are pooled and reused across streams.",
+			Value:   16 * 1024,
+			Hidden:  true,
+		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:    connectionMaxLifetime,
+			EnvVars: []string{"TUNNEL_CONNECTION_MAX_LIFETIME"},
+			Usage:   "Use this option to recycle each connection to Cloudflare's edge after it has been up for this long, so that long-running connectors can move to a better data center. Connections are recycled at staggered times, up to 25% before the deadline, and each one is drained like on shutdown: it stops taking new requests and in-flight ones get the grace period to finish before it reconnects. Default is 0 which keeps connections for as long as they are up.",
+			Value:   0,
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewBoolFlag(&cli.BoolFlag{
 			Name:    quicDisablePathMTUDiscovery,
 			EnvVars: []string{"TUNNEL_DISABLE_QUIC_PMTU"},
@@ -842,6 +1061,27 @@ func tunnelFlags(shouldHide bool) []cli.Flag {
 			Value:   false,
 			Hidden:  true,
 		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    quicInitialMTU,
+			EnvVars: []string{"TUNNEL_QUIC_INITIAL_MTU"},
+			Usage:   fmt.Sprintf("Use this option to set the path MTU assumed by QUIC connections, for example when running over a VPN with a reduced MTU. Must be between %d and %d. Default is 0 which uses a packet size safe for a 1280 bytes MTU.", supervisor.MinQUICInitialMTU, supervisor.MaxQUICInitialMTU),
+			Value:   0,
+			Hidden:  true,
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    quicMaxUDPPayloadSize,
+			EnvVars: []string{"TUNNEL_QUIC_MAX_UDP_PAYLOAD_SIZE"},
+			Usage:   fmt.Sprintf("Use this option to lower the size of the UDP payloads from origins that are proxied to Cloudflare's edge in QUIC datagrams, for example when path MTU discovery is disabled or the path MTU is small. Larger payloads are dropped, the flow stays open, and the drops are counted in the cloudflared_udp_dropped_datagrams_total metric with the payload_too_large reason. Payloads that don't fit in the QUIC packets allowed by the path MTU are dropped the same way. Must be between %d and %d. Default is 0 which uses %d.", v3.MinDatagramPayloadLen, v3.MaxDatagramPayloadLen, v3.MaxDatagramPayloadLen),
+			Value:   0,
+			Hidden:  true,
+		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:    quicMaxIdleTimeout,
+			EnvVars: []string{"TUNNEL_QUIC_MAX_IDLE_TIMEOUT"},
+			Usage:   fmt.Sprintf("Use this option to change how long a QUIC connection to Cloudflare's edge may go without receiving any packet before it is closed, for example on networks that drop packets for long periods. Must be longer than the keepalive period of %s. The edge may still close the connection sooner if its own idle timeout is shorter.", quicpogs.MaxIdlePingPeriod),
+			Value:   quicpogs.MaxIdleTimeout,
+			Hidden:  true,
+		}),
 		altsrc.NewIntFlag(&cli.IntFlag{
 			Name:    quicConnLevelFlowControlLimit,
 			EnvVars: []string{"TUNNEL_QUIC_CONN_LEVEL_FLOW_CONTROL_LIMIT"},
@@ -858,7 +1098,7 @@ func tunnelFlags(shouldHide bool) []cli.Flag {
 		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:  connectorLabelFlag,
-			Usage: "Use this option to give a meaningful label to a specific connector. When a tunnel starts up, a connector id unique to the tunnel is generated. This is a uuid. To make it easier to identify a connector, we will use the hostname of the machine the tunnel is running on along with the connector ID. This option exists if one wants to have more control over what their individual connectors are called.",
+			Usage: "Use this option to give a meaningful label to a specific connector. When a tunnel starts up, a connector id unique to the tunnel is generated. This is a uuid. To make it easier to identify a connector, we will use the hostname of the machine the tunnel is running on along with the connector ID. This option exists if one wants to have more control over what their individual connectors are called. The {hostname} and {pid} placeholders are replaced with the hostname of the machine and the process ID of cloudflared.",
 			Value: "",
 		}),
 		altsrc.NewDurationFlag(&cli.DurationFlag{
@@ -922,6 +1162,12 @@ func tunnelFlags(shouldHide bool) []cli.Flag {
 			EnvVars: []string{"TUNNEL_MAX_FETCH_SIZE"},
 			Hidden:  true,
 		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    "fetch-concurrency",
+			Usage:   "The maximum number of pages of results that cloudflared fetches at once from Cloudflare API for listing operations. Pages are fetched one at a time by default.",
+			EnvVars: []string{"TUNNEL_FETCH_CONCURRENCY"},
+			Hidden:  true,
+		}),
 		altsrc.NewBoolFlag(&cli.BoolFlag{
 			Name:    "post-quantum",
 			Usage:   "When given creates an experimental post-quantum secure tunnel",
@@ -936,6 +1182,7 @@ func tunnelFlags(shouldHide bool) []cli.Flag {
 			Value:   true,
 		}),
 		selectProtocolFlag,
+		requireProtocolFlag,
 		overwriteDNSFlag,
 	}...)
 
@@ -951,6 +1198,12 @@ func configureCloudflaredFlags(shouldHide bool) []cli.Flag {
 			Value:  config.FindDefaultConfigPath(),
 			Hidden: shouldHide,
 		},
+		&cli.StringFlag{
+			Name:    config.OriginRequestDefaultsFlag,
+			Usage:   "Specifies a YAML file of originRequest settings, without the originRequest key, that apply to every ingress rule of the config file. Settings of a rule take precedence over the originRequest block of the config file, which takes precedence over this file.",
+			EnvVars: []string{"TUNNEL_ORIGIN_REQUEST_DEFAULTS"},
+			Hidden:  shouldHide,
+		},
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    credentials.OriginCertFlag,
 			Usage:   "Path to the certificate generated for your origin when you run cloudflared login.",
@@ -984,12 +1237,49 @@ and virtualized host network stacks from each other`,
 			EnvVars: []string{"TUNNEL_METRICS"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    metricsNamespaceFlag,
+			Usage:   "Prefix the name of every metric exported on the metrics server with this namespace, e.g. 'edge1' exports 'edge1_cloudflared_tunnel_ha_connections'. Useful to tell connectors apart when they are scraped into the same Prometheus. Default is no prefix.",
+			EnvVars: []string{"TUNNEL_METRICS_NAMESPACE"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    exitAfterRequestsFlag,
+			Usage:   "Shut down gracefully once this number of requests and TCP sessions have been proxied, e.g. for tunnels that serve a single interaction in tests. Requests that arrive in the meantime are still served during the grace period. Default is 0 which keeps running.",
+			EnvVars: []string{"TUNNEL_EXIT_AFTER_REQUESTS"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    onceFlag,
+			Usage:   "Shut down gracefully after proxying a single request or TCP session. Shorthand for --exit-after-requests 1.",
+			EnvVars: []string{"TUNNEL_ONCE"},
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    "pidfile",
 			Usage:   "Write the application's PID to this file after first successful connection.",
 			EnvVars: []string{"TUNNEL_PIDFILE"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "status-file",
+			Usage:   "Keep a JSON summary of the tunnel (pid, tunnel and connector IDs, active connections, protocol and when it last became ready) in this file, updated whenever a connection changes state.",
+			EnvVars: []string{"TUNNEL_STATUS_FILE"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "ready-file",
+			Usage:   "Create this empty file once the tunnel is connected and remove it on shutdown, so that other services can wait for it.",
+			EnvVars: []string{"TUNNEL_READY_FILE"},
+			Hidden:  shouldHide,
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    maxProcsFlag,
+			Usage:   "Maximum number of CPUs that cloudflared uses at the same time (GOMAXPROCS). By default it is derived from the CPU quota of the host or container.",
+			EnvVars: []string{"TUNNEL_MAX_PROCS"},
+			Hidden:  shouldHide,
+		}),
+		cliutil.NewDisableTelemetryFlag(shouldHide),
 	}
 }
 
@@ -1077,16 +1367,22 @@ func configureProxyFlags(shouldHide bool) []cli.Flag {
 		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    "unix-socket",
-			Usage:   "Path to unix socket to use instead of --url",
+			Usage:   "Path to unix socket to use instead of --url. To proxy to several unix sockets, use ingress rules with unix:<path> or unix+tls:<path> services.",
 			EnvVars: []string{"TUNNEL_UNIX_SOCKET"},
 			Hidden:  shouldHide,
 		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    tlsconfig.OriginCAPoolFlag,
-			Usage:   legacyTunnelFlag("Path to the CA for the certificate of your origin. This option should be used only if your certificate is not signed by Cloudflare."),
+			Usage:   legacyTunnelFlag("Path to the CA for the certificate of your origin, or to a directory of *.pem CA files. This option should be used only if your certificate is not signed by Cloudflare."),
 			EnvVars: []string{"TUNNEL_ORIGIN_CA_POOL"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    tlsconfig.OriginCAPoolPEMFlag,
+			Usage:   legacyTunnelFlag("PEM encoded CA certificates for the certificate of your origin, trusted in addition to the ones in --origin-ca-pool. Useful to pass the CA as an environment variable."),
+			EnvVars: []string{"TUNNEL_ORIGIN_CA_POOL_PEM"},
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewBoolFlag(&cli.BoolFlag{
 			Name:    ingress.NoTLSVerifyFlag,
 			Usage:   legacyTunnelFlag("Disables TLS verification of the certificate presented by your origin. Will allow any certificate from the origin to be accepted. Note: The connection from your machine to Cloudflare's Edge is still encrypted."),
@@ -1257,6 +1553,12 @@ func configureProxyDNSFlags(shouldHide bool) []cli.Flag {
 			EnvVars: []string{"TUNNEL_DNS_UPSTREAM"},
 			Hidden:  shouldHide,
 		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:    "proxy-dns-upstream-header",
+			Usage:   "Header to add to every request to the upstreams, in the \"Name: Value\" form, e.g. to authenticate with a private DNS over HTTPS resolver. You can specify multiple headers.",
+			EnvVars: []string{"TUNNEL_DNS_UPSTREAM_HEADER"},
+			Hidden:  shouldHide,
+		}),
 		altsrc.NewIntFlag(&cli.IntFlag{
 			Name:    "proxy-dns-max-upstream-conns",
 			Usage:   "Maximum concurrent connections to upstream. Setting to 0 means unlimited.",
@@ -1264,6 +1566,24 @@ func configureProxyDNSFlags(shouldHide bool) []cli.Flag {
 			Hidden:  shouldHide,
 			EnvVars: []string{"TUNNEL_DNS_MAX_UPSTREAM_CONNS"},
 		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    "proxy-dns-max-qps",
+			Usage:   "Maximum DNS queries per second accepted from each client IP, queries over the limit are refused. Setting to 0 means unlimited.",
+			Hidden:  shouldHide,
+			EnvVars: []string{"TUNNEL_DNS_MAX_QPS"},
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    "proxy-dns-max-qps-burst",
+			Usage:   "Maximum burst of DNS queries accepted from each client IP when proxy-dns-max-qps is set. Defaults to proxy-dns-max-qps.",
+			Hidden:  shouldHide,
+			EnvVars: []string{"TUNNEL_DNS_MAX_QPS_BURST"},
+		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:    "proxy-dns-serve-stale",
+			Usage:   "When none of the upstreams can be reached, answer with cached answers that expired up to this long ago instead of failing. Setting to 0 disables it.",
+			Hidden:  shouldHide,
+			EnvVars: []string{"TUNNEL_DNS_SERVE_STALE"},
+		}),
 		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
 			Name:  "proxy-dns-bootstrap",
 			Usage: "bootstrap endpoint URL, you can specify multiple endpoints for redundancy.",
@@ -1,6 +1,8 @@
 package tunnel
 
 import (
+	"os"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,3 +17,15 @@ func TestHostnameFromURI(t *testing.T) {
 	assert.Equal(t, "", hostnameFromURI("trash"))
 	assert.Equal(t, "", hostnameFromURI("https://awesomesauce.com"))
 }
+
+func TestExpandConnectorLabel(t *testing.T) {
+	hostname, err := os.Hostname()
+	assert.NoError(t, err)
+	pid := strconv.Itoa(os.Getpid())
+
+	assert.Equal(t, "", expandConnectorLabel(""))
+	assert.Equal(t, "web", expandConnectorLabel("web"))
+	assert.Equal(t, "web-"+hostname, expandConnectorLabel("web-{hostname}"))
+	assert.Equal(t, hostname+"-"+pid, expandConnectorLabel("{hostname}-{pid}"))
+	assert.Equal(t, "{unknown}", expandConnectorLabel("{unknown}"))
+}
@@ -25,6 +25,8 @@ import (
 	"github.com/cloudflare/cloudflared/features"
 	"github.com/cloudflare/cloudflared/ingress"
 	"github.com/cloudflare/cloudflared/orchestration"
+	quicpogs "github.com/cloudflare/cloudflared/quic"
+	v3 "github.com/cloudflare/cloudflared/quic/v3"
 	"github.com/cloudflare/cloudflared/supervisor"
 	"github.com/cloudflare/cloudflared/tlsconfig"
 	"github.com/cloudflare/cloudflared/tunnelrpc/pogs"
@@ -136,6 +138,11 @@ func prepareTunnelConfig(
 	tags = append(tags, pogs.Tag{Name: "ID", Value: clientID.String()})
 
 	transportProtocol := c.String("protocol")
+	requireProtocol := c.Bool("require-protocol")
+	if requireProtocol && transportProtocol == connection.HTTP2.String() {
+		// http2 is the last resort protocol: there is nothing to fall back from, nor a way to tell it is blocked
+		return nil, nil, fmt.Errorf("--require-protocol can't be used with --protocol %s", connection.HTTP2)
+	}
 
 	clientFeatures := features.Dedup(append(c.StringSlice("features"), features.DefaultFeatures...))
 
@@ -199,6 +206,13 @@ func prepareTunnelConfig(
 		}
 		edgeTLSConfigs[p] = edgeTLSConfig
 	}
+	if c.Bool(tlsconfig.EdgeInsecureSkipVerifyFlag) {
+		log.Warn().Msgf("--%s is set: the certificate of Cloudflare's edge will NOT be verified. Anyone on the network "+
+			"path can impersonate the edge and read or alter all the traffic of this tunnel. Never use it in production.",
+			tlsconfig.EdgeInsecureSkipVerifyFlag)
+	} else if c.IsSet(tlsconfig.CaCertFlag) {
+		log.Info().Msgf("Trusting only the Certificate Authorities in %s for connections to Cloudflare's edge", c.String(tlsconfig.CaCertFlag))
+	}
 
 	gracePeriod, err := gracePeriod(c)
 	if err != nil {
@@ -221,6 +235,20 @@ func prepareTunnelConfig(
 		log.Warn().Str("edgeIPVersion", edgeIPVersion.String()).Err(err).Msg("Overriding edge-ip-version")
 	}
 
+	quicMTU := c.Int(quicInitialMTU)
+	if quicMTU != 0 && (quicMTU < supervisor.MinQUICInitialMTU || quicMTU > supervisor.MaxQUICInitialMTU) {
+		return nil, nil, fmt.Errorf("%s must be between %d and %d", quicInitialMTU, supervisor.MinQUICInitialMTU, supervisor.MaxQUICInitialMTU)
+	}
+
+	maxUDPPayloadSize := c.Int(quicMaxUDPPayloadSize)
+	if maxUDPPayloadSize != 0 && (maxUDPPayloadSize < v3.MinDatagramPayloadLen || maxUDPPayloadSize > v3.MaxDatagramPayloadLen) {
+		return nil, nil, fmt.Errorf("%s must be between %d and %d", quicMaxUDPPayloadSize, v3.MinDatagramPayloadLen, v3.MaxDatagramPayloadLen)
+	}
+
+	if maxIdleTimeout := c.Duration(quicMaxIdleTimeout); maxIdleTimeout <= quicpogs.MaxIdlePingPeriod {
+		return nil, nil, fmt.Errorf("%s must be longer than the keepalive period of %s", quicMaxIdleTimeout, quicpogs.MaxIdlePingPeriod)
+	}
+
 	tunnelConfig := &supervisor.TunnelConfig{
 		GracePeriod:     gracePeriod,
 		ReplaceExisting: c.Bool("force"),
@@ -246,9 +274,16 @@ func prepareTunnelConfig(
 		EdgeTLSConfigs:                      edgeTLSConfigs,
 		FeatureSelector:                     featureSelector,
 		MaxEdgeAddrRetries:                  uint8(c.Int("max-edge-addr-retries")),
+		InitialConnectRetries:               uint(c.Int("initial-connect-retries")),
 		RPCTimeout:                          c.Duration(rpcTimeout),
 		WriteStreamTimeout:                  c.Duration(writeStreamTimeout),
+		HAConnectionsInterval:               c.Duration(haConnectionsIntervalFlag),
+		ConnectionMaxLifetime:               c.Duration(connectionMaxLifetime),
+		RequireProtocol:                     requireProtocol,
 		DisableQUICPathMTUDiscovery:         c.Bool(quicDisablePathMTUDiscovery),
+		QUICInitialMTU:                      uint16(quicMTU),
+		QUICMaxUDPPayloadSize:               maxUDPPayloadSize,
+		QUICMaxIdleTimeout:                  c.Duration(quicMaxIdleTimeout),
 		QUICConnectionLevelFlowControlLimit: c.Uint64(quicConnLevelFlowControlLimit),
 		QUICStreamLevelFlowControlLimit:     c.Uint64(quicStreamLevelFlowControlLimit),
 	}
@@ -258,11 +293,16 @@ func prepareTunnelConfig(
 	} else {
 		tunnelConfig.ICMPRouterServer = icmpRouter
 	}
+	warpRouting, err := ingress.NewWarpRoutingConfig(&cfg.WarpRouting)
+	if err != nil {
+		return nil, nil, err
+	}
 	orchestratorConfig := &orchestration.Config{
-		Ingress:            &ingressRules,
-		WarpRouting:        ingress.NewWarpRoutingConfig(&cfg.WarpRouting),
-		ConfigurationFlags: parseConfigFlags(c),
-		WriteTimeout:       c.Duration(writeStreamTimeout),
+		Ingress:              &ingressRules,
+		WarpRouting:          warpRouting,
+		ConfigurationFlags:   parseConfigFlags(c),
+		WriteTimeout:         c.Duration(writeStreamTimeout),
+		SlowRequestThreshold: c.Duration(slowRequestThreshold),
 	}
 	return tunnelConfig, orchestratorConfig, nil
 }
@@ -0,0 +1,117 @@
+package tunnel
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
+)
+
+// minTunnelSecretSize is the smallest tunnel secret accepted by Cloudflare, in bytes.
+const minTunnelSecretSize = 32
+
+func buildTokenValidateSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "validate",
+		Action:    cliutil.ConfiguredAction(tokenValidateCommand),
+		Usage:     "Check that a tunnel credentials file is complete and well formed",
+		UsageText: "cloudflared tunnel [tunnel command options] token validate --cred-file PATH",
+		Description: `Parses a tunnel credentials file and reports every problem found with it, such as a file truncated while
+copying it or a secret that isn't valid base64. It works offline and doesn't check the credentials against Cloudflare.`,
+		Flags:              []cli.Flag{credentialsFileFlagCLIOnly},
+		CustomHelpTemplate: commandHelpTemplate(),
+	}
+}
+
+func tokenValidateCommand(c *cli.Context) error {
+	path := c.String(CredFileFlag)
+	if path == "" {
+		return cliutil.UsageError(`"cloudflared tunnel token validate" requires the --%s flag`, CredFileFlag)
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't read tunnel credentials from %s", path)
+	}
+	problems := credentialsProblems(body)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Printf("- %s\n", problem)
+		}
+		return fmt.Errorf("tunnel credentials file %s is invalid", path)
+	}
+	fmt.Printf("Tunnel credentials file %s is valid\n", path)
+	return nil
+}
+
+// credentialsProblems returns what is wrong with the content of a tunnel credentials file, or nothing if it is valid.
+func credentialsProblems(body []byte) []string {
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("-----BEGIN")) {
+		return []string{"this is a PEM file, such as the cert.pem generated by `cloudflared tunnel login`, " +
+			"not the JSON credentials file generated by `cloudflared tunnel create`"}
+	}
+
+	var raw struct {
+		AccountTag   *string
+		TunnelSecret *string
+		TunnelID     *string
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case len(bytes.TrimSpace(body)) == 0:
+			return []string{"the file is empty"}
+		case errors.As(err, &syntaxErr) && strings.Contains(err.Error(), "unexpected end of JSON input"):
+			return []string{"the JSON ends unexpectedly, the file may have been truncated"}
+		case errors.As(err, &syntaxErr):
+			// The offset is past the invalid character
+			line, column := jsonPosition(body, syntaxErr.Offset-1)
+			return []string{fmt.Sprintf("invalid JSON at line %d, column %d: %s", line, column, syntaxErr)}
+		case errors.As(err, &typeErr):
+			return []string{fmt.Sprintf("%s must be a string, not a JSON %s", typeErr.Field, typeErr.Value)}
+		default:
+			return []string{fmt.Sprintf("invalid JSON: %s", err)}
+		}
+	}
+
+	var problems []string
+	if raw.AccountTag == nil || *raw.AccountTag == "" {
+		problems = append(problems, "AccountTag is missing")
+	}
+	if raw.TunnelSecret == nil || *raw.TunnelSecret == "" {
+		problems = append(problems, "TunnelSecret is missing")
+	} else if secret, err := base64.StdEncoding.DecodeString(*raw.TunnelSecret); err != nil {
+		problems = append(problems, fmt.Sprintf("TunnelSecret is not valid base64: %s", err))
+	} else if len(secret) < minTunnelSecretSize {
+		problems = append(problems, fmt.Sprintf("TunnelSecret is %d bytes long, it must be at least %d bytes", len(secret), minTunnelSecretSize))
+	}
+	if raw.TunnelID == nil || *raw.TunnelID == "" {
+		problems = append(problems, "TunnelID is missing")
+	} else if id, err := uuid.Parse(*raw.TunnelID); err != nil {
+		problems = append(problems, fmt.Sprintf("TunnelID %q is not a UUID", *raw.TunnelID))
+	} else if id == uuid.Nil {
+		problems = append(problems, "TunnelID is the nil UUID")
+	}
+	return problems
+}
+
+// jsonPosition converts a byte offset of body to a line and column, both starting at 1.
+func jsonPosition(body []byte, offset int64) (line, column int) {
+	if offset < 0 {
+		offset = 0
+	} else if offset > int64(len(body)) {
+		offset = int64(len(body))
+	}
+	before := body[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -0,0 +1,104 @@
+package tunnel
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/features"
+)
+
+// buildCapabilities lists the protocols and features compiled into this build.
+type buildCapabilities struct {
+	Version   string       `json:"version" yaml:"version"`
+	BuildType string       `json:"buildType,omitempty" yaml:"buildType,omitempty"`
+	FIPS      bool         `json:"fips" yaml:"fips"`
+	Protocols []capability `json:"protocols" yaml:"protocols"`
+	Features  []capability `json:"features" yaml:"features"`
+}
+
+type capability struct {
+	Name string `json:"name" yaml:"name"`
+	// Default is true if the capability is used without any flag.
+	Default bool `json:"default" yaml:"default"`
+	// Supported is false if the capability is compiled in but can't be used with this build.
+	Supported bool `json:"supported" yaml:"supported"`
+}
+
+func buildFeaturesCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "features",
+		Action: cliutil.ConfiguredAction(featuresCommand),
+		Usage:  "List the protocols and features supported by this build",
+		Description: `Lists the protocols that can be used to connect to Cloudflare's edge and the features that this build
+of cloudflared can announce to it, along with whether they are used by default. Features that are not used by
+default can be turned on with --features, or with their own flag like --post-quantum.
+Include this output when reporting issues.`,
+		Flags:              []cli.Flag{outputFormatFlag},
+		CustomHelpTemplate: commandHelpTemplate(),
+	}
+}
+
+func featuresCommand(c *cli.Context) error {
+	capabilities := getBuildCapabilities(buildInfo, FipsEnabled)
+	if outputFormat := c.String(outputFormatFlag.Name); outputFormat != "" {
+		return renderOutput(outputFormat, capabilities)
+	}
+	formatAndPrintCapabilities(capabilities)
+	return nil
+}
+
+func getBuildCapabilities(info *cliutil.BuildInfo, fipsEnabled bool) buildCapabilities {
+	capabilities := buildCapabilities{FIPS: fipsEnabled}
+	if info != nil {
+		capabilities.Version = info.CloudflaredVersion
+		capabilities.BuildType = info.BuildType
+	}
+	// The protocol selector starts with the first protocol of the list unless told otherwise
+	for i, protocol := range connection.ProtocolList {
+		capabilities.Protocols = append(capabilities.Protocols, capability{
+			Name:      protocol.String(),
+			Default:   i == 0,
+			Supported: true,
+		})
+	}
+	for _, feature := range features.KnownFeatures {
+		capabilities.Features = append(capabilities.Features, capability{
+			Name:      feature,
+			Default:   features.Contains(feature),
+			Supported: feature != features.FeaturePostQuantum || !fipsEnabled,
+		})
+	}
+	return capabilities
+}
+
+func formatAndPrintCapabilities(capabilities buildCapabilities) {
+	writer := tabWriter()
+	defer writer.Flush()
+
+	buildType := capabilities.BuildType
+	if buildType == "" {
+		buildType = "standard"
+	}
+	_, _ = fmt.Fprintf(writer, "Version: %s (%s build, FIPS: %t)\n\n", capabilities.Version, buildType, capabilities.FIPS)
+	_, _ = fmt.Fprintln(writer, "TYPE\tNAME\tSTATE\t")
+	for _, protocol := range capabilities.Protocols {
+		_, _ = fmt.Fprintf(writer, "protocol\t%s\t%s\t\n", protocol.Name, fmtCapabilityState(protocol))
+	}
+	for _, feature := range capabilities.Features {
+		_, _ = fmt.Fprintf(writer, "feature\t%s\t%s\t\n", feature.Name, fmtCapabilityState(feature))
+	}
+}
+
+func fmtCapabilityState(c capability) string {
+	switch {
+	case !c.Supported:
+		return "unsupported"
+	case c.Default:
+		return "default"
+	default:
+		return "opt-in"
+	}
+}
@@ -0,0 +1,35 @@
+package tunnel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
+	"github.com/cloudflare/cloudflared/features"
+)
+
+func TestGetBuildCapabilities(t *testing.T) {
+	info := cliutil.GetBuildInfo("FIPS", "2024.1.0")
+	for _, fipsEnabled := range []bool{false, true} {
+		capabilities := getBuildCapabilities(info, fipsEnabled)
+		require.Equal(t, "2024.1.0", capabilities.Version)
+		require.Equal(t, "FIPS", capabilities.BuildType)
+		require.Equal(t, fipsEnabled, capabilities.FIPS)
+
+		require.Equal(t, []capability{
+			{Name: "quic", Default: true, Supported: true},
+			{Name: "http2", Default: false, Supported: true},
+		}, capabilities.Protocols)
+
+		require.Len(t, capabilities.Features, len(features.KnownFeatures))
+		for _, feature := range capabilities.Features {
+			require.Equal(t, features.Contains(feature.Name), feature.Default, feature.Name)
+			if feature.Name == features.FeaturePostQuantum {
+				require.Equal(t, !fipsEnabled, feature.Supported)
+			} else {
+				require.True(t, feature.Supported, feature.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+
+	"github.com/cloudflare/cloudflared/cfapi"
+	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
+)
+
+const (
+	// minWatchInterval keeps --watch from hammering the API
+	minWatchInterval = time.Second
+	// maxWatchEvents is how many of the last connection changes are listed under the connector table
+	maxWatchEvents = 10
+	// clearScreen moves the cursor to the top left corner of the terminal and clears it
+	clearScreen = "\033[H\033[2J"
+)
+
+// watchTunnelInfo redraws the info of the tunnel every --watch-interval until interrupted, followed by the
+// connections that were opened and closed since the command started.
+func watchTunnelInfo(c *cli.Context, sc *subcommandContext, client cfapi.Client, tunnelID uuid.UUID) error {
+	if c.String(outputFormatFlag.Name) != "" {
+		return cliutil.UsageError("--%s can't be used with --%s", watchInfoFlag.Name, outputFormatFlag.Name)
+	}
+	interval := c.Duration(watchIntervalFlag.Name)
+	if interval < minWatchInterval {
+		return cliutil.UsageError("--%s must be at least %s", watchIntervalFlag.Name, minWatchInterval)
+	}
+	showRecentlyDisconnected := c.Bool("show-recently-disconnected")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previous []*cfapi.ActiveClient
+	var events []string
+	for {
+		info, err := getTunnelInfo(c, sc, client, tunnelID)
+		fmt.Print(clearScreen)
+		fmt.Printf("Every %s, press Ctrl+C to stop. Last refresh: %s\n\n", interval, time.Now().Format(time.RFC3339))
+		if err != nil {
+			fmt.Printf("Failed to get the tunnel info: %s\n", err)
+		} else {
+			if previous != nil {
+				events = append(events, connectionChanges(previous, info.Connectors, time.Now())...)
+				if len(events) > maxWatchEvents {
+					events = events[len(events)-maxWatchEvents:]
+				}
+			}
+			previous = info.Connectors
+			printTunnelInfo(info, showRecentlyDisconnected)
+		}
+		if len(events) > 0 {
+			fmt.Println("\nCHANGES:")
+			for _, event := range events {
+				fmt.Println(event)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+type watchedConnection struct {
+	connectorID uuid.UUID
+	colo        string
+}
+
+// connectionChanges lists the connections that were opened and closed between two listings of the connectors of a
+// tunnel. Connections pending reconnect count as closed.
+func connectionChanges(previous, current []*cfapi.ActiveClient, now time.Time) []string {
+	before := activeConnections(previous)
+	after := activeConnections(current)
+	var changes []string
+	for id, conn := range after {
+		if _, ok := before[id]; !ok {
+			changes = append(changes, fmt.Sprintf("%s connector %s opened a connection to %s", now.Format(time.TimeOnly), conn.connectorID, conn.colo))
+		}
+	}
+	for id, conn := range before {
+		if _, ok := after[id]; !ok {
+			changes = append(changes, fmt.Sprintf("%s connector %s closed its connection to %s", now.Format(time.TimeOnly), conn.connectorID, conn.colo))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func activeConnections(connectors []*cfapi.ActiveClient) map[uuid.UUID]watchedConnection {
+	connections := make(map[uuid.UUID]watchedConnection)
+	for _, connector := range connectors {
+		for _, conn := range connector.Connections {
+			if !conn.IsPendingReconnect {
+				connections[conn.ID] = watchedConnection{connectorID: connector.ID, colo: conn.ColoName}
+			}
+		}
+	}
+	return connections
+}
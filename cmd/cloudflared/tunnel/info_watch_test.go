@@ -0,0 +1,34 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudflare/cloudflared/cfapi"
+)
+
+func TestConnectionChanges(t *testing.T) {
+	connectorID := uuid.MustParse("6f1dc5a3-2b1c-4b0e-8d3e-1f0c2a3b4c5d")
+	kept := cfapi.Connection{ID: uuid.New(), ColoName: "lhr01"}
+	closed := cfapi.Connection{ID: uuid.New(), ColoName: "ams01"}
+	opened := cfapi.Connection{ID: uuid.New(), ColoName: "cdg01"}
+	pending := cfapi.Connection{ID: kept.ID, ColoName: "lhr01", IsPendingReconnect: true}
+	now := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	previous := []*cfapi.ActiveClient{{ID: connectorID, Connections: []cfapi.Connection{kept, closed}}}
+	current := []*cfapi.ActiveClient{{ID: connectorID, Connections: []cfapi.Connection{kept, opened}}}
+	assert.Equal(t, []string{
+		"10:30:00 connector 6f1dc5a3-2b1c-4b0e-8d3e-1f0c2a3b4c5d closed its connection to ams01",
+		"10:30:00 connector 6f1dc5a3-2b1c-4b0e-8d3e-1f0c2a3b4c5d opened a connection to cdg01",
+	}, connectionChanges(previous, current, now))
+
+	// A connection pending reconnect is no longer active
+	assert.Equal(t, []string{
+		"10:30:00 connector 6f1dc5a3-2b1c-4b0e-8d3e-1f0c2a3b4c5d closed its connection to lhr01",
+	}, connectionChanges(current, []*cfapi.ActiveClient{{ID: connectorID, Connections: []cfapi.Connection{pending, opened}}}, now))
+
+	assert.Empty(t, connectionChanges(current, current, now))
+}
@@ -4,16 +4,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
 	"github.com/cloudflare/cloudflared/config"
 	"github.com/cloudflare/cloudflared/ingress"
+	"github.com/cloudflare/cloudflared/logger"
+	"github.com/cloudflare/cloudflared/orchestration"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 )
 
-const ingressDataJSONFlagName = "json"
+const (
+	ingressDataJSONFlagName = "json"
+	benchmarkAttemptsFlag   = "attempts"
+	exportFormatFlag        = "format"
+	exportFormatDashboard   = "dashboard"
+)
 
 var ingressDataJSON = &cli.StringFlag{
 	Name:    ingressDataJSONFlagName,
@@ -45,10 +53,12 @@ func buildIngressSubcommand() *cli.Command {
 
 		To ensure cloudflared can route all incoming requests, the last rule must be a catch-all
 		rule that matches all traffic. You can validate these rules with the 'ingress validate'
-		command, and test which rule matches a particular URL with 'ingress rule <URL>'.
+		command, test which rule matches a particular URL with 'ingress rule <URL>', check that
+		the origins are reachable with 'ingress benchmark', and export them for a remotely-managed
+		tunnel with 'ingress export'.
 
 		Multiple-origin routing is incompatible with the --url flag.`,
-		Subcommands: []*cli.Command{buildValidateIngressCommand(), buildTestURLCommand()},
+		Subcommands: []*cli.Command{buildValidateIngressCommand(), buildTestURLCommand(), buildBenchmarkIngressCommand(), buildExportIngressCommand()},
 	}
 }
 
@@ -78,6 +88,46 @@ func buildTestURLCommand() *cli.Command {
 	}
 }
 
+func buildBenchmarkIngressCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "benchmark",
+		Action:    cliutil.ConfiguredAction(benchmarkIngressCommand),
+		Usage:     "Check that the origin of each ingress rule is reachable and how fast it answers",
+		UsageText: "cloudflared tunnel [--config FILEPATH] ingress benchmark [--attempts N]",
+		Description: "Connects to the origin of each ingress rule a few times, with the same settings cloudflared " +
+			"proxies with, and reports the success rate, the average connect time and the average time to " +
+			"first byte. HTTP origins get a HEAD request on a new connection each time. TCP origins are only " +
+			"dialed. Origins served by cloudflared itself, like hello_world or http_status, are skipped.",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  benchmarkAttemptsFlag,
+				Usage: "Number of test connections to each origin",
+				Value: 3,
+			},
+		},
+	}
+}
+
+func buildExportIngressCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Action:    cliutil.ConfiguredAction(exportIngressCommand),
+		Usage:     "Print the ingress configuration in the format of a remotely-managed tunnel",
+		UsageText: "cloudflared tunnel [--config FILEPATH] ingress export [--format dashboard]",
+		Description: "Prints the ingress rules and warp-routing settings of the configuration file as the JSON body " +
+			"that the Cloudflare API takes to configure a remotely-managed tunnel " +
+			"(PUT accounts/<account>/cfd_tunnel/<tunnel>/configurations). originRequest settings are copied " +
+			"into each rule, since remotely-managed tunnels don't have top-level originRequest settings.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  exportFormatFlag,
+				Usage: "Format of the exported configuration. Only 'dashboard' is supported",
+				Value: exportFormatDashboard,
+			},
+		},
+	}
+}
+
 // validateIngressCommand check the syntax of the ingress rules in the cloudflared config file
 func validateIngressCommand(c *cli.Context, warnings string) error {
 	conf, err := getConfiguration(c)
@@ -143,3 +193,73 @@ func testURLCommand(c *cli.Context) error {
 	fmt.Println(ing.Rules[i].MultiLineString())
 	return nil
 }
+
+// benchmarkIngressCommand connects to the origin of every ingress rule and reports how it went.
+func benchmarkIngressCommand(c *cli.Context) error {
+	attempts := c.Int(benchmarkAttemptsFlag)
+	if attempts <= 0 {
+		return errors.New("--attempts must be greater than 0")
+	}
+
+	conf := config.GetConfiguration()
+	fmt.Println("Using rules from", conf.Source())
+	ing, err := ingress.ParseIngress(conf)
+	if err != nil {
+		return errors.Wrap(err, "Validation failed")
+	}
+
+	log := logger.CreateLoggerFromContext(c, logger.EnableTerminalLog)
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	if err := ing.StartOrigins(log, shutdownC); err != nil {
+		return err
+	}
+
+	for i := range ing.Rules {
+		rule := &ing.Rules[i]
+		fmt.Printf("Rule #%d\n%s\n", i, rule.MultiLineString())
+		result, ok := rule.Benchmark(c.Context, attempts, log)
+		if !ok {
+			fmt.Println("\tskipped: origin can't be benchmarked")
+			continue
+		}
+		fmt.Printf("\tsuccess: %d/%d\n", result.Successes, result.Attempts)
+		if result.Successes > 0 {
+			fmt.Printf("\tconnect: %s\n", result.ConnectTime.Round(time.Microsecond))
+			if result.TTFB > 0 {
+				fmt.Printf("\tttfb: %s\n", result.TTFB.Round(time.Microsecond))
+			}
+		}
+		if result.LastErr != nil {
+			fmt.Printf("\tlast error: %s\n", result.LastErr)
+		}
+	}
+	return nil
+}
+
+// exportIngressCommand prints the ingress rules in the format of a remotely-managed tunnel.
+func exportIngressCommand(c *cli.Context) error {
+	if format := c.String(exportFormatFlag); format != exportFormatDashboard {
+		return fmt.Errorf("unknown export format '%s', only '%s' is supported", format, exportFormatDashboard)
+	}
+
+	conf := config.GetConfiguration()
+	if conf.Source() == "" {
+		return errors.New("No configuration file was found. Please create one, or use the --config flag to specify its filepath")
+	}
+	ing, err := ingress.ParseIngress(conf)
+	if err != nil {
+		return errors.Wrap(err, "Validation failed")
+	}
+	warpRouting, err := ingress.NewWarpRoutingConfig(&conf.WarpRouting)
+	if err != nil {
+		return errors.Wrap(err, "Validation failed")
+	}
+
+	dashboardJSON, err := orchestration.DashboardConfigJSON(ing, warpRouting)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(dashboardJSON))
+	return nil
+}
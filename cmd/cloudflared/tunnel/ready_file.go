@@ -0,0 +1,59 @@
+package tunnel
+
+import (
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/signal"
+)
+
+// readyFile is the --ready-file, which exists only while the tunnel is ready, so that init systems and dependent
+// services can wait for it.
+type readyFile struct {
+	path string
+	log  *zerolog.Logger
+
+	mutex   sync.Mutex
+	removed bool
+}
+
+// newReadyFile removes a ready file left behind by a previous run, which would otherwise signal readiness too early.
+func newReadyFile(path string, log *zerolog.Logger) *readyFile {
+	f := &readyFile{path: path, log: log}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Err(err).Str(LogFieldExpandedPath, path).Msg("Unable to remove stale ready file")
+	}
+	return f
+}
+
+// writeWhenReady creates the file once connected is notified, unless shutdownC is closed first.
+func (f *readyFile) writeWhenReady(connected *signal.Signal, shutdownC <-chan struct{}) {
+	select {
+	case <-connected.Wait():
+	case <-shutdownC:
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.removed {
+		return
+	}
+	file, err := os.Create(f.path)
+	if err != nil {
+		f.log.Err(err).Str(LogFieldExpandedPath, f.path).Msg("Unable to write ready file")
+		return
+	}
+	file.Close()
+}
+
+// remove deletes the file on shutdown and prevents it from being created afterwards.
+func (f *readyFile) remove() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.removed = true
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		f.log.Err(err).Str(LogFieldExpandedPath, f.path).Msg("Unable to remove ready file")
+	}
+}
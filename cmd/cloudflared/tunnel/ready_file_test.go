@@ -0,0 +1,39 @@
+package tunnel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/signal"
+)
+
+func TestReadyFile(t *testing.T) {
+	log := zerolog.Nop()
+	path := filepath.Join(t.TempDir(), "ready")
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+
+	// A file left behind by a previous run is removed until the tunnel is ready again
+	readyFile := newReadyFile(path, &log)
+	require.NoFileExists(t, path)
+
+	connected := signal.New(make(chan struct{}))
+	connected.Notify()
+	readyFile.writeWhenReady(connected, make(chan struct{}))
+	require.FileExists(t, path)
+
+	readyFile.remove()
+	require.NoFileExists(t, path)
+
+	// The file isn't created once the tunnel shut down
+	readyFile.writeWhenReady(connected, make(chan struct{}))
+	require.NoFileExists(t, path)
+
+	shutdownC := make(chan struct{})
+	close(shutdownC)
+	newReadyFile(path, &log).writeWhenReady(signal.New(make(chan struct{})), shutdownC)
+	require.NoFileExists(t, path)
+}
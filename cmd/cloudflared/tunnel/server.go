@@ -19,7 +19,26 @@ func runDNSProxyServer(c *cli.Context, dnsReadySignal chan struct{}, shutdownC <
 	if maxUpstreamConnections < 0 {
 		return fmt.Errorf("'%s' must be 0 or higher", "proxy-dns-max-upstream-conns")
 	}
-	listener, err := tunneldns.CreateListener(c.String("proxy-dns-address"), uint16(port), c.StringSlice("proxy-dns-upstream"), c.StringSlice("proxy-dns-bootstrap"), maxUpstreamConnections, log)
+	maxQPS := c.Int("proxy-dns-max-qps")
+	if maxQPS < 0 {
+		return fmt.Errorf("'%s' must be 0 or higher", "proxy-dns-max-qps")
+	}
+	upstreamHeaders, err := tunneldns.ParseUpstreamHeaders(c.StringSlice("proxy-dns-upstream-header"))
+	if err != nil {
+		close(dnsReadySignal)
+		return errors.Wrap(err, "Invalid 'proxy-dns-upstream-header'")
+	}
+	listener, err := tunneldns.CreateListener(tunneldns.ListenerConfig{
+		Address:                c.String("proxy-dns-address"),
+		Port:                   uint16(port),
+		Upstreams:              c.StringSlice("proxy-dns-upstream"),
+		Bootstraps:             c.StringSlice("proxy-dns-bootstrap"),
+		UpstreamHeaders:        upstreamHeaders,
+		MaxUpstreamConnections: maxUpstreamConnections,
+		MaxQPS:                 maxQPS,
+		MaxQPSBurst:            c.Int("proxy-dns-max-qps-burst"),
+		ServeStale:             c.Duration("proxy-dns-serve-stale"),
+	}, log)
 	if err != nil {
 		close(dnsReadySignal)
 		listener.Stop()
@@ -8,8 +8,9 @@ import (
 	"github.com/rs/zerolog"
 )
 
-// waitForSignal closes graceShutdownC to indicate that we should start graceful shutdown sequence
-func waitForSignal(graceShutdownC chan struct{}, logger *zerolog.Logger) {
+// waitForSignal closes graceShutdownC to indicate that we should start graceful shutdown sequence, on a signal or once
+// requestsDoneC is closed. requestsDoneC may be nil.
+func waitForSignal(graceShutdownC chan struct{}, requestsDoneC <-chan struct{}, logger *zerolog.Logger) {
 	signals := make(chan os.Signal, 10)
 	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
 	defer signal.Stop(signals)
@@ -18,6 +19,9 @@ func waitForSignal(graceShutdownC chan struct{}, logger *zerolog.Logger) {
 	case s := <-signals:
 		logger.Info().Msgf("Initiating graceful shutdown due to signal %s ...", s)
 		close(graceShutdownC)
+	case <-requestsDoneC:
+		logger.Info().Msg("Initiating graceful shutdown after proxying the number of requests of --exit-after-requests ...")
+		close(graceShutdownC)
 	case <-graceShutdownC:
 	}
 }
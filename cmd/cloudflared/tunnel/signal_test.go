@@ -3,6 +3,8 @@
 package tunnel
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"syscall"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const tick = 100 * time.Millisecond
@@ -52,7 +55,7 @@ func TestSignalShutdown(t *testing.T) {
 			}
 		})
 
-		waitForSignal(graceShutdownC, &log)
+		waitForSignal(graceShutdownC, nil, &log)
 		assert.True(t, channelClosed(graceShutdownC))
 	}
 }
@@ -76,7 +79,7 @@ func TestWaitForShutdown(t *testing.T) {
 	go func() {
 		errC <- serverErr
 	}()
-	err := waitToShutdown(&wg, cancel, errC, graceShutdownC, gracePeriod, &log)
+	err := waitToShutdown(&wg, cancel, errC, graceShutdownC, nil, gracePeriod, &log)
 	assert.Equal(t, serverErr, err)
 	assert.True(t, contextCancelled)
 	assert.False(t, channelClosed(graceShutdownC))
@@ -90,7 +93,7 @@ func TestWaitForShutdown(t *testing.T) {
 		time.Sleep(tick)
 		errC <- serverErr
 	}()
-	err = waitToShutdown(&wg, cancel, errC, graceShutdownC, gracePeriod, &log)
+	err = waitToShutdown(&wg, cancel, errC, graceShutdownC, nil, gracePeriod, &log)
 	assert.Nil(t, err)
 	assert.True(t, contextCancelled)
 	assert.True(t, time.Now().Sub(startTime) < time.Second) // check that wait ended early
@@ -98,8 +101,61 @@ func TestWaitForShutdown(t *testing.T) {
 	// with graceShutdownC closed stop right away without grace period
 	contextCancelled = false
 	startTime = time.Now()
-	err = waitToShutdown(&wg, cancel, errC, graceShutdownC, 0, &log)
+	err = waitToShutdown(&wg, cancel, errC, graceShutdownC, nil, 0, &log)
 	assert.Nil(t, err)
 	assert.True(t, contextCancelled)
 	assert.True(t, time.Now().Sub(startTime) < time.Second) // check that wait ended early
 }
+
+func TestWaitForShutdownLogsExitEvent(t *testing.T) {
+	var wg sync.WaitGroup
+	cancel := func() {}
+
+	var output bytes.Buffer
+	log := zerolog.New(&output)
+	errC := make(chan error)
+	go func() {
+		errC <- serverErr
+	}()
+	_ = waitToShutdown(&wg, cancel, errC, make(chan struct{}), nil, time.Second, &log)
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(lastLine(output.Bytes()), &event))
+	assert.Equal(t, shutdownTriggerError, event["trigger"])
+	assert.Equal(t, drainResultSkipped, event["drain"])
+	assert.Equal(t, serverErr.Error(), event["error"])
+
+	output.Reset()
+	event = map[string]interface{}{}
+	graceShutdownC := make(chan struct{})
+	close(graceShutdownC)
+	_ = waitToShutdown(&wg, cancel, make(chan error), graceShutdownC, nil, tick, &log)
+	require.NoError(t, json.Unmarshal(lastLine(output.Bytes()), &event))
+	assert.Equal(t, shutdownTriggerSignal, event["trigger"])
+	assert.Equal(t, drainResultGracePeriodExpired, event["drain"])
+	assert.Equal(t, float64(0), event["abortedRequests"])
+
+	output.Reset()
+	event = map[string]interface{}{}
+	errC = make(chan error)
+	go func() {
+		errC <- serverErr
+	}()
+	_ = waitToShutdown(&wg, cancel, errC, graceShutdownC, nil, time.Minute, &log)
+	require.NoError(t, json.Unmarshal(lastLine(output.Bytes()), &event))
+	assert.Equal(t, shutdownTriggerSignal, event["trigger"])
+	assert.Equal(t, drainResultComplete, event["drain"])
+
+	// --exit-after-requests is reported as the trigger of the graceful shutdown it started
+	output.Reset()
+	event = map[string]interface{}{}
+	requestsDoneC := make(chan struct{})
+	close(requestsDoneC)
+	_ = waitToShutdown(&wg, cancel, make(chan error), graceShutdownC, requestsDoneC, 0, &log)
+	require.NoError(t, json.Unmarshal(lastLine(output.Bytes()), &event))
+	assert.Equal(t, shutdownTriggerRequests, event["trigger"])
+}
+
+func lastLine(output []byte) []byte {
+	lines := bytes.Split(bytes.TrimSpace(output), []byte("\n"))
+	return lines[len(lines)-1]
+}
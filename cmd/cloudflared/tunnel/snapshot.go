@@ -0,0 +1,77 @@
+package tunnel
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/tunnelstate"
+)
+
+// snapshotExcludedPrefixes are the metrics of the Go runtime and the process, which don't say anything about the tunnel.
+var snapshotExcludedPrefixes = []string{"go_", "process_", "promhttp_"}
+
+// logSnapshot logs the active edge connections and the current value of the tunnel metrics, for hosts where the
+// metrics server can't be reached. Histograms and summaries are reported by their count and sum.
+func logSnapshot(log *zerolog.Logger, tracker *tunnelstate.ConnTracker, gatherer prometheus.Gatherer) {
+	connections := tracker.GetActiveConnections()
+	sort.Slice(connections, func(i, j int) bool { return connections[i].Index < connections[j].Index })
+
+	values := make(map[string]float64)
+	families, err := gatherer.Gather()
+	if err != nil {
+		// Gather returns what it could collect along with the error
+		log.Err(err).Msg("Failed to gather some metrics for the snapshot")
+	}
+	for _, family := range families {
+		if excludedFromSnapshot(family.GetName()) {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			name, labels := family.GetName(), snapshotLabels(metric.GetLabel())
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				values[name+labels] = metric.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				values[name+labels] = metric.GetGauge().GetValue()
+			case dto.MetricType_UNTYPED:
+				values[name+labels] = metric.GetUntyped().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				values[name+"_count"+labels] = float64(metric.GetHistogram().GetSampleCount())
+				values[name+"_sum"+labels] = metric.GetHistogram().GetSampleSum()
+			case dto.MetricType_SUMMARY:
+				values[name+"_count"+labels] = float64(metric.GetSummary().GetSampleCount())
+				values[name+"_sum"+labels] = metric.GetSummary().GetSampleSum()
+			}
+		}
+	}
+
+	log.Info().
+		Interface("connections", connections).
+		Interface("metrics", values).
+		Msg("Tunnel snapshot")
+}
+
+func excludedFromSnapshot(name string) bool {
+	for _, prefix := range snapshotExcludedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotLabels formats labels like Prometheus does, e.g. {protocol="quic"}, or returns "" if there are none.
+func snapshotLabels(labels []*dto.LabelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		pairs = append(pairs, label.GetName()+`="`+label.GetValue()+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
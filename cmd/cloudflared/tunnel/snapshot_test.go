@@ -0,0 +1,51 @@
+package tunnel
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/tunnelstate"
+)
+
+func TestLogSnapshot(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "cloudflared_requests_total"}, []string{"code"})
+	requests.WithLabelValues("200").Add(3)
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "cloudflared_latency_seconds"})
+	latency.Observe(0.5)
+	latency.Observe(1.5)
+	goroutines := prometheus.NewGauge(prometheus.GaugeOpts{Name: "go_goroutines"})
+	registry.MustRegister(requests, latency, goroutines)
+
+	nopLog := zerolog.Nop()
+	tracker := tunnelstate.NewConnTracker(&nopLog)
+	tracker.OnTunnelEvent(connection.Event{Index: 1, EventType: connection.Connected, Protocol: connection.QUIC, EdgeAddress: net.ParseIP("198.41.192.7")})
+
+	var output bytes.Buffer
+	log := zerolog.New(&output)
+	logSnapshot(&log, tracker, registry)
+
+	var snapshot struct {
+		Message     string
+		Connections []tunnelstate.IndexedConnectionInfo
+		Metrics     map[string]float64
+	}
+	require.NoError(t, json.Unmarshal(output.Bytes(), &snapshot))
+	assert.Equal(t, "Tunnel snapshot", snapshot.Message)
+	require.Len(t, snapshot.Connections, 1)
+	assert.Equal(t, uint8(1), snapshot.Connections[0].Index)
+	assert.Equal(t, connection.QUIC, snapshot.Connections[0].Protocol)
+	assert.Equal(t, map[string]float64{
+		`cloudflared_requests_total{code="200"}`: 3,
+		"cloudflared_latency_seconds_count":      2,
+		"cloudflared_latency_seconds_sum":        2,
+	}, snapshot.Metrics)
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package tunnel
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/tunnelstate"
+)
+
+// logSnapshotOnSignal logs a snapshot of the tunnel every time cloudflared receives SIGUSR1, until ctx is done.
+func logSnapshotOnSignal(ctx context.Context, tracker *tunnelstate.ConnTracker, log *zerolog.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			logSnapshot(log, tracker, prometheus.DefaultGatherer)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
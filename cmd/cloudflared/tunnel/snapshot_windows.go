@@ -0,0 +1,14 @@
+//go:build windows
+
+package tunnel
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/tunnelstate"
+)
+
+// logSnapshotOnSignal does nothing since Windows has no SIGUSR1.
+func logSnapshotOnSignal(_ context.Context, _ *tunnelstate.ConnTracker, _ *zerolog.Logger) {}
@@ -0,0 +1,89 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/tunnelstate"
+)
+
+// tunnelStatus is the content of the --status-file.
+type tunnelStatus struct {
+	PID         int        `json:"pid"`
+	TunnelID    uuid.UUID  `json:"tunnelID"`
+	ConnectorID uuid.UUID  `json:"connectorID"`
+	Connections uint       `json:"connections"`
+	Protocol    string     `json:"protocol,omitempty"`
+	LastReadyAt *time.Time `json:"lastReadyAt,omitempty"`
+}
+
+// statusFileWriter is a connection.EventSink that rewrites the status file every time a connection changes state.
+type statusFileWriter struct {
+	path    string
+	tracker *tunnelstate.ConnTracker
+	log     *zerolog.Logger
+
+	mutex  sync.Mutex
+	status tunnelStatus
+}
+
+func newStatusFileWriter(path string, tunnelID, connectorID uuid.UUID, log *zerolog.Logger) *statusFileWriter {
+	return &statusFileWriter{
+		path:    path,
+		tracker: tunnelstate.NewConnTracker(log),
+		log:     log,
+		status: tunnelStatus{
+			PID:         os.Getpid(),
+			TunnelID:    tunnelID,
+			ConnectorID: connectorID,
+		},
+	}
+}
+
+func (w *statusFileWriter) OnTunnelEvent(event connection.Event) {
+	if event.EventType == connection.SetURL {
+		return
+	}
+	w.tracker.OnTunnelEvent(event)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.status.Connections = w.tracker.CountActiveConns()
+	if event.EventType == connection.Connected {
+		now := time.Now().UTC()
+		w.status.Protocol = event.Protocol.String()
+		w.status.LastReadyAt = &now
+	}
+	if err := w.write(); err != nil {
+		w.log.Err(err).Str(LogFieldExpandedPath, w.path).Msg("Unable to write status file")
+	}
+}
+
+// write replaces the status file with a new one, so that readers never see a partially written file.
+func (w *statusFileWriter) write() error {
+	content, err := json.Marshal(w.status)
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize status")
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(w.path), filepath.Base(w.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary status file")
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "failed to write temporary status file")
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "failed to close temporary status file")
+	}
+	return os.Rename(tmpFile.Name(), w.path)
+}
@@ -0,0 +1,57 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/connection"
+)
+
+func TestStatusFileWriter(t *testing.T) {
+	log := zerolog.Nop()
+	path := filepath.Join(t.TempDir(), "status.json")
+	tunnelID, connectorID := uuid.New(), uuid.New()
+	writer := newStatusFileWriter(path, tunnelID, connectorID, &log)
+
+	readStatus := func() tunnelStatus {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var status tunnelStatus
+		require.NoError(t, json.Unmarshal(content, &status))
+		return status
+	}
+
+	writer.OnTunnelEvent(connection.Event{Index: 0, EventType: connection.RegisteringTunnel})
+	status := readStatus()
+	assert.Equal(t, os.Getpid(), status.PID)
+	assert.Equal(t, tunnelID, status.TunnelID)
+	assert.Equal(t, connectorID, status.ConnectorID)
+	assert.Equal(t, uint(0), status.Connections)
+	assert.Nil(t, status.LastReadyAt)
+
+	writer.OnTunnelEvent(connection.Event{Index: 0, EventType: connection.Connected, Protocol: connection.QUIC, EdgeAddress: net.IPv4(1, 1, 1, 1)})
+	writer.OnTunnelEvent(connection.Event{Index: 1, EventType: connection.Connected, Protocol: connection.QUIC, EdgeAddress: net.IPv4(1, 1, 1, 2)})
+	status = readStatus()
+	assert.Equal(t, uint(2), status.Connections)
+	assert.Equal(t, "quic", status.Protocol)
+	require.NotNil(t, status.LastReadyAt)
+	lastReadyAt := *status.LastReadyAt
+
+	writer.OnTunnelEvent(connection.Event{Index: 1, EventType: connection.Disconnected})
+	status = readStatus()
+	assert.Equal(t, uint(1), status.Connections)
+	assert.Equal(t, lastReadyAt, *status.LastReadyAt)
+
+	// Only the status file is left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
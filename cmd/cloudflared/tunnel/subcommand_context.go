@@ -123,8 +123,8 @@ func (sc *subcommandContext) create(name string, credentialsFilePath string, sec
 			return nil, errors.Wrap(err, "Couldn't decode tunnel secret from base64")
 		}
 		tunnelSecret = []byte(decodedSecret)
-		if len(tunnelSecret) < 32 {
-			return nil, errors.New("Decoded tunnel secret must be at least 32 bytes long")
+		if len(tunnelSecret) < minTunnelSecretSize {
+			return nil, fmt.Errorf("Decoded tunnel secret must be at least %d bytes long", minTunnelSecretSize)
 		}
 	}
 
@@ -143,6 +143,12 @@ var (
 		EnvVars: []string{"TUNNEL_TRANSPORT_PROTOCOL"},
 		Hidden:  true,
 	})
+	requireProtocolFlag = altsrc.NewBoolFlag(&cli.BoolFlag{
+		Name:    "require-protocol",
+		Usage:   "Exit with an error instead of falling back to another protocol. With --protocol auto, the initial protocol is kept rather than switching to http2; with --protocol auto or quic, cloudflared exits as soon as QUIC is found to be blocked, without retrying it. Can't be used with --protocol http2.",
+		EnvVars: []string{"TUNNEL_REQUIRE_PROTOCOL"},
+		Hidden:  true,
+	})
 	postQuantumFlag = altsrc.NewBoolFlag(&cli.BoolFlag{
 		Name:    "post-quantum",
 		Usage:   "When given creates an experimental post-quantum secure tunnel",
@@ -161,6 +167,15 @@ var (
 		Usage:   "Inverts the sort order of the tunnel info.",
 		EnvVars: []string{"TUNNEL_INFO_INVERT_SORT"},
 	}
+	watchInfoFlag = &cli.BoolFlag{
+		Name:  "watch",
+		Usage: "Refresh the tunnel info periodically until interrupted, listing the connections opened and closed in between.",
+	}
+	watchIntervalFlag = &cli.DurationFlag{
+		Name:  "watch-interval",
+		Value: 5 * time.Second,
+		Usage: "How often the tunnel info is refreshed with --watch.",
+	}
 	cleanupClientFlag = &cli.StringFlag{
 		Name:    "connector-id",
 		Aliases: []string{"c"},
@@ -250,7 +265,7 @@ func buildCreateCommand() *cli.Command {
 
 // generateTunnelSecret as an array of 32 bytes using secure random number generator
 func generateTunnelSecret() ([]byte, error) {
-	randomBytes := make([]byte, 32)
+	randomBytes := make([]byte, minTunnelSecretSize)
 	_, err := rand.Read(randomBytes)
 	return randomBytes, err
 }
@@ -353,6 +368,9 @@ func listCommand(c *cli.Context) error {
 	if maxFetch := c.Int("max-fetch-size"); maxFetch > 0 {
 		filter.MaxFetchSize(uint(maxFetch))
 	}
+	if fetchConcurrency := c.Int("fetch-concurrency"); fetchConcurrency > 0 {
+		filter.FetchConcurrency(uint(fetchConcurrency))
+	}
 
 	tunnels, err := sc.list(filter)
 	if err != nil {
@@ -425,13 +443,7 @@ func formatAndPrintTunnelList(tunnels []*cfapi.Tunnel, showRecentlyDisconnected
 }
 
 func fmtConnections(connections []cfapi.Connection, showRecentlyDisconnected bool) string {
-	// Count connections per colo
-	numConnsPerColo := make(map[string]uint, len(connections))
-	for _, connection := range connections {
-		if !connection.IsPendingReconnect || showRecentlyDisconnected {
-			numConnsPerColo[connection.ColoName]++
-		}
-	}
+	numConnsPerColo := countConnsPerColo(connections, showRecentlyDisconnected)
 
 	// Get sorted list of colos
 	sortedColos := []string{}
@@ -448,6 +460,51 @@ func fmtConnections(connections []cfapi.Connection, showRecentlyDisconnected boo
 	return strings.Join(output, ", ")
 }
 
+func countConnsPerColo(connections []cfapi.Connection, showRecentlyDisconnected bool) map[string]uint {
+	numConnsPerColo := make(map[string]uint, len(connections))
+	for _, connection := range connections {
+		if !connection.IsPendingReconnect || showRecentlyDisconnected {
+			numConnsPerColo[connection.ColoName]++
+		}
+	}
+	return numConnsPerColo
+}
+
+// singleColoWarnings flags connectors, and the tunnel as a whole when it has several connectors, whose HA connections
+// all landed in the same colo. An outage of that data center would then disconnect them entirely.
+func singleColoWarnings(connectors []*cfapi.ActiveClient, showRecentlyDisconnected bool) []string {
+	var warnings []string
+	var allConnections []cfapi.Connection
+	activeConnectors := 0
+	for _, c := range connectors {
+		allConnections = append(allConnections, c.Connections...)
+		numConnsPerColo := countConnsPerColo(c.Connections, showRecentlyDisconnected)
+		if len(numConnsPerColo) > 0 {
+			activeConnectors++
+		}
+		if colo, count, ok := singleColo(numConnsPerColo); ok && count > 1 {
+			warnings = append(warnings, fmt.Sprintf("all %d connections of connector %s are in %s", count, c.ID, colo))
+		}
+	}
+	if activeConnectors > 1 {
+		numConnsPerColo := countConnsPerColo(allConnections, showRecentlyDisconnected)
+		if colo, count, ok := singleColo(numConnsPerColo); ok {
+			warnings = append(warnings, fmt.Sprintf("all %d connections of this tunnel are in %s", count, colo))
+		}
+	}
+	return warnings
+}
+
+func singleColo(numConnsPerColo map[string]uint) (string, uint, bool) {
+	if len(numConnsPerColo) != 1 {
+		return "", 0, false
+	}
+	for colo, count := range numConnsPerColo {
+		return colo, count, true
+	}
+	return "", 0, false
+}
+
 func buildReadyCommand() *cli.Command {
 	return &cli.Command{
 		Name:               "ready",
@@ -493,6 +550,8 @@ func buildInfoCommand() *cli.Command {
 			showRecentlyDisconnected,
 			sortInfoByFlag,
 			invertInfoSortFlag,
+			watchInfoFlag,
+			watchIntervalFlag,
 		},
 		CustomHelpTemplate: commandHelpTemplate(),
 	}
@@ -520,11 +579,30 @@ func tunnelInfo(c *cli.Context) error {
 		return err
 	}
 
-	clients, err := client.ListActiveClients(tunnelID)
+	if c.Bool(watchInfoFlag.Name) {
+		return watchTunnelInfo(c, sc, client, tunnelID)
+	}
+
+	info, err := getTunnelInfo(c, sc, client, tunnelID)
 	if err != nil {
 		return err
 	}
 
+	if outputFormat := c.String(outputFormatFlag.Name); outputFormat != "" {
+		return renderOutput(outputFormat, info)
+	}
+
+	printTunnelInfo(info, c.Bool("show-recently-disconnected"))
+	return nil
+}
+
+// getTunnelInfo returns the tunnel with its active connectors, sorted as requested by the sort flags.
+func getTunnelInfo(c *cli.Context, sc *subcommandContext, client cfapi.Client, tunnelID uuid.UUID) (Info, error) {
+	clients, err := client.ListActiveClients(tunnelID)
+	if err != nil {
+		return Info{}, err
+	}
+
 	sortBy := c.String("sort-by")
 	invalidSortField := false
 	sort.Slice(clients, func(i, j int) bool {
@@ -554,26 +632,22 @@ func tunnelInfo(c *cli.Context) error {
 
 	tunnel, err := getTunnel(sc, tunnelID)
 	if err != nil {
-		return err
+		return Info{}, err
 	}
-	info := Info{
+	return Info{
 		tunnel.ID,
 		tunnel.Name,
 		tunnel.CreatedAt,
 		clients,
-	}
-
-	if outputFormat := c.String(outputFormatFlag.Name); outputFormat != "" {
-		return renderOutput(outputFormat, info)
-	}
+	}, nil
+}
 
-	if len(clients) > 0 {
-		formatAndPrintConnectionsList(info, c.Bool("show-recently-disconnected"))
+func printTunnelInfo(info Info, showRecentlyDisconnected bool) {
+	if len(info.Connectors) > 0 {
+		formatAndPrintConnectionsList(info, showRecentlyDisconnected)
 	} else {
-		fmt.Printf("Your tunnel %s does not have any active connection.\n", tunnelID)
+		fmt.Printf("Your tunnel %s does not have any active connection.\n", info.ID)
 	}
-
-	return nil
 }
 
 func getTunnel(sc *subcommandContext, tunnelID uuid.UUID) (*cfapi.Tunnel, error) {
@@ -628,6 +702,16 @@ func formatAndPrintConnectionsList(tunnelInfo Info, showRecentlyDisconnected boo
 		)
 		_, _ = fmt.Fprintln(writer, formattedStr)
 	}
+
+	// Print a summary of where the connections of all connectors landed
+	var allConnections []cfapi.Connection
+	for _, c := range tunnelInfo.Connectors {
+		allConnections = append(allConnections, c.Connections...)
+	}
+	_, _ = fmt.Fprintf(writer, "\nCONNECTIONS PER COLO: %s\n", fmtConnections(allConnections, showRecentlyDisconnected))
+	for _, warning := range singleColoWarnings(tunnelInfo.Connectors, showRecentlyDisconnected) {
+		_, _ = fmt.Fprintf(writer, "WARNING: %s, an outage of this data center would disconnect them\n", warning)
+	}
 }
 
 func tabWriter() *tabwriter.Writer {
@@ -695,6 +779,7 @@ func buildRunCommand() *cli.Command {
 		credentialsContentsFlag,
 		postQuantumFlag,
 		selectProtocolFlag,
+		requireProtocolFlag,
 		featuresFlag,
 		tunnelTokenFlag,
 		icmpv4SrcFlag,
@@ -817,6 +902,7 @@ func buildTokenCommand() *cli.Command {
 		Description:        "cloudflared tunnel token will fetch the credentials token for a given tunnel (by its name or UUID), which is then used to run the tunnel. This command fails if the tunnel does not exist or has been deleted. Use the flag `cloudflared tunnel token --cred-file /my/path/file.json TUNNEL` to output the token to the credentials JSON file. Note: this command only works for Tunnels created since cloudflared version 2022.3.0",
 		Flags:              []cli.Flag{credentialsFileFlagCLIOnly},
 		CustomHelpTemplate: commandHelpTemplate(),
+		Subcommands:        []*cli.Command{buildTokenValidateSubcommand()},
 	}
 }
 
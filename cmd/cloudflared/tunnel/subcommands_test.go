@@ -90,6 +90,44 @@ func Test_fmtConnections(t *testing.T) {
 	}
 }
 
+func Test_singleColoWarnings(t *testing.T) {
+	connectorA := uuid.MustParse("ea550130-57fd-4463-aab1-752822231ddd")
+	connectorB := uuid.MustParse("c13c0b3b-0fbf-453c-8169-a1990fced6d0")
+	conns := func(colos ...string) []cfapi.Connection {
+		var connections []cfapi.Connection
+		for _, colo := range colos {
+			connections = append(connections, cfapi.Connection{ColoName: colo, ID: uuid.New()})
+		}
+		return connections
+	}
+
+	// A single connection can only ever be in one colo
+	require.Empty(t, singleColoWarnings([]*cfapi.ActiveClient{
+		{ID: connectorA, Connections: conns("DFW")},
+	}, false))
+
+	require.Empty(t, singleColoWarnings([]*cfapi.ActiveClient{
+		{ID: connectorA, Connections: conns("DFW", "ATL")},
+		{ID: connectorB, Connections: conns("DFW", "ATL")},
+	}, false))
+
+	require.Equal(t, []string{
+		"all 2 connections of connector ea550130-57fd-4463-aab1-752822231ddd are in DFW",
+	}, singleColoWarnings([]*cfapi.ActiveClient{
+		{ID: connectorA, Connections: conns("DFW", "DFW")},
+		{ID: connectorB, Connections: conns("DFW", "ATL")},
+	}, false))
+
+	require.Equal(t, []string{
+		"all 2 connections of connector ea550130-57fd-4463-aab1-752822231ddd are in DFW",
+		"all 2 connections of connector c13c0b3b-0fbf-453c-8169-a1990fced6d0 are in DFW",
+		"all 4 connections of this tunnel are in DFW",
+	}, singleColoWarnings([]*cfapi.ActiveClient{
+		{ID: connectorA, Connections: conns("DFW", "DFW")},
+		{ID: connectorB, Connections: conns("DFW", "DFW")},
+	}, false))
+}
+
 func TestTunnelfilePath(t *testing.T) {
 	tunnelID, err := uuid.Parse("f48d8918-bc23-4647-9d48-082c5b76de65")
 	assert.NoError(t, err)
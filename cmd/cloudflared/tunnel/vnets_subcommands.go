@@ -3,6 +3,7 @@ package tunnel
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/google/uuid"
@@ -39,6 +40,10 @@ var (
 		Usage: "Force the deletion of the virtual network even if it is being relied upon by other resources. Those" +
 			"resources will either be deleted (e.g. IP Routes) or moved to the current default virutal network.",
 	}
+	vnetShowRoutesFlag = &cli.BoolFlag{
+		Name:  "show-routes",
+		Usage: "Also show the IP routes attached to each virtual network.",
+	}
 )
 
 func buildVirtualNetworkSubcommand(hidden bool) *cli.Command {
@@ -77,7 +82,7 @@ be the current default.`,
 				Action:      cliutil.ConfiguredAction(listVirtualNetworksCommand),
 				Usage:       "Lists the virtual networks",
 				UsageText:   "cloudflared tunnel [--config FILEPATH] network list [flags]",
-				Description: "Lists the virtual networks based on the given filter flags.",
+				Description: "Lists the virtual networks based on the given filter flags. With --show-routes, the IP routes attached to each of them are listed too.",
 				Flags:       listVirtualNetworksFlags(),
 				Hidden:      hidden,
 			},
@@ -110,7 +115,7 @@ default or update an existing one to become the default.`,
 func listVirtualNetworksFlags() []cli.Flag {
 	flags := make([]cli.Flag, 0)
 	flags = append(flags, cfapi.VnetFilterFlags...)
-	flags = append(flags, outputFormatFlag)
+	flags = append(flags, outputFormatFlag, vnetShowRoutesFlag)
 	return flags
 }
 
@@ -177,7 +182,23 @@ func listVirtualNetworksCommand(c *cli.Context) error {
 		return err
 	}
 
-	if outputFormat := c.String(outputFormatFlag.Name); outputFormat != "" {
+	if c.Bool(vnetShowRoutesFlag.Name) {
+		routeFilter := cfapi.NewIPRouteFilter()
+		routeFilter.NotDeleted()
+		routes, err := sc.listRoutes(routeFilter)
+		if err != nil {
+			return errors.Wrap(err, "Could not list the IP routes of the virtual networks")
+		}
+		vnetsWithRoutes := attachRoutesToVnets(vnets, routes)
+
+		if outputFormat := c.String(outputFormatFlag.Name); outputFormat != "" {
+			return renderOutput(outputFormat, vnetsWithRoutes)
+		}
+		if len(vnetsWithRoutes) > 0 {
+			formatAndPrintVnetsWithRoutesList(vnetsWithRoutes)
+			return nil
+		}
+	} else if outputFormat := c.String(outputFormatFlag.Name); outputFormat != "" {
 		return renderOutput(outputFormat, vnets)
 	}
 
@@ -295,3 +316,57 @@ func formatAndPrintVnetsList(vnets []*cfapi.VirtualNetwork) {
 		_, _ = fmt.Fprintln(writer, formattedStr)
 	}
 }
+
+// vnetWithRoutes is a virtual network along with the IP routes attached to it.
+type vnetWithRoutes struct {
+	cfapi.VirtualNetwork `yaml:",inline"`
+	Routes               []*cfapi.DetailedRoute `json:"routes" yaml:"routes"`
+}
+
+// attachRoutesToVnets groups routes by the virtual network they are attached to. Routes without a virtual network
+// belong to the default one.
+func attachRoutesToVnets(vnets []*cfapi.VirtualNetwork, routes []*cfapi.DetailedRoute) []vnetWithRoutes {
+	var defaultVnetID uuid.UUID
+	for _, vnet := range vnets {
+		if vnet.IsDefault {
+			defaultVnetID = vnet.ID
+		}
+	}
+	routesByVnet := make(map[uuid.UUID][]*cfapi.DetailedRoute)
+	for _, route := range routes {
+		vnetID := defaultVnetID
+		if route.VNetID != nil {
+			vnetID = *route.VNetID
+		}
+		routesByVnet[vnetID] = append(routesByVnet[vnetID], route)
+	}
+
+	result := make([]vnetWithRoutes, 0, len(vnets))
+	for _, vnet := range vnets {
+		routes := routesByVnet[vnet.ID]
+		if routes == nil {
+			routes = []*cfapi.DetailedRoute{}
+		}
+		result = append(result, vnetWithRoutes{VirtualNetwork: *vnet, Routes: routes})
+	}
+	return result
+}
+
+func formatAndPrintVnetsWithRoutesList(vnets []vnetWithRoutes) {
+	writer := tabWriter()
+	defer writer.Flush()
+
+	_, _ = fmt.Fprintln(writer, "ID\tNAME\tIS DEFAULT\tCOMMENT\tCREATED\tDELETED\tROUTES\t")
+
+	for _, vnet := range vnets {
+		routes := make([]string, 0, len(vnet.Routes))
+		for _, route := range vnet.Routes {
+			routes = append(routes, fmt.Sprintf("%s via %s", route.Network.String(), route.TunnelName))
+		}
+		routesColumn := "-"
+		if len(routes) > 0 {
+			routesColumn = strings.Join(routes, ", ")
+		}
+		_, _ = fmt.Fprintf(writer, "%s%s\t\n", vnet.TableString(), routesColumn)
+	}
+}
@@ -0,0 +1,38 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/cfapi"
+)
+
+func TestAttachRoutesToVnets(t *testing.T) {
+	defaultVnet := &cfapi.VirtualNetwork{ID: uuid.New(), Name: "default", IsDefault: true}
+	otherVnet := &cfapi.VirtualNetwork{ID: uuid.New(), Name: "other"}
+	emptyVnet := &cfapi.VirtualNetwork{ID: uuid.New(), Name: "empty"}
+
+	newRoute := func(network string, vnetID *uuid.UUID) *cfapi.DetailedRoute {
+		_, ipNet, err := net.ParseCIDR(network)
+		require.NoError(t, err)
+		return &cfapi.DetailedRoute{ID: uuid.New(), Network: cfapi.CIDR(*ipNet), VNetID: vnetID}
+	}
+	implicitDefaultRoute := newRoute("10.0.0.0/8", nil)
+	explicitDefaultRoute := newRoute("172.16.0.0/12", &defaultVnet.ID)
+	otherRoute := newRoute("10.0.0.0/8", &otherVnet.ID)
+	// Routes of virtual networks that were filtered out are left out
+	filteredOutRoute := newRoute("192.168.0.0/16", &uuid.UUID{1})
+
+	vnets := attachRoutesToVnets(
+		[]*cfapi.VirtualNetwork{defaultVnet, otherVnet, emptyVnet},
+		[]*cfapi.DetailedRoute{implicitDefaultRoute, otherRoute, explicitDefaultRoute, filteredOutRoute},
+	)
+	require.Equal(t, []vnetWithRoutes{
+		{VirtualNetwork: *defaultVnet, Routes: []*cfapi.DetailedRoute{implicitDefaultRoute, explicitDefaultRoute}},
+		{VirtualNetwork: *otherVnet, Routes: []*cfapi.DetailedRoute{otherRoute}},
+		{VirtualNetwork: *emptyVnet, Routes: []*cfapi.DetailedRoute{}},
+	}, vnets)
+}
@@ -3,6 +3,7 @@ package updater
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -19,10 +20,13 @@ import (
 	"github.com/getsentry/sentry-go"
 
 	"github.com/cloudflare/cloudflared/cmd/cloudflared/cliutil"
+	"github.com/cloudflare/cloudflared/retry"
 )
 
 const (
 	clientTimeout = time.Second * 60
+	// downloadRetries is how many times a failed download is resumed before giving up on the update
+	downloadRetries = 5
 	// stop the service
 	// rename cloudflared.exe to cloudflared.exe.old
 	// rename cloudflared.exe.new to cloudflared.exe
@@ -38,6 +42,9 @@ exit /b 0`
 	batchFileName = "cfd_update.bat"
 )
 
+// Base time of the backoff between download attempts, overridden in tests
+var downloadRetryBaseTime = 2 * time.Second
+
 // Prepare some data to insert into the template.
 type batchData struct {
 	TargetPath string
@@ -153,25 +160,46 @@ func (v *WorkersVersion) UserMessage() string {
 	return v.userMessage
 }
 
-// download the file from the link in the json
+// download the file from the link in the json. Failed downloads are retried with backoff, resuming from where they
+// stopped if the server supports range requests.
 func download(url, filepath string, isCompressed bool) error {
-	client := &http.Client{
-		Timeout: clientTimeout,
+	partialPath := filepath + ".part"
+	// A partial file left by an earlier run may be of another version, so it is never resumed
+	if err := os.Remove(partialPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	defer os.Remove(partialPath)
+
+	// validator identifies the version of the file being downloaded, so that resuming doesn't mix two versions
+	var validator string
+	backoff := retry.NewBackoff(downloadRetries, downloadRetryBaseTime, false)
+	for {
+		err := downloadPartial(url, partialPath, &validator)
+		if err == nil {
+			break
+		}
+		var statusErr downloadStatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return err
+		}
+		if !backoff.Backoff(context.Background()) {
+			return err
+		}
 	}
-	resp, err := client.Get(url)
 
+	in, err := os.Open(partialPath)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer in.Close()
 
 	var r io.Reader
-	r = resp.Body
+	r = in
 
 	// compressed macos binary, need to decompress
 	if isCompressed || isCompressedFile(url) {
 		// first the gzip reader
-		gr, err := gzip.NewReader(resp.Body)
+		gr, err := gzip.NewReader(in)
 		if err != nil {
 			return err
 		}
@@ -196,6 +224,81 @@ func download(url, filepath string, isCompressed bool) error {
 	return err
 }
 
+// downloadPartial downloads url to path. If path already holds the beginning of the file, only the rest of it is
+// requested, on the condition that the file still matches validator. validator is updated from the response.
+func downloadPartial(url, path string, validator *string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if *validator != "" {
+			req.Header.Set("If-Range", *validator)
+		}
+	}
+	client := &http.Client{
+		Timeout: clientTimeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The server doesn't support range requests or the file changed, start over
+		if err := out.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		*validator = resp.Header.Get("ETag")
+		if *validator == "" {
+			*validator = resp.Header.Get("Last-Modified")
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The previous attempt got the whole file
+		return nil
+	default:
+		return downloadStatusError{url: url, statusCode: resp.StatusCode}
+	}
+
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+type downloadStatusError struct {
+	url        string
+	statusCode int
+}
+
+func (e downloadStatusError) Error() string {
+	return fmt.Sprintf("failed to download %s: %d %s", e.url, e.statusCode, http.StatusText(e.statusCode))
+}
+
+// retryable tells whether the download could succeed if tried again: client errors other than timeouts and rate
+// limiting won't go away.
+func (e downloadStatusError) retryable() bool {
+	if e.statusCode == http.StatusRequestTimeout || e.statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return e.statusCode < 400 || e.statusCode >= 500
+}
+
 // isCompressedFile is a really simple file extension check to see if this is a macos tar and gzipped
 func isCompressedFile(urlstring string) bool {
 	if strings.HasSuffix(urlstring, ".tgz") {
@@ -0,0 +1,112 @@
+package updater
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setFastDownloadRetries(t *testing.T) {
+	baseTime := downloadRetryBaseTime
+	downloadRetryBaseTime = time.Millisecond
+	t.Cleanup(func() { downloadRetryBaseTime = baseTime })
+}
+
+func TestDownloadResumesAfterFailure(t *testing.T) {
+	setFastDownloadRetries(t)
+	content := bytes.Repeat([]byte("cloudflared"), 10000)
+
+	var requests int32
+	var resumedFrom, ifRange atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// Break the connection halfway through the body
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			if conn, _, err := w.(http.Hijacker).Hijack(); assert.NoError(t, err) {
+				_ = conn.Close()
+			}
+			return
+		}
+		resumedFrom.Store(r.Header.Get("Range"))
+		ifRange.Store(r.Header.Get("If-Range"))
+		http.ServeContent(w, r, "cloudflared", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	target := filepath.Join(t.TempDir(), "cloudflared.new")
+	require.NoError(t, download(server.URL, target, false))
+
+	downloaded, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, "bytes="+strconv.Itoa(len(content)/2)+"-", resumedFrom.Load())
+	assert.Equal(t, `"v1"`, ifRange.Load())
+
+	// The partial download is cleaned up
+	_, err = os.Stat(target + ".part")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownloadIgnoresStalePartialFile(t *testing.T) {
+	setFastDownloadRetries(t)
+	content := []byte("new cloudflared")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Range"))
+		http.ServeContent(w, r, "cloudflared", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	// Left by an earlier run that crashed while downloading a bigger version
+	target := filepath.Join(t.TempDir(), "cloudflared.new")
+	require.NoError(t, os.WriteFile(target+".part", bytes.Repeat([]byte("old"), 100), 0600))
+
+	require.NoError(t, download(server.URL, target, false))
+	downloaded, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+}
+
+func TestDownloadDoesNotRetryClientErrors(t *testing.T) {
+	setFastDownloadRetries(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	target := filepath.Join(t.TempDir(), "cloudflared.new")
+	assert.Error(t, download(server.URL, target, false))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestDownloadGivesUpAfterRetries(t *testing.T) {
+	setFastDownloadRetries(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	target := filepath.Join(t.TempDir(), "cloudflared.new")
+	assert.Error(t, download(server.URL, target, false))
+	assert.Equal(t, int32(downloadRetries+1), atomic.LoadInt32(&requests))
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -217,3 +219,73 @@ func assertConfig(
 
 	require.Equal(t, config2, config)
 }
+
+func TestExpandIngressEnv(t *testing.T) {
+	t.Setenv("TEST_BACKEND_PORT", "8080")
+	t.Setenv("TEST_HOST_HEADER", "app.internal")
+
+	rawYAML := `
+originRequest:
+  httpHostHeader: ${TEST_HOST_HEADER}
+ingress:
+  - hostname: app.example.com
+    path: ^/api$
+    service: http://localhost:${TEST_BACKEND_PORT}
+    originRequest:
+      originHosts:
+        old.example.com: ${TEST_HOST_HEADER}
+  - service: http_status:404
+`
+	var config Configuration
+	require.NoError(t, yaml.Unmarshal([]byte(rawYAML), &config))
+	require.NoError(t, ExpandIngressEnv(&config))
+
+	assert.Equal(t, "app.internal", *config.OriginRequest.HTTPHostHeader)
+	assert.Equal(t, "http://localhost:8080", config.Ingress[0].Service)
+	assert.Equal(t, "^/api$", config.Ingress[0].Path, "only ${VAR} references are expanded")
+	assert.Equal(t, map[string]string{"old.example.com": "app.internal"}, config.Ingress[0].OriginRequest.OriginHosts)
+	assert.Equal(t, "http_status:404", config.Ingress[1].Service)
+
+	config = Configuration{Ingress: []UnvalidatedIngressRule{{Service: "http://localhost:${TEST_UNSET_PORT}"}}}
+	err := ExpandIngressEnv(&config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TEST_UNSET_PORT")
+	assert.Contains(t, err.Error(), "ingress rule #1")
+}
+
+func TestOriginRequestDefaults(t *testing.T) {
+	defaultsFile := filepath.Join(t.TempDir(), "defaults.yml")
+	require.NoError(t, os.WriteFile(defaultsFile, []byte(`
+connectTimeout: 10s
+noTLSVerify: true
+httpHostHeader: defaults.internal
+`), 0o600))
+
+	rawYAML := `
+originRequest:
+  connectTimeout: 5s
+ingress:
+  - hostname: app.example.com
+    service: https://localhost:8000
+    originRequest:
+      noTLSVerify: false
+  - service: http_status:404
+`
+	var config Configuration
+	require.NoError(t, yaml.Unmarshal([]byte(rawYAML), &config))
+	defaults, err := readOriginRequestDefaults(defaultsFile)
+	require.NoError(t, err)
+	applyOriginRequestDefaults(&config.OriginRequest, defaults)
+
+	// The originRequest block of the config file takes precedence over the defaults file
+	assert.Equal(t, 5*time.Second, config.OriginRequest.ConnectTimeout.Duration)
+	assert.True(t, *config.OriginRequest.NoTLSVerify)
+	assert.Equal(t, "defaults.internal", *config.OriginRequest.HTTPHostHeader)
+	// Rules are left untouched, so their settings still override the merged defaults
+	assert.False(t, *config.Ingress[0].OriginRequest.NoTLSVerify)
+	assert.Nil(t, config.Ingress[0].OriginRequest.HTTPHostHeader)
+
+	require.NoError(t, os.WriteFile(defaultsFile, []byte("originRequest:\n  noTLSVerify: true\n"), 0o600))
+	_, err = readOriginRequestDefaults(defaultsFile)
+	assert.Error(t, err, "settings must be at the top level of the defaults file")
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+)
+
+// envReference matches ${VAR}. $VAR isn't supported since $ is common in other values, e.g. in path regexes.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandIngressEnv replaces the ${VAR} references in the services and origin request settings of the ingress with
+// the value of the environment variable VAR, so that one config file can be shared by hosts with different origins.
+// It fails if a referenced variable is not set.
+func ExpandIngressEnv(c *Configuration) error {
+	if err := expandEnvInValue(reflect.ValueOf(&c.OriginRequest).Elem()); err != nil {
+		return fmt.Errorf("originRequest: %w", err)
+	}
+	for i := range c.Ingress {
+		rule := &c.Ingress[i]
+		var err error
+		if rule.Service, err = expandEnv(rule.Service); err != nil {
+			return fmt.Errorf("ingress rule #%d service: %w", i+1, err)
+		}
+		if err := expandEnvInValue(reflect.ValueOf(&rule.OriginRequest).Elem()); err != nil {
+			return fmt.Errorf("ingress rule #%d originRequest: %w", i+1, err)
+		}
+		if rule.Canary != nil {
+			if rule.Canary.Service, err = expandEnv(rule.Canary.Service); err != nil {
+				return fmt.Errorf("ingress rule #%d canary service: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expandEnvInValue expands the environment variables in every string reachable from v.
+func expandEnvInValue(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		expanded, err := expandEnv(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(expanded)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return expandEnvInValue(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := expandEnvInValue(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandEnvInValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			expanded, err := expandEnv(iter.Value().String())
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), reflect.ValueOf(expanded).Convert(v.Type().Elem()))
+		}
+	}
+	return nil
+}
+
+func expandEnv(s string) (string, error) {
+	var err error
+	expanded := envReference.ReplaceAllStringFunc(s, func(reference string) string {
+		name := envReference.FindStringSubmatch(reference)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	return expanded, err
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// OriginRequestDefaultsFlag is the flag with the path of a YAML file of originRequest settings that apply to every
+// ingress rule.
+const OriginRequestDefaultsFlag = "origin-request-defaults"
+
+// readOriginRequestDefaults reads the originRequest settings of the --origin-request-defaults file. The file has the
+// same fields as an originRequest block, at the top level. Unknown fields are rejected since the file has no other use.
+func readOriginRequestDefaults(path string) (OriginRequestConfig, error) {
+	var defaults OriginRequestConfig
+	file, err := os.Open(path)
+	if err != nil {
+		return defaults, err
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&defaults); err != nil && err != io.EOF {
+		return defaults, errors.Wrap(err, "error parsing YAML in origin request defaults file at "+path)
+	}
+	if err := expandEnvInValue(reflect.ValueOf(&defaults).Elem()); err != nil {
+		return defaults, errors.Wrap(err, "error expanding environment variables in origin request defaults file at "+path)
+	}
+	return defaults, nil
+}
+
+// applyOriginRequestDefaults sets every field of c that is not configured to its value in defaults. Fields are
+// replaced as a whole, e.g. a statusMap in c isn't merged with the one in defaults.
+func applyOriginRequestDefaults(c *OriginRequestConfig, defaults OriginRequestConfig) {
+	values := reflect.ValueOf(c).Elem()
+	defaultValues := reflect.ValueOf(defaults)
+	for i := 0; i < values.NumField(); i++ {
+		if field := values.Field(i); field.CanSet() && field.IsZero() {
+			field.Set(defaultValues.Field(i))
+		}
+	}
+}
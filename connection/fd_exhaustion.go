@@ -0,0 +1,60 @@
+package connection
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// fdExhaustionLogInterval limits how often running out of file descriptors is logged, since it typically fails
+// every request at once.
+const fdExhaustionLogInterval = 10 * time.Second
+
+var (
+	fdExhaustedDials = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Subsystem: TunnelSubsystem,
+			Name:      "fd_exhausted_dials_total",
+			Help:      "Count of connections to origins or to the edge that failed because cloudflared ran out of file descriptors",
+		},
+		[]string{"target"},
+	)
+
+	fdExhaustionLogMu    sync.Mutex
+	fdExhaustionLoggedAt time.Time
+)
+
+func init() {
+	prometheus.MustRegister(fdExhaustedDials)
+}
+
+// IsFDExhausted returns true if err was caused by the process (EMFILE) or the system (ENFILE) running out of file
+// descriptors.
+func IsFDExhausted(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
+
+// ReportFDExhausted counts a connection to target ("origin" or "edge") that failed because of IsFDExhausted, and logs
+// how to fix it at most once every fdExhaustionLogInterval. It returns false, without reporting, for other errors.
+func ReportFDExhausted(err error, target string, log *zerolog.Logger) bool {
+	if !IsFDExhausted(err) {
+		return false
+	}
+	fdExhaustedDials.WithLabelValues(target).Inc()
+
+	fdExhaustionLogMu.Lock()
+	defer fdExhaustionLogMu.Unlock()
+	if time.Since(fdExhaustionLoggedAt) < fdExhaustionLogInterval {
+		return true
+	}
+	fdExhaustionLoggedAt = time.Now()
+	log.Error().Err(err).Msgf("cloudflared ran out of file descriptors and can't open new connections to the %s. "+
+		"New requests fail until connections are closed. Raise the open files limit of cloudflared, e.g. with "+
+		"`ulimit -n` or LimitNOFILE= in its systemd unit, or lower the number of concurrent connections.", target)
+	return true
+}
@@ -0,0 +1,46 @@
+package connection
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsFDExhausted(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("socket", syscall.EMFILE)}
+	assert.True(t, IsFDExhausted(dialErr))
+	assert.True(t, IsFDExhausted(fmt.Errorf("dial origin: %w", dialErr)))
+	assert.True(t, IsFDExhausted(&EdgeQuicDialError{Cause: syscall.ENFILE}))
+	assert.False(t, IsFDExhausted(io.EOF))
+	assert.False(t, IsFDExhausted(nil))
+}
+
+func TestReportFDExhausted(t *testing.T) {
+	fdExhaustionLoggedAt = time.Time{}
+	var logs bytes.Buffer
+	log := zerolog.New(&logs)
+	before := fdExhaustedDialsCount(t, "origin")
+
+	assert.False(t, ReportFDExhausted(io.EOF, "origin", &log))
+	assert.True(t, ReportFDExhausted(syscall.EMFILE, "origin", &log))
+	assert.True(t, ReportFDExhausted(syscall.EMFILE, "origin", &log))
+
+	assert.Equal(t, before+2, fdExhaustedDialsCount(t, "origin"))
+	assert.Equal(t, 1, bytes.Count(logs.Bytes(), []byte("ran out of file descriptors")), "repeated failures should be logged once")
+}
+
+func fdExhaustedDialsCount(t *testing.T, target string) float64 {
+	var metric dto.Metric
+	require.NoError(t, fdExhaustedDials.WithLabelValues(target).Write(&metric))
+	return metric.GetCounter().GetValue()
+}
@@ -25,6 +25,14 @@ type tunnelMetrics struct {
 	// oldServerLocations stores the last server the tunnel was connected to
 	oldServerLocations map[string]string
 
+	lastErrors *prometheus.GaugeVec
+	// lastErrorLock is a mutex for oldLastErrors
+	lastErrorLock sync.Mutex
+	// oldLastErrors stores the reason of the last error of each tunnel
+	oldLastErrors map[string]string
+
+	connectDuration *prometheus.HistogramVec
+
 	regSuccess *prometheus.CounterVec
 	regFail    *prometheus.CounterVec
 	rpcFail    *prometheus.CounterVec
@@ -90,6 +98,29 @@ func initTunnelMetrics() *tunnelMetrics {
 	)
 	prometheus.MustRegister(serverLocations)
 
+	lastErrors := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Subsystem: TunnelSubsystem,
+			Name:      "connection_last_error",
+			Help:      "Reason of the last error of each tunnel. The value is the Unix time the error happened at.",
+		},
+		[]string{"connection_id", "reason"},
+	)
+	prometheus.MustRegister(lastErrors)
+
+	connectDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Subsystem: TunnelSubsystem,
+			Name:      "connect_duration_seconds",
+			Help:      "Time it took each tunnel connection to get ready, from dialing the edge to being registered",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 30},
+		},
+		[]string{"protocol"},
+	)
+	prometheus.MustRegister(connectDuration)
+
 	rpcFail := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: MetricsNamespace,
@@ -137,6 +168,9 @@ func initTunnelMetrics() *tunnelMetrics {
 	return &tunnelMetrics{
 		serverLocations:     serverLocations,
 		oldServerLocations:  make(map[string]string),
+		lastErrors:          lastErrors,
+		oldLastErrors:       make(map[string]string),
+		connectDuration:     connectDuration,
 		tunnelsHA:           newTunnelsForHA(),
 		regSuccess:          registerSuccess,
 		regFail:             registerFail,
@@ -158,6 +192,16 @@ func (t *tunnelMetrics) registerServerLocation(connectionID, loc string) {
 	t.oldServerLocations[connectionID] = loc
 }
 
+func (t *tunnelMetrics) registerLastError(connectionID, reason string) {
+	t.lastErrorLock.Lock()
+	defer t.lastErrorLock.Unlock()
+	if oldReason, ok := t.oldLastErrors[connectionID]; ok && oldReason != reason {
+		t.lastErrors.DeleteLabelValues(connectionID, oldReason)
+	}
+	t.lastErrors.WithLabelValues(connectionID, reason).SetToCurrentTime()
+	t.oldLastErrors[connectionID] = reason
+}
+
 var tunnelMetricsInternal struct {
 	sync.Once
 	metrics *tunnelMetrics
@@ -3,6 +3,7 @@ package connection
 import (
 	"net"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -58,6 +59,17 @@ func (o *Observer) logConnected(connectionID uuid.UUID, connIndex uint8, locatio
 	o.metrics.registerServerLocation(uint8ToString(connIndex), location)
 }
 
+// RecordConnectionError keeps reason as the last error of the connection, for metrics. To keep the number of metric
+// series bounded, reason must come from a small fixed set rather than from the error message.
+func (o *Observer) RecordConnectionError(connIndex uint8, reason string) {
+	o.metrics.registerLastError(uint8ToString(connIndex), reason)
+}
+
+// RecordConnectDuration records how long a connection took from dialing the edge to being registered.
+func (o *Observer) RecordConnectDuration(protocol Protocol, duration time.Duration) {
+	o.metrics.connectDuration.WithLabelValues(protocol.String()).Observe(duration.Seconds())
+}
+
 func (o *Observer) sendRegisteringEvent(connIndex uint8) {
 	o.sendEvent(Event{Index: connIndex, EventType: RegisteringTunnel})
 }
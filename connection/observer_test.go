@@ -62,6 +62,59 @@ func TestRegisterServerLocation(t *testing.T) {
 
 }
 
+func TestRecordConnectionError(t *testing.T) {
+	observer := NewObserver(&log, &log)
+	// The metrics are shared by every observer
+	observer.metrics.lastErrors.Reset()
+	clear(observer.metrics.oldLastErrors)
+
+	observer.RecordConnectionError(2, "tls")
+	assert.Equal(t, []string{"2/tls"}, getLastErrors(t, observer.metrics.lastErrors))
+
+	// Only the last error of each connection is kept
+	observer.RecordConnectionError(2, "dial")
+	observer.RecordConnectionError(3, "dial")
+	assert.ElementsMatch(t, []string{"2/dial", "3/dial"}, getLastErrors(t, observer.metrics.lastErrors))
+}
+
+func TestRecordConnectDuration(t *testing.T) {
+	observer := NewObserver(&log, &log)
+	// The metrics are shared by every observer
+	observer.metrics.connectDuration.Reset()
+
+	observer.RecordConnectDuration(QUIC, 300*time.Millisecond)
+	observer.RecordConnectDuration(QUIC, 2*time.Second)
+	observer.RecordConnectDuration(HTTP2, time.Second)
+
+	var m = &dto.Metric{}
+	assert.NoError(t, observer.metrics.connectDuration.WithLabelValues("quic").(prometheus.Histogram).Write(m))
+	assert.Equal(t, uint64(2), m.Histogram.GetSampleCount())
+	assert.InDelta(t, 2.3, m.Histogram.GetSampleSum(), 0.001)
+
+	m = &dto.Metric{}
+	assert.NoError(t, observer.metrics.connectDuration.WithLabelValues("http2").(prometheus.Histogram).Write(m))
+	assert.Equal(t, uint64(1), m.Histogram.GetSampleCount())
+}
+
+// getLastErrors returns the connection_id/reason pairs of metric, checking that they are all set to a time.
+func getLastErrors(t *testing.T, metric *prometheus.GaugeVec) []string {
+	ch := make(chan prometheus.Metric, 10)
+	metric.Collect(ch)
+	close(ch)
+	var lastErrors []string
+	for series := range ch {
+		var m = &dto.Metric{}
+		assert.NoError(t, series.Write(m))
+		assert.InDelta(t, float64(time.Now().Unix()), m.Gauge.GetValue(), 5)
+		labels := make(map[string]string)
+		for _, label := range m.Label {
+			labels[label.GetName()] = label.GetValue()
+		}
+		lastErrors = append(lastErrors, labels["connection_id"]+"/"+labels["reason"])
+	}
+	return lastErrors
+}
+
 func TestObserverEventsDontBlock(t *testing.T) {
 	observer := NewObserver(&log, &log)
 	var mu sync.Mutex
@@ -155,7 +155,8 @@ func (q *quicConnection) runStream(quicStream quic.Stream) {
 	// we are going to fuse readers/writers from stream <- cloudflared -> origin, and we want to guarantee that
 	// code executed in the code path of handleStream don't trigger an earlier close to the downstream write stream.
 	// So, we wrap the stream with a no-op write closer and only this method can actually close write side of the stream.
-	// A call to close will simulate a close to the read-side, which will fail subsequent reads.
+	// A call to close will simulate a close to the read-side, which will fail subsequent reads. The only exception is
+	// an explicit CloseWrite, which TCP streams use to propagate a half-close from the origin.
 	noCloseStream := &nopCloserReadWriter{ReadWriteCloser: stream}
 	ss := rpcquic.NewCloudflaredServer(q.handleDataStream, q.datagramHandler, q, q.rpcTimeout)
 	if err := ss.Serve(ctx, noCloseStream); err != nil {
@@ -247,6 +248,14 @@ func (s *streamReadWriteAcker) AckConnection(tracePropagation string) error {
 	return s.WriteConnectResponseData(nil, metadata...)
 }
 
+// CloseWrite closes the write side of the stream, so that the edge reads EOF while the stream can still be read from.
+func (s *streamReadWriteAcker) CloseWrite() error {
+	if closeWriter, ok := s.ReadWriteCloser.(interface{ CloseWrite() error }); ok {
+		return closeWriter.CloseWrite()
+	}
+	return nil
+}
+
 // httpResponseAdapter translates responses written by the HTTP Proxy into ones that can be used in QUIC.
 type httpResponseAdapter struct {
 	*rpcquic.RequestServerStream
@@ -325,6 +334,8 @@ func buildHTTPRequest(
 	host := metadata[HTTPHostKey]
 	isWebsocket := connectRequest.Type == pogs.ConnectionTypeWebsocket
 
+	// The edge doesn't tell us which protocol the eyeball negotiated with it, so req.Proto is always
+	// HTTP/1.1 here and must not be reported to the origin as the eyeball's protocol.
 	req, err := http.NewRequestWithContext(ctx, method, dest, body)
 	if err != nil {
 		return nil, err
@@ -410,6 +421,15 @@ func (np *nopCloserReadWriter) Read(p []byte) (n int, err error) {
 	return
 }
 
+// CloseWrite is not affected by the guarantee of Close: it is how a TCP stream propagates the end of the data from the
+// origin without closing the whole stream.
+func (np *nopCloserReadWriter) CloseWrite() error {
+	if closeWriter, ok := np.ReadWriteCloser.(interface{ CloseWrite() error }); ok {
+		return closeWriter.CloseWrite()
+	}
+	return nil
+}
+
 func (np *nopCloserReadWriter) Close() error {
 	atomic.StoreUint32(&np.closed, 1)
 
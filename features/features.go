@@ -19,6 +19,18 @@ var (
 		FeatureQUICSupportEOF,
 		FeatureManagementLogs,
 	}
+
+	// KnownFeatures are all the features this build supports, whether they are enabled by default or not.
+	KnownFeatures = []string{
+		FeatureSerializedHeaders,
+		FeatureQuickReconnects,
+		FeatureAllowRemoteConfig,
+		FeatureDatagramV2,
+		FeaturePostQuantum,
+		FeatureQUICSupportEOF,
+		FeatureManagementLogs,
+		FeatureDatagramV3,
+	}
 )
 
 func Contains(feature string) bool {
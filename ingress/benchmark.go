@@ -0,0 +1,127 @@
+package ingress
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// BenchmarkResult summarizes the test requests sent to the origin of a rule.
+type BenchmarkResult struct {
+	Attempts  int
+	Successes int
+	// ConnectTime is the average time it took to connect to the origin, TLS handshake included.
+	ConnectTime time.Duration
+	// TTFB is the average time until the first response byte, or 0 for TCP origins.
+	TTFB time.Duration
+	// LastErr is the error of the last failed attempt.
+	LastErr error
+}
+
+// Benchmark connects to the origin of the rule attempts times, through the same transport that cloudflared proxies
+// with, and reports how it went. HTTP origins get a HEAD request on a new connection each time, so the origin sees
+// and logs them. TCP origins are only dialed. Origins that have no fixed destination or that are served by
+// cloudflared itself, like hello_world or http_status, can't be benchmarked and return false.
+// The origins of the ingress must have been started.
+func (r *Rule) Benchmark(ctx context.Context, attempts int, log *zerolog.Logger) (BenchmarkResult, bool) {
+	return r.benchmark(ctx, attempts, false, log)
+}
+
+// benchmark is Benchmark where isolated requests HTTP origins through a copy of their transport, so that the
+// connections of the proxied traffic are neither closed nor used.
+func (r *Rule) benchmark(ctx context.Context, attempts int, isolated bool, log *zerolog.Logger) (BenchmarkResult, bool) {
+	var attempt func(ctx context.Context) (connectTime, ttfb time.Duration, err error)
+	switch service := r.Service.(type) {
+	case *httpService:
+		attempt = func(ctx context.Context) (time.Duration, time.Duration, error) {
+			return benchmarkHTTP(ctx, service, service.transport, isolated, service.url, r.Hostname)
+		}
+	case *unixSocketPath:
+		// The host is ignored since the transport always dials the socket
+		originURL := &url.URL{Scheme: service.scheme, Host: "localhost"}
+		attempt = func(ctx context.Context) (time.Duration, time.Duration, error) {
+			return benchmarkHTTP(ctx, service, service.transport, isolated, originURL, r.Hostname)
+		}
+	case *tcpOverWSService:
+		if service.isBastion {
+			return BenchmarkResult{}, false
+		}
+		attempt = func(ctx context.Context) (time.Duration, time.Duration, error) {
+			start := time.Now()
+			conn, err := service.EstablishConnection(ctx, service.dest, log)
+			if err != nil {
+				return 0, 0, err
+			}
+			conn.Close()
+			return time.Since(start), 0, nil
+		}
+	default:
+		return BenchmarkResult{}, false
+	}
+
+	result := BenchmarkResult{Attempts: attempts}
+	var totalConnectTime, totalTTFB time.Duration
+	for i := 0; i < attempts; i++ {
+		connectTime, ttfb, err := attempt(ctx)
+		if err != nil {
+			result.LastErr = err
+			continue
+		}
+		result.Successes++
+		totalConnectTime += connectTime
+		totalTTFB += ttfb
+	}
+	if result.Successes > 0 {
+		result.ConnectTime = totalConnectTime / time.Duration(result.Successes)
+		result.TTFB = totalTTFB / time.Duration(result.Successes)
+	}
+	return result, true
+}
+
+func benchmarkHTTP(
+	ctx context.Context,
+	originProxy HTTPOriginProxy,
+	transport *http.Transport,
+	isolated bool,
+	originURL *url.URL,
+	hostname string,
+) (connectTime, ttfb time.Duration, err error) {
+	var roundTripper http.RoundTripper = originProxy
+	if isolated {
+		// A copy of the transport has its own connections, which are all new
+		transport = transport.Clone()
+		defer transport.CloseIdleConnections()
+		roundTripper = transport
+	} else {
+		// Measure a new connection every time
+		transport.CloseIdleConnections()
+	}
+
+	var start, gotConn, gotFirstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn:              func(httptrace.GotConnInfo) { gotConn = time.Now() },
+		GotFirstResponseByte: func() { gotFirstByte = time.Now() },
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodHead, originURL.String(), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if hostname != "" && !strings.Contains(hostname, "*") {
+		req.Host = hostname
+	}
+
+	start = time.Now()
+	resp, err := roundTripper.RoundTrip(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return gotConn.Sub(start), gotFirstByte.Sub(start), nil
+}
@@ -0,0 +1,174 @@
+package ingress
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func TestRuleBenchmark(t *testing.T) {
+	var hosts []string
+	var methods []string
+	httpOrigin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		methods = append(methods, r.Method)
+	}))
+	defer httpOrigin.Close()
+
+	tcpOrigin, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcpOrigin.Close()
+	go func() {
+		for {
+			conn, err := tcpOrigin.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	closedOrigin, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, closedOrigin.Close())
+
+	ing, err := ParseIngress(&config.Configuration{
+		TunnelID: t.Name(),
+		Ingress: []config.UnvalidatedIngressRule{
+			{Hostname: "app.example.com", Service: httpOrigin.URL},
+			{Hostname: "ssh.example.com", Service: "ssh://" + tcpOrigin.Addr().String()},
+			{Hostname: "down.example.com", Service: "http://" + closedOrigin.Addr().String()},
+			{Service: "http_status:404"},
+		},
+	})
+	require.NoError(t, err)
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(TestLogger, shutdownC))
+
+	result, ok := ing.Rules[0].Benchmark(context.Background(), 3, TestLogger)
+	require.True(t, ok)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, 3, result.Successes)
+	assert.Positive(t, result.ConnectTime)
+	assert.GreaterOrEqual(t, result.TTFB, result.ConnectTime)
+	assert.NoError(t, result.LastErr)
+	assert.Equal(t, []string{"app.example.com", "app.example.com", "app.example.com"}, hosts)
+	assert.Equal(t, []string{http.MethodHead, http.MethodHead, http.MethodHead}, methods)
+
+	result, ok = ing.Rules[1].Benchmark(context.Background(), 2, TestLogger)
+	require.True(t, ok)
+	assert.Equal(t, 2, result.Successes)
+	assert.Positive(t, result.ConnectTime)
+	assert.Zero(t, result.TTFB)
+
+	result, ok = ing.Rules[2].Benchmark(context.Background(), 2, TestLogger)
+	require.True(t, ok)
+	assert.Equal(t, 0, result.Successes)
+	assert.Error(t, result.LastErr)
+
+	_, ok = ing.Rules[3].Benchmark(context.Background(), 2, TestLogger)
+	assert.False(t, ok)
+}
+
+func TestCheckCriticalOrigins(t *testing.T) {
+	upOrigin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upOrigin.Close()
+	downOrigin, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, downOrigin.Close())
+
+	tests := []struct {
+		name      string
+		rules     []config.UnvalidatedIngressRule
+		expectErr bool
+	}{
+		{
+			name: "critical origin up",
+			rules: []config.UnvalidatedIngressRule{
+				{Hostname: "app.example.com", Service: upOrigin.URL, Critical: true},
+				{Service: "http_status:404"},
+			},
+		},
+		{
+			name: "critical origin down",
+			rules: []config.UnvalidatedIngressRule{
+				{Hostname: "app.example.com", Service: upOrigin.URL, Critical: true},
+				{Hostname: "db.example.com", Service: "tcp://" + downOrigin.Addr().String(), Critical: true},
+				{Service: "http_status:404"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "other origin down",
+			rules: []config.UnvalidatedIngressRule{
+				{Hostname: "db.example.com", Service: "tcp://" + downOrigin.Addr().String()},
+				{Service: "http_status:404", Critical: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ing, err := ParseIngress(&config.Configuration{TunnelID: t.Name(), Ingress: test.rules})
+			require.NoError(t, err)
+			shutdownC := make(chan struct{})
+			defer close(shutdownC)
+			require.NoError(t, ing.StartOrigins(TestLogger, shutdownC))
+
+			err = ing.CheckCriticalOrigins(context.Background(), TestLogger)
+			if test.expectErr {
+				require.ErrorContains(t, err, "critical rule #1")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckCriticalOriginsKeepsProxiedConnections(t *testing.T) {
+	var newConns atomic.Int32
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	origin.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	origin.Start()
+	defer origin.Close()
+
+	ing, err := ParseIngress(&config.Configuration{
+		TunnelID: t.Name(),
+		Ingress: []config.UnvalidatedIngressRule{
+			{Service: origin.URL, Critical: true},
+		},
+	})
+	require.NoError(t, err)
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, ing.StartOrigins(TestLogger, shutdownC))
+	service := ing.Rules[0].Service.(*httpService)
+	proxyRequest := func() {
+		req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+		require.NoError(t, err)
+		resp, err := service.RoundTrip(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	proxyRequest()
+	require.NoError(t, ing.CheckCriticalOrigins(context.Background(), TestLogger))
+	require.NoError(t, ing.CheckCriticalOrigins(context.Background(), TestLogger))
+	// The proxied request reuses its idle connection, while each check opened one of its own
+	proxyRequest()
+	assert.EqualValues(t, 3, newConns.Load())
+}
@@ -0,0 +1,96 @@
+package ingress
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+// canaryBuckets is the resolution of the percentage split, 0.01%
+const canaryBuckets = 10000
+
+// Canary routes part of the requests of a rule to a second origin.
+type Canary struct {
+	// Service is the canary origin.
+	Service OriginService
+
+	raw config.CanaryConfig
+}
+
+func newCanary(c config.CanaryConfig) (*Canary, error) {
+	if c.Percentage < 0 || c.Percentage > 100 {
+		return nil, fmt.Errorf("canary percentage %v must be from 0 to 100", c.Percentage)
+	}
+	if c.Percentage == 0 && c.MatchHeader == "" && c.MatchCookie == "" {
+		return nil, fmt.Errorf("canary must have a percentage, a matchHeader or a matchCookie")
+	}
+	u, err := url.Parse(c.Service)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("canary service %s must be an http or https URL", c.Service)
+	}
+	if u.Path != "" {
+		return nil, fmt.Errorf("canary service %s can't have a path, the path will be the same as the eyeball request's path", c.Service)
+	}
+	return &Canary{Service: &httpService{url: u}, raw: c}, nil
+}
+
+// Selects returns whether the request goes to the canary.
+func (c *Canary) Selects(req *http.Request) bool {
+	if name, value, hasValue := strings.Cut(c.raw.MatchHeader, "="); name != "" {
+		for _, v := range req.Header.Values(name) {
+			if !hasValue || v == value {
+				return true
+			}
+		}
+	}
+	if name, value, hasValue := strings.Cut(c.raw.MatchCookie, "="); name != "" {
+		if cookie, err := req.Cookie(name); err == nil && (!hasValue || cookie.Value == value) {
+			return true
+		}
+	}
+	if c.raw.Percentage <= 0 {
+		return false
+	}
+	return float64(c.bucket(req)) < c.raw.Percentage*canaryBuckets/100
+}
+
+// bucket returns the bucket of the request from the hash of its sticky key, or a random one if it has none.
+func (c *Canary) bucket(req *http.Request) uint32 {
+	key := ""
+	if c.raw.StickyHeader != "" {
+		key = req.Header.Get(c.raw.StickyHeader)
+	}
+	if key == "" && c.raw.StickyCookie != "" {
+		if cookie, err := req.Cookie(c.raw.StickyCookie); err == nil {
+			key = cookie.Value
+		}
+	}
+	if key == "" {
+		return uint32(rand.Intn(canaryBuckets))
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return hash.Sum32() % canaryBuckets
+}
+
+// RawConfig returns the configuration the canary was created from, nil for a nil canary.
+func (c *Canary) RawConfig() *config.CanaryConfig {
+	if c == nil {
+		return nil
+	}
+	raw := c.raw
+	return &raw
+}
+
+func (c *Canary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.raw)
+}
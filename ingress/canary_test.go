@@ -0,0 +1,98 @@
+package ingress
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/config"
+)
+
+func TestCanarySelects(t *testing.T) {
+	newRequest := func(header http.Header) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+		require.NoError(t, err)
+		req.Header = header
+		return req
+	}
+	tests := []struct {
+		name     string
+		config   config.CanaryConfig
+		header   http.Header
+		expected bool
+	}{
+		{name: "header", config: config.CanaryConfig{MatchHeader: "X-Canary"}, header: http.Header{"X-Canary": {"yes"}}, expected: true},
+		{name: "missing header", config: config.CanaryConfig{MatchHeader: "X-Canary"}, header: http.Header{}},
+		{name: "header value", config: config.CanaryConfig{MatchHeader: "X-Canary=1"}, header: http.Header{"X-Canary": {"1"}}, expected: true},
+		{name: "other header value", config: config.CanaryConfig{MatchHeader: "X-Canary=1"}, header: http.Header{"X-Canary": {"0"}}},
+		{name: "cookie", config: config.CanaryConfig{MatchCookie: "beta"}, header: http.Header{"Cookie": {"session=a; beta=0"}}, expected: true},
+		{name: "cookie value", config: config.CanaryConfig{MatchCookie: "beta=1"}, header: http.Header{"Cookie": {"beta=0"}}},
+		{name: "all requests", config: config.CanaryConfig{Percentage: 100}, header: http.Header{}, expected: true},
+		{name: "match without percentage", config: config.CanaryConfig{MatchHeader: "X-Canary", Percentage: 0}, header: http.Header{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.Service = "http://canary:8080"
+			canary, err := newCanary(test.config)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, canary.Selects(newRequest(test.header)))
+		})
+	}
+}
+
+func TestCanarySticky(t *testing.T) {
+	canary, err := newCanary(config.CanaryConfig{Service: "http://canary:8080", Percentage: 30, StickyCookie: "session"})
+	require.NoError(t, err)
+
+	selected := 0
+	for i := 0; i < 1000; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+		require.NoError(t, err)
+		req.Header.Set("Cookie", fmt.Sprintf("session=user-%d", i))
+		first := canary.Selects(req)
+		// A user always gets the same origin
+		for j := 0; j < 3; j++ {
+			require.Equal(t, first, canary.Selects(req))
+		}
+		if first {
+			selected++
+		}
+	}
+	assert.InDelta(t, 300, selected, 60)
+}
+
+func TestParseCanary(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+- hostname: app.example.com
+  service: http://stable:8080
+  canary:
+    service: http://canary:8080
+    percentage: 10
+    stickyCookie: session
+- service: http_status:404
+`))
+	require.NoError(t, err)
+	require.NotNil(t, ing.Rules[0].Canary)
+	assert.Equal(t, "http://canary:8080", ing.Rules[0].Canary.Service.String())
+	assert.Equal(t, &config.CanaryConfig{Service: "http://canary:8080", Percentage: 10, StickyCookie: "session"}, ing.Rules[0].Canary.RawConfig())
+	assert.Nil(t, ing.Rules[1].Canary)
+
+	rule, err := json.Marshal(ing.Rules[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(rule), `"canary":{"service":"http://canary:8080","percentage":10,"stickyCookie":"session"}`)
+
+	for _, rule := range []string{
+		"service: tcp://localhost:22\n  canary:\n    service: http://canary:8080\n    percentage: 10",
+		"service: http://stable:8080\n  canary:\n    service: tcp://canary:22\n    percentage: 10",
+		"service: http://stable:8080\n  canary:\n    service: http://canary:8080\n    percentage: 101",
+		"service: http://stable:8080\n  canary:\n    service: http://canary:8080",
+	} {
+		_, err := ParseIngress(MustReadIngress("ingress:\n- " + rule + "\n"))
+		require.Error(t, err, rule)
+	}
+}
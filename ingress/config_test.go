@@ -3,6 +3,7 @@ package ingress
 import (
 	"encoding/json"
 	"flag"
+	"net/netip"
 	"testing"
 	"time"
 
@@ -68,6 +69,40 @@ func TestUnmarshalRemoteConfigOverridesGlobal(t *testing.T) {
 	require.True(t, remoteConfig.Ingress.Defaults.NoHappyEyeballs)
 }
 
+func TestWarpRoutingPortOverrides(t *testing.T) {
+	raw := config.WarpRoutingConfig{
+		PortOverrides: []config.WarpRoutingPortOverride{
+			{Network: "10.0.0.0/24", Port: 443, OriginPort: 8443},
+			{Network: "2001:db8::1", Port: 22, OriginPort: 2222},
+		},
+	}
+	cfg, err := NewWarpRoutingConfig(&raw)
+	require.NoError(t, err)
+	require.Equal(t, []PortOverride{
+		{Network: netip.MustParsePrefix("10.0.0.0/24"), Port: 443, OriginPort: 8443},
+		{Network: netip.MustParsePrefix("2001:db8::1/128"), Port: 22, OriginPort: 2222},
+	}, cfg.PortOverrides)
+	require.Equal(t, []config.WarpRoutingPortOverride{
+		{Network: "10.0.0.0/24", Port: 443, OriginPort: 8443},
+		{Network: "2001:db8::1/128", Port: 22, OriginPort: 2222},
+	}, cfg.RawConfig().PortOverrides)
+
+	svc := NewWarpRoutingService(cfg, 0)
+	require.Equal(t, "10.0.0.5:8443", svc.OriginDest("10.0.0.5:443"))
+	require.Equal(t, "10.0.0.5:80", svc.OriginDest("10.0.0.5:80"))
+	require.Equal(t, "10.0.1.5:443", svc.OriginDest("10.0.1.5:443"))
+	require.Equal(t, "[2001:db8::1]:2222", svc.OriginDest("[2001:db8::1]:22"))
+
+	_, err = NewWarpRoutingConfig(&config.WarpRoutingConfig{
+		PortOverrides: []config.WarpRoutingPortOverride{{Network: "10.0.0.0/33", Port: 443, OriginPort: 8443}},
+	})
+	require.Error(t, err)
+	_, err = NewWarpRoutingConfig(&config.WarpRoutingConfig{
+		PortOverrides: []config.WarpRoutingPortOverride{{Network: "10.0.0.1", Port: 443}},
+	})
+	require.Error(t, err)
+}
+
 func TestOriginRequestConfigOverrides(t *testing.T) {
 	validate := func(ing Ingress) {
 		// Rule 0 didn't override anything, so it inherits the user-specified
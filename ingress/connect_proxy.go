@@ -0,0 +1,98 @@
+package ingress
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// parseConnectProxy parses the connectProxy of a rule, which must be an http URL with a port.
+func parseConnectProxy(connectProxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(connectProxy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid connectProxy %s", connectProxy)
+	}
+	if proxyURL.Scheme != "http" || proxyURL.Port() == "" {
+		return nil, fmt.Errorf("connectProxy %s must be an http URL with a port, e.g. http://proxy.internal:3128", proxyURL.Redacted())
+	}
+	return proxyURL, nil
+}
+
+// RedactConnectProxy returns connectProxy with the password of its userinfo replaced, for serialized configurations
+// that are served or sent to the edge. An unparsable connectProxy is dropped altogether.
+func RedactConnectProxy(connectProxy string) string {
+	if connectProxy == "" {
+		return ""
+	}
+	proxyURL, err := url.Parse(connectProxy)
+	if err != nil {
+		return ""
+	}
+	return proxyURL.Redacted()
+}
+
+// dialConnectProxy connects to dest through the HTTP proxy at proxyURL with a CONNECT request.
+func dialConnectProxy(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, dest string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	// The dialer timeout also bounds the CONNECT exchange
+	deadline, ok := ctx.Deadline()
+	if dialer.Timeout > 0 && (!ok || time.Now().Add(dialer.Timeout).Before(deadline)) {
+		deadline, ok = time.Now().Add(dialer.Timeout), true
+	}
+	if ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: dest},
+		Host:   dest,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "failed to send CONNECT request to %s", proxyURL.Host)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "failed to read CONNECT response from %s", proxyURL.Host)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyURL.Host, dest, resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn that first returns the data read ahead from it while parsing the CONNECT response.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
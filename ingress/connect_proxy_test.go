@@ -0,0 +1,107 @@
+package ingress
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveConnectProxy accepts one CONNECT request, answers it with status and, if it is 200, sends greeting right after
+// the response and echoes what it receives.
+func serveConnectProxy(t *testing.T, listener net.Listener, status int, greeting string) <-chan *http.Request {
+	requests := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		req, err := http.ReadRequest(reader)
+		if !assert.NoError(t, err) {
+			return
+		}
+		requests <- req
+		resp := &http.Response{StatusCode: status, ProtoMajor: 1, ProtoMinor: 1}
+		_ = resp.Write(conn)
+		if status != http.StatusOK {
+			return
+		}
+		_, _ = conn.Write([]byte(greeting))
+		_, _ = io.Copy(conn, reader)
+	}()
+	return requests
+}
+
+func TestDialConnectProxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	requests := serveConnectProxy(t, listener, http.StatusOK, "greeting")
+
+	proxyURL, err := parseConnectProxy("http://user:secret@" + listener.Addr().String())
+	require.NoError(t, err)
+	conn, err := dialConnectProxy(context.Background(), &net.Dialer{}, proxyURL, "origin.internal:22")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req := <-requests
+	assert.Equal(t, http.MethodConnect, req.Method)
+	assert.Equal(t, "origin.internal:22", req.Host)
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", req.Header.Get("Proxy-Authorization"))
+
+	// Data the origin sent along with the CONNECT response isn't lost
+	buf := make([]byte, len("greeting"))
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "greeting", string(buf))
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf = make([]byte, len("ping"))
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
+
+func TestDialConnectProxyRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	serveConnectProxy(t, listener, http.StatusProxyAuthRequired, "")
+
+	proxyURL := &url.URL{Scheme: "http", Host: listener.Addr().String()}
+	_, err = dialConnectProxy(context.Background(), &net.Dialer{}, proxyURL, "origin.internal:22")
+	assert.ErrorContains(t, err, "407")
+}
+
+func TestTCPOverWSServiceConnectProxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	requests := serveConnectProxy(t, listener, http.StatusOK, "")
+
+	originURL, err := url.Parse("ssh://origin.internal")
+	require.NoError(t, err)
+	service := newTCPOverWSService(originURL)
+	require.NoError(t, service.start(TestLogger, nil, OriginRequestConfig{ConnectProxy: "http://" + listener.Addr().String()}))
+
+	originConn, err := service.EstablishConnection(context.Background(), "", TestLogger)
+	require.NoError(t, err)
+	defer originConn.Close()
+	assert.Equal(t, "origin.internal:22", (<-requests).Host)
+}
+
+func TestParseConnectProxy(t *testing.T) {
+	for _, connectProxy := range []string{"proxy.internal:3128", "https://proxy.internal:3128", "http://proxy.internal"} {
+		_, err := parseConnectProxy(connectProxy)
+		assert.Error(t, err, connectProxy)
+	}
+}
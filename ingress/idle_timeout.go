@@ -0,0 +1,92 @@
+package ingress
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudflare/cloudflared/socks"
+)
+
+var idleTimeoutCloses = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "tcp",
+	Name:      "idle_timeout_closes_total",
+	Help:      "Total count of TCP and SOCKS origin connections closed because they were idle for longer than tcpIdleTimeout",
+}, []string{"service"})
+
+func init() {
+	prometheus.MustRegister(idleTimeoutCloses)
+}
+
+// idleTimeoutConn is a net.Conn that fails reads and writes once no data went through it in either direction for
+// timeout, which ends the stream it is proxied in. Every read and write pushes the deadline of both directions back,
+// so a connection that only sends data one way isn't considered idle.
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+	service string
+	report  sync.Once
+}
+
+// newIdleTimeoutConn wraps conn so that it is closed after being idle for timeout. A timeout of 0 returns conn as is.
+func newIdleTimeoutConn(conn net.Conn, timeout time.Duration, service string) net.Conn {
+	if timeout <= 0 {
+		return conn
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	return &idleTimeoutConn{Conn: conn, timeout: timeout, service: service}
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.afterIO(n, err)
+	return n, err
+}
+
+func (c *idleTimeoutConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.afterIO(n, err)
+	return n, err
+}
+
+// CloseWrite closes the write direction of the connection, if supported, so that half-close keeps working.
+func (c *idleTimeoutConn) CloseWrite() error {
+	if closeWriter, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return closeWriter.CloseWrite()
+	}
+	return nil
+}
+
+func (c *idleTimeoutConn) afterIO(n int, err error) {
+	if n > 0 {
+		_ = c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		c.report.Do(func() {
+			idleTimeoutCloses.WithLabelValues(c.service).Inc()
+		})
+	}
+}
+
+// idleTimeoutDialer is a socks.Dialer whose connections are closed after being idle for timeout.
+type idleTimeoutDialer struct {
+	socks.Dialer
+	timeout time.Duration
+}
+
+func (d *idleTimeoutDialer) Dial(address string) (io.ReadWriteCloser, *socks.AddrSpec, error) {
+	conn, addr, err := d.Dialer.Dial(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	if netConn, ok := conn.(net.Conn); ok {
+		return newIdleTimeoutConn(netConn, d.timeout, ServiceSocksProxy), addr, nil
+	}
+	return conn, addr, nil
+}
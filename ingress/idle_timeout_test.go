@@ -0,0 +1,66 @@
+package ingress
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleTimeoutConn(t *testing.T) {
+	closes := func() float64 {
+		var metric dto.Metric
+		require.NoError(t, idleTimeoutCloses.WithLabelValues("test").Write(&metric))
+		return metric.GetCounter().GetValue()
+	}
+	before := closes()
+
+	origin, remote := net.Pipe()
+	defer origin.Close()
+	defer remote.Close()
+	conn := newIdleTimeoutConn(origin, 200*time.Millisecond, "test")
+
+	// Writing to the origin keeps a pending read alive past the initial deadline
+	go func() {
+		for i := 0; i < 4; i++ {
+			time.Sleep(100 * time.Millisecond)
+			_, _ = conn.Write([]byte("ping"))
+		}
+	}()
+	buf := make([]byte, 4)
+	for i := 0; i < 4; i++ {
+		_, err := remote.Read(buf)
+		require.NoError(t, err)
+	}
+
+	start := time.Now()
+	_, err := conn.Read(buf)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Greater(t, time.Since(start), 50*time.Millisecond, "deadline should have been pushed back by the writes")
+	_, err = conn.Read(buf)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Equal(t, before+1, closes(), "a connection is only counted once")
+
+	assert.Same(t, origin, newIdleTimeoutConn(origin, 0, "test"))
+}
+
+func TestParseTCPIdleTimeout(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+originRequest:
+  tcpIdleTimeout: 5m
+ingress:
+  - hostname: ssh.example.com
+    service: ssh://localhost:22
+    originRequest:
+      tcpIdleTimeout: 30s
+  - service: socks-proxy
+`))
+	require.NoError(t, err)
+	require.NoError(t, ing.StartOrigins(TestLogger, make(chan struct{})))
+	assert.Equal(t, 30*time.Second, ing.Rules[0].Service.(*tcpOverWSService).idleTimeout)
+	assert.Equal(t, 5*time.Minute, ing.Rules[1].Service.(*socksProxyOverWSService).conn.idleTimeout)
+}
@@ -1,6 +1,7 @@
 package ingress
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/url"
@@ -12,6 +13,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/net/idna"
+	yaml "gopkg.in/yaml.v3"
 
 	"github.com/cloudflare/cloudflared/config"
 	"github.com/cloudflare/cloudflared/ingress/middleware"
@@ -51,10 +53,17 @@ func (ing Ingress) FindMatchingRule(hostname, path string) (*Rule, int) {
 			return &rule, -1 - i
 		}
 	}
-	for i, rule := range ing.Rules {
-		if rule.Matches(hostname, path) {
+	if ing.hostIndex.indexes(ing.Rules) {
+		if i, ok := ing.hostIndex.find(ing.Rules, hostname, path); ok {
+			rule := ing.Rules[i]
 			return &rule, i
 		}
+	} else {
+		for i, rule := range ing.Rules {
+			if rule.Matches(hostname, path) {
+				return &rule, i
+			}
+		}
 	}
 
 	i := len(ing.Rules) - 1
@@ -78,9 +87,12 @@ func matchHost(ruleHost, reqHost string) bool {
 type Ingress struct {
 	// Set of ingress rules that are not added to remote config, e.g. management
 	InternalRules []Rule
-	// Rules that are provided by the user from remote or local configuration
+	// Rules that are provided by the user from remote or local configuration. Assigning other rules after parsing
+	// makes FindMatchingRule check them one by one; the rules themselves must not be modified in place.
 	Rules    []Rule              `json:"ingress"`
 	Defaults OriginRequestConfig `json:"originRequest"`
+	// hostIndex is built by ParseIngress to find the matching rule without checking every rule
+	hostIndex *hostIndex
 }
 
 // ParseIngress parses ingress rules, but does not send HTTP requests to the origins.
@@ -91,6 +103,20 @@ func ParseIngress(conf *config.Configuration) (Ingress, error) {
 	return validateIngress(conf.Ingress, originRequestFromConfig(conf.OriginRequest))
 }
 
+// ValidateBytes parses ingress rules from a YAML document in the format of the cloudflared config file and validates
+// them the same way `cloudflared tunnel ingress validate` does, including the expansion of ${VAR} references. Like
+// ParseIngress, it does not start or contact the origins.
+func ValidateBytes(b []byte) (Ingress, error) {
+	var conf config.Configuration
+	if err := yaml.Unmarshal(b, &conf); err != nil {
+		return Ingress{}, errors.Wrap(err, "error parsing YAML")
+	}
+	if err := config.ExpandIngressEnv(&conf); err != nil {
+		return Ingress{}, err
+	}
+	return ParseIngress(&conf)
+}
+
 // ParseIngressFromConfigAndCLI will parse the configuration rules from config files for ingress
 // rules and then attempt to parse CLI for ingress rules.
 // Will always return at least one valid ingress rule. If none are provided by the user, the default
@@ -208,6 +234,28 @@ func (ing Ingress) StartOrigins(
 		if err := rule.Service.start(log, shutdownC, rule.Config); err != nil {
 			return errors.Wrapf(err, "Error starting local service %s", rule.Service)
 		}
+		if rule.Canary != nil {
+			if err := rule.Canary.Service.start(log, shutdownC, rule.Config); err != nil {
+				return errors.Wrapf(err, "Error starting canary service %s", rule.Canary.Service)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckCriticalOrigins returns an error if the origin of a critical rule can't be reached. Each origin is probed once,
+// like by Rule.Benchmark, but on connections of its own so that the proxied traffic isn't affected. Origins that can't
+// be probed are considered reachable. The origins must have been started.
+func (ing Ingress) CheckCriticalOrigins(ctx context.Context, log *zerolog.Logger) error {
+	for i := range ing.Rules {
+		rule := &ing.Rules[i]
+		if !rule.Critical {
+			continue
+		}
+		result, ok := rule.benchmark(ctx, 1, true, log)
+		if ok && result.Successes == 0 {
+			return errors.Wrapf(result.LastErr, "origin %s of critical rule #%d is unreachable", rule.Service, i)
+		}
 	}
 	return nil
 }
@@ -246,6 +294,32 @@ func validateIngress(ingress []config.UnvalidatedIngressRule, defaults OriginReq
 	rules := make([]Rule, len(ingress))
 	for i, r := range ingress {
 		cfg := setConfig(defaults, r.OriginRequest)
+		if err := cfg.LoadErrorPage(); err != nil {
+			return Ingress{}, err
+		}
+		if err := cfg.validateOriginDNS(); err != nil {
+			return Ingress{}, err
+		}
+		if err := cfg.validateStatusMap(); err != nil {
+			return Ingress{}, err
+		}
+		if err := cfg.validateLocalHealth(); err != nil {
+			return Ingress{}, err
+		}
+		if err := cfg.validateALPNProtocols(); err != nil {
+			return Ingress{}, err
+		}
+		if err := cfg.validateForwardedHeaders(); err != nil {
+			return Ingress{}, err
+		}
+		if err := cfg.validatePinnedPublicKeys(); err != nil {
+			return Ingress{}, err
+		}
+		if cfg.ConnectProxy != "" {
+			if _, err := parseConnectProxy(cfg.ConnectProxy); err != nil {
+				return Ingress{}, err
+			}
+		}
 		var service OriginService
 
 		if prefix := "unix:"; strings.HasPrefix(r.Service, prefix) {
@@ -311,6 +385,17 @@ func validateIngress(ingress []config.UnvalidatedIngressRule, defaults OriginReq
 			}
 		}
 
+		var canary *Canary
+		if r.Canary != nil {
+			if _, ok := service.(*httpService); !ok {
+				return Ingress{}, fmt.Errorf("rule #%d: canary is only supported for http and https services", i+1)
+			}
+			var err error
+			if canary, err = newCanary(*r.Canary); err != nil {
+				return Ingress{}, errors.Wrapf(err, "rule #%d", i+1)
+			}
+		}
+
 		var handlers []middleware.Handler
 		if access := r.OriginRequest.Access; access != nil {
 			if err := validateAccessConfiguration(access); err != nil {
@@ -353,9 +438,11 @@ func validateIngress(ingress []config.UnvalidatedIngressRule, defaults OriginReq
 			Path:             pathRegexp,
 			Handlers:         handlers,
 			Config:           cfg,
+			Critical:         r.Critical,
+			Canary:           canary,
 		}
 	}
-	return Ingress{Rules: rules, Defaults: defaults}, nil
+	return Ingress{Rules: rules, Defaults: defaults, hostIndex: newHostIndex(rules)}, nil
 }
 
 func validateHostname(r config.UnvalidatedIngressRule, ruleIndex, totalRules int) error {
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -43,6 +46,187 @@ ingress:
 	require.Equal(t, "https", s.scheme)
 }
 
+func TestParseMultipleUnixSockets(t *testing.T) {
+	rawYAML := `
+ingress:
+- hostname: app.example.com
+  service: unix:/run/app.sock
+- hostname: api.example.com
+  service: unix+tls:/run/api.sock
+- service: unix:/run/default.sock
+`
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+	require.Len(t, ing.Rules, 3)
+	expected := []unixSocketPath{
+		{path: "/run/app.sock", scheme: "http"},
+		{path: "/run/api.sock", scheme: "https"},
+		{path: "/run/default.sock", scheme: "http"},
+	}
+	for i, rule := range ing.Rules {
+		s, ok := rule.Service.(*unixSocketPath)
+		require.True(t, ok)
+		require.Equal(t, expected[i].path, s.path)
+		require.Equal(t, expected[i].scheme, s.scheme)
+	}
+}
+
+func TestParseErrorPage(t *testing.T) {
+	errorPage := []byte("<html><body>We'll be right back</body></html>")
+	errorPagePath := filepath.Join(t.TempDir(), "error.html")
+	require.NoError(t, os.WriteFile(errorPagePath, errorPage, 0o600))
+
+	rawYAML := fmt.Sprintf(`
+originRequest:
+  errorPage: %s
+ingress:
+- hostname: app.example.com
+  service: http://localhost:8000
+- service: http_status:404
+`, errorPagePath)
+	ing, err := ParseIngress(MustReadIngress(rawYAML))
+	require.NoError(t, err)
+
+	// The page is served from memory once loaded
+	require.NoError(t, os.Remove(errorPagePath))
+	require.Equal(t, errorPage, ing.Rules[0].Config.ErrorPageContent())
+
+	// A missing page fails the configuration rather than the first failed request
+	_, err = ParseIngress(MustReadIngress(rawYAML))
+	require.Error(t, err)
+}
+
+func TestParseOriginDNS(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+- hostname: app.example.com
+  service: http://app.internal:8000
+  originRequest:
+    originResolver: 10.0.0.53:53
+    originHosts:
+      app.internal: 10.0.0.5
+- service: http_status:404
+`))
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.53:53", ing.Rules[0].Config.OriginResolver)
+	require.Equal(t, map[string]string{"app.internal": "10.0.0.5"}, ing.Rules[0].Config.OriginHosts)
+
+	_, err = ParseIngress(MustReadIngress(`
+ingress:
+- service: http://app.internal:8000
+  originRequest:
+    originResolver: 10.0.0.53
+`))
+	require.Error(t, err)
+
+	_, err = ParseIngress(MustReadIngress(`
+ingress:
+- service: http://app.internal:8000
+  originRequest:
+    originHosts:
+      app.internal: app.other
+`))
+	require.Error(t, err)
+}
+
+func TestParseStatusMap(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+- service: http://localhost:8000
+  originRequest:
+    statusMap:
+      502: 503
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{502: 503}, ing.Rules[0].Config.StatusMap)
+
+	_, err = ParseIngress(MustReadIngress(`
+ingress:
+- service: http://localhost:8000
+  originRequest:
+    statusMap:
+      101: 200
+`))
+	require.Error(t, err)
+}
+
+func TestParseLocalHealth(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+- service: http://localhost:8000
+  originRequest:
+    localHealthPath: /cf-health
+    localHealthStatus: 204
+`))
+	require.NoError(t, err)
+	require.Equal(t, "/cf-health", ing.Rules[0].Config.LocalHealthPath)
+	require.Equal(t, 204, ing.Rules[0].Config.LocalHealthStatus)
+
+	for _, originRequest := range []string{"localHealthPath: cf-health", "localHealthStatus: 600"} {
+		_, err = ParseIngress(MustReadIngress(`
+ingress:
+- service: http://localhost:8000
+  originRequest:
+    ` + originRequest + `
+`))
+		require.Error(t, err, originRequest)
+	}
+}
+
+func TestParseALPNProtocols(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+ingress:
+- service: https://localhost:8000
+  originRequest:
+    http2Origin: true
+    alpnProtocols: [h2, http/1.1]
+`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"h2", "http/1.1"}, ing.Rules[0].Config.ALPNProtocols)
+
+	_, err = ParseIngress(MustReadIngress(`
+ingress:
+- service: https://localhost:8000
+  originRequest:
+    alpnProtocols: [h2]
+`))
+	require.Error(t, err)
+}
+
+func TestParseForwardedHeaders(t *testing.T) {
+	ing, err := ParseIngress(MustReadIngress(`
+originRequest:
+  xForwardedFor: replace
+ingress:
+- service: https://localhost:8000
+  originRequest:
+    xForwardedProto: omit
+    xForwardedHost: replace
+`))
+	require.NoError(t, err)
+	require.Equal(t, ForwardedHeaderReplace, ing.Rules[0].Config.XForwardedFor)
+	require.Equal(t, ForwardedHeaderOmit, ing.Rules[0].Config.XForwardedProto)
+	require.Equal(t, ForwardedHeaderReplace, ing.Rules[0].Config.XForwardedHost)
+
+	_, err = ParseIngress(MustReadIngress(`
+ingress:
+- service: https://localhost:8000
+  originRequest:
+    xForwardedFor: keep
+`))
+	require.Error(t, err)
+
+	for _, option := range []string{"xForwardedProto", "xForwardedHost"} {
+		_, err = ParseIngress(MustReadIngress(fmt.Sprintf(`
+ingress:
+- service: https://localhost:8000
+  originRequest:
+    %s: append
+`, option)))
+		require.Error(t, err, option)
+	}
+}
+
 func TestParseIngressNilConfig(t *testing.T) {
 	_, err := ParseIngress(nil)
 	require.Error(t, err)
@@ -467,6 +651,53 @@ func ipRulePrefix(s string) *string {
 	return &s
 }
 
+func TestValidateBytes(t *testing.T) {
+	ing, err := ValidateBytes([]byte(`
+originRequest:
+  noTLSVerify: true
+ingress:
+  - hostname: tunnel1.example.com
+    service: https://localhost:8000
+    originRequest:
+      connectTimeout: 10s
+  - service: http_status:404
+`))
+	require.NoError(t, err)
+	require.Len(t, ing.Rules, 2)
+	require.Equal(t, "tunnel1.example.com", ing.Rules[0].Hostname)
+	require.Equal(t, "https://localhost:8000", ing.Rules[0].Service.String())
+	require.Equal(t, 10*time.Second, ing.Rules[0].Config.ConnectTimeout.Duration)
+	require.True(t, ing.Rules[0].Config.NoTLSVerify)
+	require.True(t, ing.Defaults.NoTLSVerify)
+
+	_, err = ValidateBytes([]byte(`
+ingress:
+  - hostname: tunnel1.example.com
+    service: https://localhost:8000
+`))
+	require.ErrorIs(t, err, errLastRuleNotCatchAll)
+
+	_, err = ValidateBytes([]byte(`tunnel: abc`))
+	require.ErrorIs(t, err, ErrNoIngressRules)
+
+	_, err = ValidateBytes([]byte(`ingress: [`))
+	require.Error(t, err)
+
+	t.Setenv("TEST_VALIDATE_PORT", "8001")
+	ing, err = ValidateBytes([]byte(`
+ingress:
+  - service: https://localhost:${TEST_VALIDATE_PORT}
+`))
+	require.NoError(t, err)
+	require.Equal(t, "https://localhost:8001", ing.Rules[0].Service.String())
+
+	_, err = ValidateBytes([]byte(`
+ingress:
+  - service: https://localhost:${TEST_VALIDATE_UNSET_PORT}
+`))
+	require.ErrorContains(t, err, "TEST_VALIDATE_UNSET_PORT")
+}
+
 func TestSingleOriginSetsConfig(t *testing.T) {
 	flagSet := flag.NewFlagSet(t.Name(), flag.PanicOnError)
 	flagSet.Bool("hello-world", true, "")
@@ -792,6 +1023,84 @@ ingress:
 	}
 }
 
+func TestFindMatchingRuleWithHostIndex(t *testing.T) {
+	indexed, err := ParseIngress(MustReadIngress(`
+ingress:
+ - hostname: api.example.com
+   path: ^/v1
+   service: https://localhost:8000
+ - hostname: "*.example.com"
+   path: ^/admin
+   service: https://localhost:8001
+ - hostname: api.example.com
+   service: https://localhost:8002
+ - hostname: "*.example.com"
+   service: https://localhost:8003
+ - hostname: môô.example.org
+   service: https://localhost:8004
+ - hostname: api.example.com
+   service: https://localhost:8005
+ - service: https://localhost:8006
+`))
+	require.NoError(t, err)
+	require.NotNil(t, indexed.hostIndex)
+	linear := Ingress{Rules: indexed.Rules}
+
+	for _, host := range []string{"api.example.com", "api.example.com:443", "www.example.com", "example.com", "môô.example.org", "xn--m-xgaa.example.org", "other.org"} {
+		for _, path := range []string{"/", "/v1/users", "/admin"} {
+			_, want := linear.FindMatchingRule(host, path)
+			_, got := indexed.FindMatchingRule(host, path)
+			assert.Equal(t, want, got, "host=%s path=%s", host, path)
+		}
+	}
+	_, ruleIndex := indexed.FindMatchingRule("www.example.com", "/admin")
+	assert.Equal(t, 1, ruleIndex)
+	_, ruleIndex = indexed.FindMatchingRule("xn--m-xgaa.example.org", "/")
+	assert.Equal(t, 4, ruleIndex)
+	// Rules assigned after parsing aren't looked up with the index built for the previous ones, even with the same
+	// number of rules
+	replaced := make([]Rule, len(indexed.Rules))
+	copy(replaced, indexed.Rules)
+	replaced[0], replaced[len(replaced)-1] = replaced[len(replaced)-1], replaced[0]
+	indexed.Rules = replaced
+	_, ruleIndex = indexed.FindMatchingRule("other.org", "/")
+	assert.Equal(t, 0, ruleIndex)
+}
+
+// BenchmarkFindMatchManyRules compares finding the rule of the last hostname of a large config with the host index
+// built by ParseIngress and with a linear scan of the rules.
+func BenchmarkFindMatchManyRules(b *testing.B) {
+	var rulesYAML strings.Builder
+	rulesYAML.WriteString("ingress:\n")
+	const numRules = 5000
+	for i := 0; i < numRules; i++ {
+		fmt.Fprintf(&rulesYAML, " - hostname: tunnel%d.example.com\n   service: https://localhost:8000\n", i)
+	}
+	rulesYAML.WriteString(" - service: http_status:404\n")
+
+	indexed, err := ParseIngress(MustReadIngress(rulesYAML.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	linear := Ingress{Rules: indexed.Rules}
+	lastHost := fmt.Sprintf("tunnel%d.example.com", numRules-1)
+
+	for _, test := range []struct {
+		name string
+		ing  Ingress
+	}{
+		{name: "indexed", ing: indexed},
+		{name: "linear", ing: linear},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				test.ing.FindMatchingRule(lastHost, "/")
+				test.ing.FindMatchingRule("unknown.example.com", "/")
+			}
+		})
+	}
+}
+
 func TestParseAccessConfig(t *testing.T) {
 	tests := []struct {
 		name        string
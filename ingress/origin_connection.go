@@ -30,6 +30,10 @@ func DefaultStreamHandler(originConn io.ReadWriter, remoteConn net.Conn, log *ze
 	stream.Pipe(originConn, remoteConn, log)
 }
 
+// tcpHalfCloseTimeout is how long a TCP connection is kept open after one of its sides closed its write direction,
+// waiting for the other side to finish sending.
+const tcpHalfCloseTimeout = 30 * time.Second
+
 // tcpConnection is an OriginConnection that directly streams to raw TCP.
 type tcpConnection struct {
 	net.Conn
@@ -37,8 +41,26 @@ type tcpConnection struct {
 	logger       *zerolog.Logger
 }
 
+// Stream proxies the connection in both directions. When both the tunnel connection and the origin connection can be
+// half-closed, the end of one direction is propagated to the other side with CloseWrite, and the other direction keeps
+// going, so that protocols that rely on half-close work end to end.
 func (tc *tcpConnection) Stream(_ context.Context, tunnelConn io.ReadWriter, _ *zerolog.Logger) {
-	stream.Pipe(tunnelConn, tc, tc.logger)
+	tunnelStream, ok := tunnelConn.(stream.Stream)
+	if _, originCanCloseWrite := tc.Conn.(stream.WriteCloser); !ok || !originCanCloseWrite {
+		stream.Pipe(tunnelConn, tc, tc.logger)
+		return
+	}
+	if err := stream.PipeBidirectional(tunnelStream, tc, tcpHalfCloseTimeout, tc.logger); err != nil {
+		tc.logger.Debug().Err(err).Msg("TCP connection closed before both sides finished")
+	}
+}
+
+// CloseWrite closes the write direction of the origin connection, so that the origin reads EOF.
+func (tc *tcpConnection) CloseWrite() error {
+	if closeWriter, ok := tc.Conn.(stream.WriteCloser); ok {
+		return closeWriter.CloseWrite()
+	}
+	return nil
 }
 
 func (tc *tcpConnection) Write(b []byte) (int, error) {
@@ -84,12 +106,17 @@ func (wc *tcpOverWSConnection) Close() {
 // details in the packet.
 type socksProxyOverWSConnection struct {
 	accessPolicy *ipaccess.Policy
+	idleTimeout  time.Duration
 }
 
 func (sp *socksProxyOverWSConnection) Stream(ctx context.Context, tunnelConn io.ReadWriter, log *zerolog.Logger) {
 	wsCtx, cancel := context.WithCancel(ctx)
 	wsConn := websocket.NewConn(wsCtx, tunnelConn, log)
-	socks.StreamNetHandler(wsConn, sp.accessPolicy, log)
+	dialer := socks.NewNetDialer()
+	if sp.idleTimeout > 0 {
+		dialer = &idleTimeoutDialer{Dialer: dialer, timeout: sp.idleTimeout}
+	}
+	socks.StreamNetHandler(wsConn, dialer, sp.accessPolicy, log)
 	cancel()
 	// Makes sure wsConn stops sending ping before terminating the stream
 	wsConn.Close()
@@ -69,6 +69,68 @@ func TestStreamTCPConnection(t *testing.T) {
 	require.NoError(t, errGroup.Wait())
 }
 
+func TestStreamTCPConnectionHalfClose(t *testing.T) {
+	// The origin answers once it has read the whole request, like protocols that rely on half-close
+	originListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer originListener.Close()
+	go func() {
+		conn, err := originListener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, err := io.ReadAll(conn)
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write(append([]byte("echo-"), request...))
+	}()
+	cfdConn, err := net.Dial("tcp", originListener.Addr().String())
+	require.NoError(t, err)
+	tcpConn := tcpConnection{
+		Conn:   cfdConn,
+		logger: TestLogger,
+	}
+	defer tcpConn.Close()
+
+	edgeListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer edgeListener.Close()
+	eyeballConn, err := net.Dial("tcp", edgeListener.Addr().String())
+	require.NoError(t, err)
+	defer eyeballConn.Close()
+	edgeConn, err := edgeListener.Accept()
+	require.NoError(t, err)
+	defer edgeConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testStreamTimeout)
+	defer cancel()
+
+	errGroup, ctx := errgroup.WithContext(ctx)
+	errGroup.Go(func() error {
+		if _, err := eyeballConn.Write(testMessage); err != nil {
+			return err
+		}
+		if err := eyeballConn.(*net.TCPConn).CloseWrite(); err != nil {
+			return err
+		}
+		response, err := io.ReadAll(eyeballConn)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(testResponse, response) {
+			return fmt.Errorf("expected response %q, got %q", testResponse, response)
+		}
+		return nil
+	})
+
+	tcpConn.Stream(ctx, edgeConn, TestLogger)
+	// The edge ends the stream once Stream returns
+	edgeConn.Close()
+	require.NoError(t, errGroup.Wait())
+}
+
 func TestDefaultStreamWSOverTCPConnection(t *testing.T) {
 	cfdConn, originConn := net.Pipe()
 	tcpOverWSConn := tcpOverWSConnection{
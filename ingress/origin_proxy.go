@@ -2,7 +2,6 @@ package ingress
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -47,31 +46,20 @@ func (o *httpService) RoundTrip(req *http.Request) (*http.Response, error) {
 	if o.hostHeader != "" {
 		// For incoming requests, the Host header is promoted to the Request.Host field and removed from the Header map.
 		// Pass the original Host header as X-Forwarded-Host.
-		req.Header.Set("X-Forwarded-Host", req.Host)
+		if !o.skipForwardedHost {
+			req.Header.Set("X-Forwarded-Host", req.Host)
+		}
 		req.Host = o.hostHeader
 	}
 
 	if o.matchSNIToHost {
-		o.SetOriginServerName(req)
+		// The transport dials with the context of the request, so its TLS dialer picks the server name up from there
+		req = req.WithContext(context.WithValue(req.Context(), originServerNameKey{}, req.Host))
 	}
 
 	return o.transport.RoundTrip(req)
 }
 
-func (o *httpService) SetOriginServerName(req *http.Request) {
-	o.transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-		conn, err := o.transport.DialContext(ctx, network, addr)
-		if err != nil {
-			return nil, err
-		}
-		return tls.Client(conn, &tls.Config{
-			RootCAs:            o.transport.TLSClientConfig.RootCAs,
-			InsecureSkipVerify: o.transport.TLSClientConfig.InsecureSkipVerify,
-			ServerName:         req.Host,
-		}), nil
-	}
-}
-
 func (o *statusCode) RoundTrip(_ *http.Request) (*http.Response, error) {
 	if o.defaultResp {
 		o.log.Warn().Msgf(ErrNoIngressRulesCLI.Error())
@@ -105,12 +93,17 @@ func (o *tcpOverWSService) EstablishConnection(ctx context.Context, dest string,
 		dest = o.dest
 	}
 
-	conn, err := o.dialer.DialContext(ctx, "tcp", dest)
+	var conn net.Conn
+	if o.connectProxy != nil {
+		conn, err = dialConnectProxy(ctx, &o.dialer, o.connectProxy, originAddress(o.originHosts, dest))
+	} else {
+		conn, err = o.dialer.DialContext(ctx, "tcp", originAddress(o.originHosts, dest))
+	}
 	if err != nil {
 		return nil, err
 	}
 	originConn := &tcpOverWSConnection{
-		conn:          conn,
+		conn:          newIdleTimeoutConn(conn, o.idleTimeout, o.metricLabel()),
 		streamHandler: o.streamHandler,
 	}
 	return originConn, nil
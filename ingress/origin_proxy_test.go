@@ -1,17 +1,30 @@
 package ingress
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
+	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
 
 	"github.com/cloudflare/cloudflared/carrier"
 	"github.com/cloudflare/cloudflared/websocket"
@@ -186,6 +199,546 @@ func TestHTTPServiceUsesIngressRuleScheme(t *testing.T) {
 	}
 }
 
+func TestHTTPServiceDisableCompression(t *testing.T) {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	_, err := gz.Write([]byte("compressed body"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(body.Bytes())
+	}
+	origin := httptest.NewServer(http.HandlerFunc(handler))
+	defer origin.Close()
+
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		disableCompression bool
+		expectedBody       []byte
+		expectedEncoding   string
+	}{
+		{
+			disableCompression: false,
+			expectedBody:       []byte("compressed body"),
+			expectedEncoding:   "",
+		},
+		{
+			disableCompression: true,
+			expectedBody:       body.Bytes(),
+			expectedEncoding:   "gzip",
+		},
+	}
+	for _, test := range tests {
+		httpService := &httpService{
+			url: originURL,
+		}
+		shutdownC := make(chan struct{})
+		require.NoError(t, httpService.start(TestLogger, shutdownC, OriginRequestConfig{DisableCompression: test.disableCompression}))
+
+		req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+		require.NoError(t, err)
+
+		resp, err := httpService.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, test.expectedEncoding, resp.Header.Get("Content-Encoding"))
+
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, test.expectedBody, respBody)
+		close(shutdownC)
+	}
+}
+
+func TestHTTPServiceTLSSessionCache(t *testing.T) {
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%t", r.TLS.DidResume)
+	}))
+	defer origin.Close()
+
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		tlsSessionCache bool
+		expectResumed   string
+	}{
+		{tlsSessionCache: false, expectResumed: "false"},
+		{tlsSessionCache: true, expectResumed: "true"},
+	}
+	for _, test := range tests {
+		httpService := &httpService{
+			url: originURL,
+		}
+		shutdownC := make(chan struct{})
+		cfg := OriginRequestConfig{NoTLSVerify: true, TLSSessionCache: test.tlsSessionCache}
+		require.NoError(t, httpService.start(TestLogger, shutdownC, cfg))
+
+		resumed := make([]string, 0, 2)
+		for i := 0; i < 2; i++ {
+			req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+			require.NoError(t, err)
+			resp, err := httpService.RoundTrip(req)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			resumed = append(resumed, string(body))
+
+			// Force the next request onto a new connection
+			httpService.transport.CloseIdleConnections()
+		}
+		require.Equal(t, []string{"false", test.expectResumed}, resumed)
+		close(shutdownC)
+	}
+}
+
+func TestHTTPServiceHTTP2MaxReadFrameSize(t *testing.T) {
+	tests := []struct {
+		maxReadFrameSize uint
+		expectFrameSize  uint32
+	}{
+		{maxReadFrameSize: 1 << 20, expectFrameSize: 1 << 20},
+		{maxReadFrameSize: 1 << 12, expectFrameSize: 1 << 14},
+		// Larger than the HTTP/2 maximum
+		{maxReadFrameSize: 1 << 30, expectFrameSize: 1<<24 - 1},
+	}
+	for _, test := range tests {
+		// Reads the SETTINGS the client sends right after the connection preface
+		origin, err := tls.Listen("tcp", "127.0.0.1:0", newH2TLSConfig(t))
+		require.NoError(t, err)
+		settingsC := make(chan *http2.SettingsFrame, 1)
+		go func() {
+			conn, err := origin.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			preface := make([]byte, len(http2.ClientPreface))
+			if _, err := io.ReadFull(conn, preface); err != nil {
+				return
+			}
+			frame, err := http2.NewFramer(io.Discard, conn).ReadFrame()
+			if err != nil {
+				return
+			}
+			settings, _ := frame.(*http2.SettingsFrame)
+			settingsC <- settings
+		}()
+
+		httpService := &httpService{
+			url: &url.URL{Scheme: "https", Host: origin.Addr().String()},
+		}
+		shutdownC := make(chan struct{})
+		cfg := OriginRequestConfig{NoTLSVerify: true, Http2Origin: true, Http2MaxReadFrameSize: test.maxReadFrameSize}
+		require.NoError(t, httpService.start(TestLogger, shutdownC, cfg))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpService.url.String(), nil)
+		require.NoError(t, err)
+		go func() {
+			if resp, err := httpService.RoundTrip(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+
+		select {
+		case settings := <-settingsC:
+			require.NotNil(t, settings)
+			frameSize, ok := settings.Value(http2.SettingMaxFrameSize)
+			require.True(t, ok)
+			require.Equal(t, test.expectFrameSize, frameSize)
+		case <-ctx.Done():
+			t.Fatal("client did not send its HTTP/2 settings")
+		}
+		cancel()
+		close(shutdownC)
+		require.NoError(t, origin.Close())
+	}
+}
+
+// newH2TLSConfig returns a TLS config with a self-signed certificate that negotiates HTTP/2.
+func newH2TLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}},
+		NextProtos:   []string{http2.NextProtoTLS},
+	}
+}
+
+func TestHTTPServiceOriginDNS(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer origin.Close()
+	_, port, err := net.SplitHostPort(origin.Listener.Addr().String())
+	require.NoError(t, err)
+
+	// Only this resolver knows about origin.internal
+	resolverConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	resolver := &dns.Server{PacketConn: resolverConn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(query)
+		if q := query.Question[0]; q.Name == "origin.internal." && q.Qtype == dns.TypeA {
+			resp.Answer = append(resp.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.IPv4(127, 0, 0, 1),
+			})
+		}
+		_ = w.WriteMsg(resp)
+	})}
+	go func() { _ = resolver.ActivateAndServe() }()
+	defer resolver.Shutdown()
+
+	tests := []struct {
+		name string
+		cfg  OriginRequestConfig
+	}{
+		{name: "resolver", cfg: OriginRequestConfig{OriginResolver: resolverConn.LocalAddr().String()}},
+		{name: "hosts", cfg: OriginRequestConfig{OriginHosts: map[string]string{"origin.internal": "127.0.0.1"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			httpService := &httpService{
+				url: &url.URL{Scheme: "http", Host: net.JoinHostPort("origin.internal", port)},
+			}
+			shutdownC := make(chan struct{})
+			defer close(shutdownC)
+			require.NoError(t, httpService.start(TestLogger, shutdownC, test.cfg))
+
+			req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+			require.NoError(t, err)
+			resp, err := httpService.RoundTrip(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		})
+	}
+}
+
+func TestHTTPServiceMaxResponseHeaderBytes(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 100; i++ {
+			w.Header().Add("Set-Cookie", fmt.Sprintf("cookie%d=%s", i, strings.Repeat("a", 100)))
+		}
+	}))
+	defer origin.Close()
+
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		maxResponseHeaderBytes int
+		expectErr              bool
+	}{
+		{maxResponseHeaderBytes: 0, expectErr: false},
+		{maxResponseHeaderBytes: 1024, expectErr: true},
+		{maxResponseHeaderBytes: 64 * 1024, expectErr: false},
+	}
+	for _, test := range tests {
+		httpService := &httpService{
+			url: originURL,
+		}
+		shutdownC := make(chan struct{})
+		cfg := OriginRequestConfig{MaxResponseHeaderBytes: test.maxResponseHeaderBytes}
+		require.NoError(t, httpService.start(TestLogger, shutdownC, cfg))
+
+		req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+		require.NoError(t, err)
+		resp, err := httpService.RoundTrip(req)
+		if test.expectErr {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Len(t, resp.Header.Values("Set-Cookie"), 100)
+		}
+		close(shutdownC)
+	}
+}
+
+func TestHTTPServiceMaxConcurrentTLSHandshakes(t *testing.T) {
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	origin.EnableHTTP2 = true
+	origin.StartTLS()
+	defer origin.Close()
+
+	// Connections are established like the transport would do it
+	for _, http2Origin := range []bool{false, true} {
+		originURL, err := url.Parse(origin.URL)
+		require.NoError(t, err)
+		httpService := &httpService{url: originURL}
+		shutdownC := make(chan struct{})
+		cfg := OriginRequestConfig{MaxConcurrentTLSHandshakes: 1, NoTLSVerify: true, Http2Origin: http2Origin}
+		require.NoError(t, httpService.start(TestLogger, shutdownC, cfg))
+
+		req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+		require.NoError(t, err)
+		resp, err := httpService.RoundTrip(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		if http2Origin {
+			require.Equal(t, "HTTP/2.0", string(body))
+		} else {
+			require.Equal(t, "HTTP/1.1", string(body))
+		}
+		close(shutdownC)
+	}
+
+	// An origin that accepts connections but never completes the handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	originURL, err := url.Parse("https://" + listener.Addr().String())
+	require.NoError(t, err)
+	httpService := &httpService{url: originURL}
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	cfg := OriginRequestConfig{MaxConcurrentTLSHandshakes: 1, NoTLSVerify: true}
+	require.NoError(t, httpService.start(TestLogger, shutdownC, cfg))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, originURL.String(), nil)
+			if err != nil {
+				return
+			}
+			if resp, err := httpService.RoundTrip(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The second connection waited for the handshake of the first one, which never finished
+	require.Len(t, accepted, 1)
+	(<-accepted).Close()
+}
+
+func TestHTTPServiceMatchSNIToHostWithMaxConcurrentTLSHandshakes(t *testing.T) {
+	var lock sync.Mutex
+	var serverNames []string
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	origin.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			serverNames = append(serverNames, hello.ServerName)
+			return nil, nil
+		},
+	}
+	origin.StartTLS()
+	defer origin.Close()
+
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+	httpService := &httpService{url: originURL}
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	cfg := OriginRequestConfig{MaxConcurrentTLSHandshakes: 1, MatchSNIToHost: true, NoTLSVerify: true}
+	require.NoError(t, httpService.start(TestLogger, shutdownC, cfg))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+			require.NoError(t, err)
+			req.Host = "origin.example.com"
+			resp, err := httpService.RoundTrip(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.NotEmpty(t, serverNames)
+	for _, serverName := range serverNames {
+		require.Equal(t, "origin.example.com", serverName)
+	}
+}
+
+func TestHTTPServiceALPNProtocols(t *testing.T) {
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	origin.EnableHTTP2 = true
+	origin.StartTLS()
+	defer origin.Close()
+
+	tests := []struct {
+		alpnProtocols  []string
+		matchSNIToHost bool
+		expectedProto  string
+	}{
+		{expectedProto: "HTTP/2.0"},
+		{alpnProtocols: []string{"http/1.1"}, expectedProto: "HTTP/1.1"},
+		{alpnProtocols: []string{"h2", "http/1.1"}, expectedProto: "HTTP/2.0"},
+		{matchSNIToHost: true, expectedProto: "HTTP/2.0"},
+		{alpnProtocols: []string{"http/1.1"}, matchSNIToHost: true, expectedProto: "HTTP/1.1"},
+	}
+	for _, test := range tests {
+		originURL, err := url.Parse(origin.URL)
+		require.NoError(t, err)
+		httpService := &httpService{url: originURL}
+		shutdownC := make(chan struct{})
+		cfg := OriginRequestConfig{
+			NoTLSVerify:    true,
+			Http2Origin:    true,
+			ALPNProtocols:  test.alpnProtocols,
+			MatchSNIToHost: test.matchSNIToHost,
+		}
+		require.NoError(t, httpService.start(TestLogger, shutdownC, cfg))
+
+		req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+		require.NoError(t, err)
+		req.Host = "origin.example.com"
+		resp, err := httpService.RoundTrip(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, test.expectedProto, string(body), test.alpnProtocols)
+		close(shutdownC)
+	}
+}
+
+func TestWarmUpConnections(t *testing.T) {
+	var lock sync.Mutex
+	newConns := 0
+	idleConns := 0
+	var methods []string
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		methods = append(methods, r.Method)
+		lock.Unlock()
+	}))
+	origin.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch state {
+		case http.StateNew:
+			newConns++
+		case http.StateIdle:
+			idleConns++
+		}
+	}
+	origin.Start()
+	defer origin.Close()
+
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	cfg := OriginRequestConfig{
+		KeepAliveConnections: 3,
+		WarmUpConnections:    5,
+	}
+	httpService := &httpService{
+		url: originURL,
+	}
+	shutdownC := make(chan struct{})
+	defer close(shutdownC)
+	require.NoError(t, httpService.start(TestLogger, shutdownC, cfg))
+
+	// Warm up is capped by the keepalive pool size, and is done with HEAD requests
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(methods) == 3
+	}, time.Second, 10*time.Millisecond)
+	lock.Lock()
+	require.Equal(t, []string{http.MethodHead, http.MethodHead, http.MethodHead}, methods)
+	require.Equal(t, 3, newConns)
+	lock.Unlock()
+	// Let the warm up return the connections to the idle pool
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return idleConns == 3
+	}, time.Second, 10*time.Millisecond)
+
+	// Requests reuse the warmed up connections
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodGet, originURL.String(), nil)
+		require.NoError(t, err)
+		resp, err := httpService.RoundTrip(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, resp.Body.Close())
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, 3, newConns)
+}
+
+func TestWarmUpConnectionsHTTP2Origin(t *testing.T) {
+	var lock sync.Mutex
+	requests := 0
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests++
+		lock.Unlock()
+	}))
+	origin.EnableHTTP2 = true
+	origin.StartTLS()
+	defer origin.Close()
+
+	originURL, err := url.Parse(origin.URL)
+	require.NoError(t, err)
+
+	// Concurrent HTTP/2 requests share a connection, so only one is warmed up
+	httpService := &httpService{
+		url: originURL,
+	}
+	cfg := OriginRequestConfig{
+		KeepAliveConnections: 3,
+		WarmUpConnections:    3,
+		Http2Origin:          true,
+		NoTLSVerify:          true,
+	}
+	transport, err := newHTTPTransport(httpService, cfg, TestLogger)
+	require.NoError(t, err)
+	warmUpConnections(transport, originURL, cfg, make(chan struct{}), TestLogger)
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, 1, requests)
+}
+
 func tcpListenRoutine(listener net.Listener, closeChan chan struct{}) {
 	go func() {
 		for {
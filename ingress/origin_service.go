@@ -6,14 +6,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"golang.org/x/net/http2"
 
 	"github.com/cloudflare/cloudflared/hello"
 	"github.com/cloudflare/cloudflared/ipaccess"
@@ -54,12 +59,14 @@ func (o *unixSocketPath) String() string {
 	return fmt.Sprintf("unix%s:%s", scheme, o.path)
 }
 
-func (o *unixSocketPath) start(log *zerolog.Logger, _ <-chan struct{}, cfg OriginRequestConfig) error {
+func (o *unixSocketPath) start(log *zerolog.Logger, shutdownC <-chan struct{}, cfg OriginRequestConfig) error {
 	transport, err := newHTTPTransport(o, cfg, log)
 	if err != nil {
 		return err
 	}
 	o.transport = transport
+	// The host is ignored since the transport always dials the socket
+	go warmUpConnections(transport, &url.URL{Scheme: o.scheme, Host: "localhost"}, cfg, shutdownC, log)
 	return nil
 }
 
@@ -72,9 +79,11 @@ type httpService struct {
 	hostHeader     string
 	transport      *http.Transport
 	matchSNIToHost bool
+	// skipForwardedHost is set with xForwardedHost, since the proxy then handles X-Forwarded-Host
+	skipForwardedHost bool
 }
 
-func (o *httpService) start(log *zerolog.Logger, _ <-chan struct{}, cfg OriginRequestConfig) error {
+func (o *httpService) start(log *zerolog.Logger, shutdownC <-chan struct{}, cfg OriginRequestConfig) error {
 	transport, err := newHTTPTransport(o, cfg, log)
 	if err != nil {
 		return err
@@ -82,6 +91,11 @@ func (o *httpService) start(log *zerolog.Logger, _ <-chan struct{}, cfg OriginRe
 	o.hostHeader = cfg.HTTPHostHeader
 	o.transport = transport
 	o.matchSNIToHost = cfg.MatchSNIToHost
+	o.skipForwardedHost = cfg.XForwardedHost != ""
+	// The URL of the Hello World server is only known once it's started
+	if o.url != nil {
+		go warmUpConnections(transport, o.url, cfg, shutdownC, log)
+	}
 	return nil
 }
 
@@ -122,6 +136,9 @@ type tcpOverWSService struct {
 	isBastion     bool
 	streamHandler streamHandlerFunc
 	dialer        net.Dialer
+	originHosts   map[string]string
+	connectProxy  *url.URL
+	idleTimeout   time.Duration
 }
 
 type socksProxyOverWSService struct {
@@ -189,14 +206,33 @@ func (o *tcpOverWSService) start(log *zerolog.Logger, _ <-chan struct{}, cfg Ori
 	}
 	o.dialer.Timeout = cfg.ConnectTimeout.Duration
 	o.dialer.KeepAlive = cfg.TCPKeepAlive.Duration
+	o.dialer.Resolver = originResolver(cfg)
+	o.originHosts = cfg.OriginHosts
+	o.idleTimeout = cfg.TCPIdleTimeout.Duration
+	if cfg.ConnectProxy != "" {
+		connectProxy, err := parseConnectProxy(cfg.ConnectProxy)
+		if err != nil {
+			return err
+		}
+		o.connectProxy = connectProxy
+	}
 	return nil
 }
 
+// metricLabel identifies the kind of service in metrics without the destination, which would be unbounded.
+func (o *tcpOverWSService) metricLabel() string {
+	if o.isBastion {
+		return ServiceBastion
+	}
+	return o.scheme
+}
+
 func (o tcpOverWSService) MarshalJSON() ([]byte, error) {
 	return json.Marshal(o.String())
 }
 
 func (o *socksProxyOverWSService) start(log *zerolog.Logger, _ <-chan struct{}, cfg OriginRequestConfig) error {
+	o.conn.idleTimeout = cfg.TCPIdleTimeout.Duration
 	return nil
 }
 
@@ -286,7 +322,8 @@ func (o statusCode) MarshalJSON() ([]byte, error) {
 // WarpRoutingService starts a tcp stream between the origin and requests from
 // warp clients.
 type WarpRoutingService struct {
-	Proxy StreamBasedOriginProxy
+	Proxy         StreamBasedOriginProxy
+	portOverrides []PortOverride
 }
 
 func NewWarpRoutingService(config WarpRoutingConfig, writeTimeout time.Duration) *WarpRoutingService {
@@ -299,7 +336,22 @@ func NewWarpRoutingService(config WarpRoutingConfig, writeTimeout time.Duration)
 		writeTimeout: writeTimeout,
 	}
 
-	return &WarpRoutingService{Proxy: svc}
+	return &WarpRoutingService{Proxy: svc, portOverrides: config.PortOverrides}
+}
+
+// OriginDest returns the address to dial for a connection a WARP client made to dest, applying the first port
+// override that matches it.
+func (s *WarpRoutingService) OriginDest(dest string) string {
+	addrPort, err := netip.ParseAddrPort(dest)
+	if err != nil {
+		return dest
+	}
+	for _, override := range s.portOverrides {
+		if override.Port == addrPort.Port() && override.Network.Contains(addrPort.Addr().Unmap()) {
+			return netip.AddrPortFrom(addrPort.Addr(), override.OriginPort).String()
+		}
+	}
+	return dest
 }
 
 // ManagementService starts a local HTTP server to handle incoming management requests.
@@ -344,35 +396,60 @@ func (nrc *NopReadCloser) Close() error {
 }
 
 func newHTTPTransport(service OriginService, cfg OriginRequestConfig, log *zerolog.Logger) (*http.Transport, error) {
-	originCertPool, err := tlsconfig.LoadOriginCA(cfg.CAPool, log)
+	originCertPool, err := tlsconfig.LoadOriginCA(cfg.CAPool, cfg.CAPoolPEM, log)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error loading cert pool")
 	}
 
 	httpTransport := http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		MaxIdleConns:          cfg.KeepAliveConnections,
-		MaxIdleConnsPerHost:   cfg.KeepAliveConnections,
-		IdleConnTimeout:       cfg.KeepAliveTimeout.Duration,
-		TLSHandshakeTimeout:   cfg.TLSTimeout.Duration,
-		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       &tls.Config{RootCAs: originCertPool, InsecureSkipVerify: cfg.NoTLSVerify},
-		ForceAttemptHTTP2:     cfg.Http2Origin,
+		Proxy:                  http.ProxyFromEnvironment,
+		MaxIdleConns:           cfg.KeepAliveConnections,
+		MaxIdleConnsPerHost:    cfg.KeepAliveConnections,
+		IdleConnTimeout:        cfg.KeepAliveTimeout.Duration,
+		TLSHandshakeTimeout:    cfg.TLSTimeout.Duration,
+		ExpectContinueTimeout:  1 * time.Second,
+		TLSClientConfig:        &tls.Config{RootCAs: originCertPool, InsecureSkipVerify: cfg.NoTLSVerify},
+		ForceAttemptHTTP2:      cfg.Http2Origin,
+		DisableCompression:     cfg.DisableCompression,
+		MaxResponseHeaderBytes: int64(cfg.MaxResponseHeaderBytes),
 	}
 	if _, isHelloWorld := service.(*helloWorld); !isHelloWorld && cfg.OriginServerName != "" {
 		httpTransport.TLSClientConfig.ServerName = cfg.OriginServerName
 	}
+	if len(cfg.PinnedPublicKeys) > 0 {
+		pins, err := parsePinnedPublicKeys(cfg.PinnedPublicKeys)
+		if err != nil {
+			return nil, err
+		}
+		httpTransport.TLSClientConfig.VerifyPeerCertificate = verifyPinnedPublicKeys(pins)
+	}
+	if cfg.TLSSessionCache {
+		// The cache is shared by every connection this transport opens, so it lives as long as the rule does.
+		httpTransport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+
+	if cfg.Http2Origin && cfg.Http2MaxReadFrameSize > 0 {
+		// The HTTP/2 transport bundled in net/http can't be tuned, so use the one from x/net instead.
+		h2Transport, err := http2.ConfigureTransports(&httpTransport)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error configuring HTTP/2 transport")
+		}
+		h2Transport.MaxReadFrameSize = uint32(min(cfg.Http2MaxReadFrameSize, math.MaxUint32))
+	}
 
 	dialer := &net.Dialer{
 		Timeout:   cfg.ConnectTimeout.Duration,
 		KeepAlive: cfg.TCPKeepAlive.Duration,
+		Resolver:  originResolver(cfg),
 	}
 	if cfg.NoHappyEyeballs {
 		dialer.FallbackDelay = -1 // As of Golang 1.12, a negative delay disables "happy eyeballs"
 	}
 
 	// DialContext depends on which kind of origin is being used.
-	dialContext := dialer.DialContext
+	dialContext := func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, originAddress(cfg.OriginHosts, address))
+	}
 	switch service := service.(type) {
 
 	// If this origin is a unix socket, enforce network type "unix".
@@ -386,9 +463,160 @@ func newHTTPTransport(service OriginService, cfg OriginRequestConfig, log *zerol
 		httpTransport.DialContext = dialContext
 	}
 
+	if cfg.MaxConcurrentTLSHandshakes > 0 || len(cfg.ALPNProtocols) > 0 || cfg.MatchSNIToHost {
+		httpTransport.DialTLSContext = originTLSDialer(&httpTransport, cfg.MaxConcurrentTLSHandshakes, cfg.ALPNProtocols)
+	}
+
 	return &httpTransport, nil
 }
 
+// originServerNameKey is the context key of the server name that matchSNItoHost sends to the origin for a request.
+type originServerNameKey struct{}
+
+// originTLSDialer returns a DialTLSContext for transport that connects and handshakes like the transport would, with at
+// most maxHandshakes connections being established at once if it is greater than 0, and offering nextProtos with ALPN
+// if they are set. The server name of the request context, if any, replaces the one of the transport.
+func originTLSDialer(transport *http.Transport, maxHandshakes int, nextProtos []string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	var handshakes chan struct{}
+	if maxHandshakes > 0 {
+		handshakes = make(chan struct{}, maxHandshakes)
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if handshakes != nil {
+			select {
+			case handshakes <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			defer func() { <-handshakes }()
+		}
+
+		conn, err := transport.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		// The transport adds the protocols it supports to its TLS config before the first dial, so this
+		// negotiates HTTP/2 when it is enabled, unless nextProtos replace them.
+		tlsConfig := transport.TLSClientConfig.Clone()
+		if len(nextProtos) > 0 {
+			tlsConfig.NextProtos = nextProtos
+		}
+		if serverName, ok := ctx.Value(originServerNameKey{}).(string); ok && serverName != "" {
+			tlsConfig.ServerName = serverName
+		} else if tlsConfig.ServerName == "" {
+			if host, _, err := net.SplitHostPort(address); err == nil {
+				tlsConfig.ServerName = host
+			}
+		}
+		if transport.TLSHandshakeTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, transport.TLSHandshakeTimeout)
+			defer cancel()
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// originResolver returns the resolver for origin hostnames, which is the OS resolver unless cfg.OriginResolver is set.
+func originResolver(cfg OriginRequestConfig) *net.Resolver {
+	if cfg.OriginResolver == "" {
+		return nil
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: cfg.ConnectTimeout.Duration}
+			return dialer.DialContext(ctx, network, cfg.OriginResolver)
+		},
+	}
+}
+
+// originAddress replaces the host of address with its IP from originHosts, if there is one.
+func originAddress(originHosts map[string]string, address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	if ip, ok := originHosts[host]; ok {
+		return net.JoinHostPort(ip, port)
+	}
+	return address
+}
+
+// warmUpConnections fills the idle connection pool of the transport by sending concurrent HEAD requests to the
+// origin, up to cfg.WarmUpConnections capped by the pool size. These are real requests that the origin sees and
+// logs. Failures are only logged since the connections are established on demand anyway.
+func warmUpConnections(transport *http.Transport, originURL *url.URL, cfg OriginRequestConfig, shutdownC <-chan struct{}, log *zerolog.Logger) {
+	count := cfg.WarmUpConnections
+	if count > cfg.KeepAliveConnections {
+		count = cfg.KeepAliveConnections
+	}
+	// HTTP/2 multiplexes concurrent requests over a single connection, so more would be pointless requests
+	if cfg.Http2Origin && count > 1 {
+		count = 1
+	}
+	if count <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-shutdownC:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	target := *originURL
+	switch target.Scheme {
+	case "ws":
+		target.Scheme = "http"
+	case "wss":
+		target.Scheme = "https"
+	}
+
+	var wg sync.WaitGroup
+	var failures atomic.Int32
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), nil)
+			if err != nil {
+				failures.Add(1)
+				return
+			}
+			if cfg.HTTPHostHeader != "" {
+				req.Host = cfg.HTTPHostHeader
+			}
+			// Avoid inserting golang default UA
+			req.Header.Set("User-Agent", "")
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				failures.Add(1)
+				return
+			}
+			// Draining and closing the body returns the connection to the idle pool
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	log.Debug().
+		Str("originService", originURL.String()).
+		Int("connections", count-int(failures.Load())).
+		Int("failures", int(failures.Load())).
+		Msg("Warmed up origin connections")
+}
+
 // MockOriginHTTPService should only be used by other packages to mock OriginService. Set Transport to configure desired RoundTripper behavior.
 type MockOriginHTTPService struct {
 	Transport http.RoundTripper
@@ -0,0 +1,67 @@
+package ingress
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// errNoPinnedPublicKey is returned by the TLS handshake when the origin doesn't present any of the pinned public keys.
+var errNoPinnedPublicKey = errors.New("origin certificate doesn't match any of the pinnedPublicKeys")
+
+// parsePinnedPublicKeys decodes the base64 SHA-256 SPKI hashes of pinnedPublicKeys.
+func parsePinnedPublicKeys(pins []string) ([][]byte, error) {
+	hashes := make([][]byte, 0, len(pins))
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("pinnedPublicKeys entry %q must be a base64 encoded SHA-256 hash", pin)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (c *OriginRequestConfig) validatePinnedPublicKeys() error {
+	_, err := parsePinnedPublicKeys(c.PinnedPublicKeys)
+	return err
+}
+
+// verifyPinnedPublicKeys returns a tls.Config VerifyPeerCertificate function that accepts the origin if its certificate
+// has one of the pinned public keys. When the chain was verified against the CAs, the keys of the intermediate and
+// root certificates can be pinned too. Without verification, e.g. with noTLSVerify, only the certificate of the origin
+// is checked since the others are not proven to be part of its chain.
+func verifyPinnedPublicKeys(pins [][]byte) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	matches := func(cert *x509.Certificate) bool {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(pin, hash[:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				if matches(cert) {
+					return nil
+				}
+			}
+		}
+		if len(verifiedChains) == 0 && len(rawCerts) > 0 {
+			leaf, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return errors.Wrap(err, "failed to parse origin certificate")
+			}
+			if matches(leaf) {
+				return nil
+			}
+		}
+		return errNoPinnedPublicKey
+	}
+}
@@ -0,0 +1,93 @@
+package ingress
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedPublicKeys(t *testing.T) {
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer origin.Close()
+	originHash := sha256.Sum256(origin.Certificate().RawSubjectPublicKeyInfo)
+	originPin := base64.StdEncoding.EncodeToString(originHash[:])
+	otherHash := sha256.Sum256([]byte("other key"))
+	otherPin := base64.StdEncoding.EncodeToString(otherHash[:])
+	originCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: origin.Certificate().Raw}))
+
+	tests := []struct {
+		name    string
+		cfg     OriginRequestConfig
+		wantErr bool
+	}{
+		{
+			name: "pinned key without CA verification",
+			cfg:  OriginRequestConfig{NoTLSVerify: true, PinnedPublicKeys: []string{otherPin, originPin}},
+		},
+		{
+			name:    "other key without CA verification",
+			cfg:     OriginRequestConfig{NoTLSVerify: true, PinnedPublicKeys: []string{otherPin}},
+			wantErr: true,
+		},
+		{
+			name: "pinned key with CA verification",
+			cfg:  OriginRequestConfig{CAPoolPEM: originCA, PinnedPublicKeys: []string{originPin}},
+		},
+		{
+			name:    "other key with CA verification",
+			cfg:     OriginRequestConfig{CAPoolPEM: originCA, PinnedPublicKeys: []string{otherPin}},
+			wantErr: true,
+		},
+		{
+			name:    "other key with matchSNItoHost",
+			cfg:     OriginRequestConfig{NoTLSVerify: true, MatchSNIToHost: true, PinnedPublicKeys: []string{otherPin}},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport, err := newHTTPTransport(&httpService{}, test.cfg, TestLogger)
+			require.NoError(t, err)
+			defer transport.CloseIdleConnections()
+
+			req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+			require.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			if test.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), errNoPinnedPublicKey.Error())
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestParsePinnedPublicKeys(t *testing.T) {
+	_, err := ParseIngress(MustReadIngress(`
+ingress:
+  - service: https://localhost:8443
+    originRequest:
+      pinnedPublicKeys:
+        - "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+`))
+	require.NoError(t, err)
+
+	for _, pin := range []string{"not base64!", "c2hvcnQ="} {
+		_, err := ParseIngress(MustReadIngress(`
+ingress:
+  - service: https://localhost:8443
+    originRequest:
+      pinnedPublicKeys:
+        - "` + pin + `"
+`))
+		assert.Error(t, err, pin)
+	}
+}
@@ -30,6 +30,12 @@ type Rule struct {
 
 	// Configure the request cloudflared sends to this specific origin.
 	Config OriginRequestConfig `json:"originRequest"`
+
+	// Critical rules need their origin to be reachable for cloudflared to report ready.
+	Critical bool `json:"critical,omitempty"`
+
+	// Canary optionally sends part of the requests to a second origin.
+	Canary *Canary `json:"canary,omitempty"`
 }
 
 // MultiLineString is for outputting rules in a human-friendly way when Cloudflared
@@ -0,0 +1,66 @@
+package ingress
+
+import "strings"
+
+// hostIndex speeds up FindMatchingRule for configurations with many rules. Rules with an exact hostname are indexed
+// by it, and the remaining ones (wildcards and catch-alls) are kept apart, so that a request only has to be checked
+// against the rules of its hostname and the remaining rules, still in rule order.
+type hostIndex struct {
+	exact map[string][]int
+	other []int
+	// rules are the rules the index was built for, to detect rules replaced after parsing
+	rules []Rule
+}
+
+func newHostIndex(rules []Rule) *hostIndex {
+	idx := &hostIndex{
+		exact: make(map[string][]int),
+		rules: rules,
+	}
+	for i, rule := range rules {
+		if !hasExactHostname(rule) {
+			idx.other = append(idx.other, i)
+			continue
+		}
+		idx.exact[rule.Hostname] = append(idx.exact[rule.Hostname], i)
+		if rule.punycodeHostname != "" {
+			idx.exact[rule.punycodeHostname] = append(idx.exact[rule.punycodeHostname], i)
+		}
+	}
+	return idx
+}
+
+// indexes returns true if the index was built for rules, i.e. they are the same slice and not ones that replaced them.
+func (idx *hostIndex) indexes(rules []Rule) bool {
+	if idx == nil || len(idx.rules) != len(rules) {
+		return false
+	}
+	return len(rules) == 0 || &idx.rules[0] == &rules[0]
+}
+
+// hasExactHostname returns true if the rule only matches requests to its hostname, see matchHost.
+func hasExactHostname(rule Rule) bool {
+	if rule.Hostname == "" || rule.Hostname == "*" {
+		return false
+	}
+	return !strings.HasPrefix(rule.Hostname, "*.") && !strings.HasPrefix(rule.punycodeHostname, "*.")
+}
+
+// find returns the index of the first rule matching hostname and path, or false if none does.
+func (idx *hostIndex) find(rules []Rule, hostname, path string) (int, bool) {
+	exact := idx.exact[hostname]
+	other := idx.other
+	// Merge both lists of candidates, which are sorted, to check them in rule order
+	for len(exact) > 0 || len(other) > 0 {
+		var i int
+		if len(other) == 0 || (len(exact) > 0 && exact[0] < other[0]) {
+			i, exact = exact[0], exact[1:]
+		} else {
+			i, other = other[0], other[1:]
+		}
+		if rules[i].Matches(hostname, path) {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	AuditLogFlag = "audit-log"
+
+	auditEventKey = "event"
+	// AuditEventAccessDenied is the event of a request or connection denied by an access control
+	AuditEventAccessDenied = "access_denied"
+)
+
+var auditLogger atomic.Pointer[zerolog.Logger]
+
+func init() {
+	nop := zerolog.Nop()
+	auditLogger.Store(&nop)
+}
+
+// AuditLogger returns the logger of security relevant decisions, kept apart from the application logs for compliance.
+// Events are discarded unless InitAuditLogger was called.
+func AuditLogger() *zerolog.Logger {
+	return auditLogger.Load()
+}
+
+// AuditEvent starts a structured audit event of the given type.
+func AuditEvent(event string) *zerolog.Event {
+	return AuditLogger().Log().Str(auditEventKey, event)
+}
+
+// InitAuditLogger writes the audit events to the file at fullpath, as JSON lines.
+func InitAuditLogger(fullpath string) error {
+	logFile, err := createDirFile(*createFileConfig(fullpath))
+	if err != nil {
+		return err
+	}
+	log := zerolog.New(logFile).With().Timestamp().Logger()
+	auditLogger.Store(&log)
+	return nil
+}
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogger(t *testing.T) {
+	// Events are discarded until an audit log is configured
+	AuditEvent(AuditEventAccessDenied).Msg("discarded")
+
+	auditLogPath := filepath.Join(t.TempDir(), "audit", "audit.log")
+	require.NoError(t, InitAuditLogger(auditLogPath))
+	defer func() {
+		nop := zerolog.Nop()
+		auditLogger.Store(&nop)
+	}()
+
+	AuditEvent(AuditEventAccessDenied).Str("host", "app.example.com").Msg("Request denied")
+
+	content, err := os.ReadFile(auditLogPath)
+	require.NoError(t, err)
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &event))
+	assert.Equal(t, AuditEventAccessDenied, event["event"])
+	assert.Equal(t, "app.example.com", event["host"])
+	assert.Equal(t, "Request denied", event["message"])
+	assert.NotEmpty(t, event["time"])
+}
@@ -17,6 +17,7 @@ type Config struct {
 
 type ConsoleConfig struct {
 	noColor bool
+	format  string // default | json | logfmt
 }
 
 type FileConfig struct {
@@ -48,6 +49,7 @@ func createDefaultConfig() Config {
 	return Config{
 		ConsoleConfig: &ConsoleConfig{
 			noColor: false,
+			format:  LogFormatDefault,
 		},
 		FileConfig: &FileConfig{
 			Dirname:  "",
@@ -68,10 +70,11 @@ func CreateConfig(
 	minLevel string,
 	disableTerminal bool,
 	rollingLogPath, nonRollingLogFilePath string,
+	format string,
 ) *Config {
 	var console *ConsoleConfig
 	if !disableTerminal {
-		console = createConsoleConfig()
+		console = createConsoleConfig(format)
 	}
 
 	var file *FileConfig
@@ -95,9 +98,14 @@ func CreateConfig(
 	}
 }
 
-func createConsoleConfig() *ConsoleConfig {
+func createConsoleConfig(format string) *ConsoleConfig {
+	if format == "" {
+		format = defaultConfig.ConsoleConfig.format
+	}
+
 	return &ConsoleConfig{
 		noColor: false,
+		format:  format,
 	}
 }
 
@@ -28,6 +28,7 @@ const (
 	LogFileFlag           = "logfile"
 	LogDirectoryFlag      = "log-directory"
 	LogTransportLevelFlag = "transport-loglevel"
+	LogFormatFlag         = "log-format"
 
 	LogSSHDirectoryFlag = "log-directory"
 	LogSSHLevelFlag     = "log-level"
@@ -36,6 +37,10 @@ const (
 	filePermMode = 0644 // rw-r--r--
 
 	consoleTimeFormat = time.RFC3339
+
+	LogFormatDefault = "default"
+	LogFormatJSON    = "json"
+	LogFormatLogfmt  = "logfmt"
 )
 
 var (
@@ -102,8 +107,14 @@ var levelErrorLogged = false
 func newZerolog(loggerConfig *Config) *zerolog.Logger {
 	var writers []io.Writer
 
+	var formatErr error
 	if loggerConfig.ConsoleConfig != nil {
-		writers = append(writers, createConsoleLogger(*loggerConfig.ConsoleConfig))
+		consoleLogger, err := createConsoleLogger(*loggerConfig.ConsoleConfig)
+		if err != nil {
+			formatErr = err
+			consoleLogger, _ = createConsoleLogger(*defaultConfig.ConsoleConfig)
+		}
+		writers = append(writers, consoleLogger)
 	}
 
 	if loggerConfig.FileConfig != nil {
@@ -140,6 +151,9 @@ func newZerolog(loggerConfig *Config) *zerolog.Logger {
 		log.Error().Msgf("Failed to parse log level %q, using %q instead", loggerConfig.MinLevel, level)
 		levelErrorLogged = true
 	}
+	if formatErr != nil {
+		log.Error().Err(formatErr).Msgf("Using %q log format instead", LogFormatDefault)
+	}
 
 	return &log
 }
@@ -165,12 +179,14 @@ func createFromContext(
 	logLevel := c.String(logLevelFlagName)
 	logFile := c.String(LogFileFlag)
 	logDirectory := c.String(logDirectoryFlagName)
+	logFormat := c.String(LogFormatFlag)
 
 	loggerConfig := CreateConfig(
 		logLevel,
 		disableTerminal,
 		logDirectory,
 		logFile,
+		logFormat,
 	)
 
 	log := newZerolog(loggerConfig)
@@ -192,12 +208,21 @@ func Create(loggerConfig *Config) *zerolog.Logger {
 	return newZerolog(loggerConfig)
 }
 
-func createConsoleLogger(config ConsoleConfig) io.Writer {
+func createConsoleLogger(config ConsoleConfig) (io.Writer, error) {
 	consoleOut := os.Stderr
-	return zerolog.ConsoleWriter{
-		Out:        colorable.NewColorable(consoleOut),
-		NoColor:    config.noColor || !term.IsTerminal(int(consoleOut.Fd())),
-		TimeFormat: consoleTimeFormat,
+	switch config.format {
+	case LogFormatDefault, "":
+		return zerolog.ConsoleWriter{
+			Out:        colorable.NewColorable(consoleOut),
+			NoColor:    config.noColor || !term.IsTerminal(int(consoleOut.Fd())),
+			TimeFormat: consoleTimeFormat,
+		}, nil
+	case LogFormatJSON:
+		return consoleOut, nil
+	case LogFormatLogfmt:
+		return logfmtWriter{out: consoleOut}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected one of %s, %s or %s", config.format, LogFormatDefault, LogFormatJSON, LogFormatLogfmt)
 	}
 }
 
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+)
+
+// logfmtWriter re-encodes the JSON events emitted by zerolog as logfmt key=value pairs. The timestamp, level and
+// message come first, followed by the other fields sorted by name.
+type logfmtWriter struct {
+	out io.Writer
+}
+
+func (w logfmtWriter) Write(p []byte) (int, error) {
+	var event map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		// Not an event we can re-encode, write it as is rather than losing it
+		return w.out.Write(p)
+	}
+
+	var buf bytes.Buffer
+	for _, key := range []string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.MessageFieldName} {
+		if value, ok := event[key]; ok {
+			writeLogfmtPair(&buf, key, value)
+			delete(event, key)
+		}
+	}
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeLogfmtPair(&buf, key, event[key])
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	buf.WriteString(logfmtValue(value))
+}
+
+func logfmtValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		s = v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	default:
+		// Nested objects and arrays are kept as compact JSON
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%q", fmt.Sprint(v))
+		}
+		s = string(b)
+	}
+	if needsQuoting(s) {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	return strings.IndexFunc(s, func(r rune) bool {
+		return r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}) >= 0
+}
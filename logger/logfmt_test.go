@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogfmtWriter(t *testing.T) {
+	var out bytes.Buffer
+	log := zerolog.New(logfmtWriter{out: &out})
+
+	log.Info().
+		Str("ingressRule", "1").
+		Int("connIndex", 2).
+		Bool("ok", true).
+		Strs("ips", []string{"198.41.200.13", "198.41.192.7"}).
+		Err(errors.New("dial tcp: connection refused")).
+		Msg("Registered tunnel connection")
+	assert.Equal(t,
+		`level=info message="Registered tunnel connection" connIndex=2 error="dial tcp: connection refused" ingressRule=1 ips="[\"198.41.200.13\",\"198.41.192.7\"]" ok=true`+"\n",
+		out.String(),
+	)
+
+	out.Reset()
+	log.Warn().Str("empty", "").Str("equals", "a=b").Send()
+	assert.Equal(t, `level=warn empty="" equals="a=b"`+"\n", out.String())
+}
+
+func TestLogfmtWriterNotJSON(t *testing.T) {
+	var out bytes.Buffer
+	n, err := logfmtWriter{out: &out}.Write([]byte("not json\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 9, n)
+	assert.Equal(t, "not json\n", out.String())
+}
+
+func TestCreateConsoleLoggerUnknownFormat(t *testing.T) {
+	_, err := createConsoleLogger(ConsoleConfig{format: "xml"})
+	assert.Error(t, err)
+
+	for _, format := range []string{"", LogFormatDefault, LogFormatJSON, LogFormatLogfmt} {
+		_, err := createConsoleLogger(ConsoleConfig{format: format})
+		assert.NoError(t, err)
+	}
+}
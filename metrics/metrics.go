@@ -57,6 +57,8 @@ type Config struct {
 	DiagnosticHandler   *diagnostic.Handler
 	QuickTunnelHostname string
 	Orchestrator        orchestrator
+	// Namespace, if set, prefixes the name of every exported metric.
+	Namespace string
 
 	ShutdownTimeout time.Duration
 }
@@ -71,7 +73,14 @@ func newMetricsHandler(
 ) *http.ServeMux {
 	router := http.NewServeMux()
 	router.Handle("/debug/", http.DefaultServeMux)
-	router.Handle("/metrics", promhttp.Handler())
+	if config.Namespace == "" {
+		router.Handle("/metrics", promhttp.Handler())
+	} else {
+		gatherer := namespacedGatherer{Gatherer: prometheus.DefaultGatherer, namespace: config.Namespace}
+		router.Handle("/metrics", promhttp.InstrumentMetricHandler(
+			prometheus.DefaultRegisterer, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}),
+		))
+	}
 	router.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = fmt.Fprintf(w, "OK\n")
 	})
@@ -182,6 +191,12 @@ func ServeMetrics(
 	return err
 }
 
+func init() {
+	// The default registry already exports process_* metrics, these are namespaced like the rest of cloudflared's
+	// metrics so they can be told apart from other processes scraped by the same Prometheus
+	prometheus.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{Namespace: "cloudflared"}))
+}
+
 func RegisterBuildInfo(buildType, buildTime, version string) {
 	buildInfo := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -1,9 +1,11 @@
 package metrics_test
 
 import (
+	"runtime"
 	"testing"
 
 	"github.com/facebookgo/grace/gracenet"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -50,3 +52,17 @@ func TestMetricsListenerCreation(t *testing.T) {
 	err = listener7.Close()
 	require.NoError(t, err)
 }
+
+func TestProcessMetrics(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process metrics are only collected on linux")
+	}
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, names, "cloudflared_process_cpu_seconds_total")
+	assert.Contains(t, names, "cloudflared_process_resident_memory_bytes")
+}
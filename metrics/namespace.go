@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var namespaceRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidateNamespace checks that namespace can be used as a prefix of Prometheus metric names.
+func ValidateNamespace(namespace string) error {
+	if !namespaceRegexp.MatchString(namespace) {
+		return fmt.Errorf("invalid metrics namespace %q: it must start with a letter or underscore and contain only letters, digits and underscores", namespace)
+	}
+	return nil
+}
+
+// namespacedGatherer prefixes the name of every metric family with namespace, so that several cloudflared
+// instances scraped into the same Prometheus export distinct metric names.
+type namespacedGatherer struct {
+	prometheus.Gatherer
+	namespace string
+}
+
+func (g namespacedGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	for _, family := range families {
+		name := g.namespace + "_" + family.GetName()
+		family.Name = &name
+	}
+	return families, err
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespacedGatherer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cloudflared",
+		Name:      "test_total",
+		Help:      "Test counter",
+	})
+	registry.MustRegister(counter)
+	counter.Inc()
+
+	families, err := namespacedGatherer{Gatherer: registry, namespace: "edge1"}.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Equal(t, "edge1_cloudflared_test_total", families[0].GetName())
+	require.Equal(t, float64(1), families[0].GetMetric()[0].GetCounter().GetValue())
+
+	// The wrapped gatherer is left untouched
+	families, err = registry.Gather()
+	require.NoError(t, err)
+	require.Equal(t, "cloudflared_test_total", families[0].GetName())
+}
+
+func TestValidateNamespace(t *testing.T) {
+	require.NoError(t, ValidateNamespace("edge1"))
+	require.NoError(t, ValidateNamespace("_connector_a"))
+	require.Error(t, ValidateNamespace(""))
+	require.Error(t, ValidateNamespace("1edge"))
+	require.Error(t, ValidateNamespace("edge-1"))
+}
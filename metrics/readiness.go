@@ -10,20 +10,29 @@ import (
 	"github.com/cloudflare/cloudflared/tunnelstate"
 )
 
+// CriticalOriginsChecker reports whether the origins the tunnel can't do without were reachable when last checked.
+type CriticalOriginsChecker interface {
+	CheckCriticalOrigins() error
+}
+
 // ReadyServer serves HTTP 200 if the tunnel can serve traffic. Intended for k8s readiness checks.
 type ReadyServer struct {
-	clientID uuid.UUID
-	tracker  *tunnelstate.ConnTracker
+	clientID        uuid.UUID
+	tracker         *tunnelstate.ConnTracker
+	criticalOrigins CriticalOriginsChecker
 }
 
 // NewReadyServer initializes a ReadyServer and starts listening for dis/connection events.
+// criticalOrigins is optional.
 func NewReadyServer(
 	clientID uuid.UUID,
 	tracker *tunnelstate.ConnTracker,
+	criticalOrigins CriticalOriginsChecker,
 ) *ReadyServer {
 	return &ReadyServer{
 		clientID,
 		tracker,
+		criticalOrigins,
 	}
 }
 
@@ -31,17 +40,27 @@ type body struct {
 	Status           int       `json:"status"`
 	ReadyConnections uint      `json:"readyConnections"`
 	ConnectorID      uuid.UUID `json:"connectorId"`
+	Error            string    `json:"error,omitempty"`
 }
 
-// ServeHTTP responds with HTTP 200 if the tunnel is connected to the edge.
+// ServeHTTP responds with HTTP 200 if the tunnel is connected to the edge and the critical origins are reachable.
 func (rs *ReadyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	statusCode, readyConnections := rs.makeResponse()
+	var originsErr error
+	if statusCode == http.StatusOK && rs.criticalOrigins != nil {
+		if originsErr = rs.criticalOrigins.CheckCriticalOrigins(); originsErr != nil {
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
 	w.WriteHeader(statusCode)
 	body := body{
 		Status:           statusCode,
 		ReadyConnections: readyConnections,
 		ConnectorID:      rs.clientID,
 	}
+	if originsErr != nil {
+		body.Error = originsErr.Error()
+	}
 	msg, err := json.Marshal(body)
 	if err != nil {
 		_, _ = fmt.Fprintf(w, `{"error": "%s"}`, err)
@@ -2,6 +2,7 @@ package metrics_test
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -36,7 +37,7 @@ func mockRequest(t *testing.T, readyServer *metrics.ReadyServer) (int, uint) {
 func TestReadinessEventHandling(t *testing.T) {
 	nopLogger := zerolog.Nop()
 	tracker := tunnelstate.NewConnTracker(&nopLogger)
-	rs := metrics.NewReadyServer(uuid.Nil, tracker)
+	rs := metrics.NewReadyServer(uuid.Nil, tracker, nil)
 
 	// start not ok
 	code, readyConnections := mockRequest(t, rs)
@@ -104,3 +105,41 @@ func TestReadinessEventHandling(t *testing.T) {
 	assert.NotEqualValues(t, http.StatusOK, code)
 	assert.Zero(t, readyConnections)
 }
+
+type mockCriticalOrigins struct {
+	err error
+}
+
+func (m *mockCriticalOrigins) CheckCriticalOrigins() error {
+	return m.err
+}
+
+func TestReadinessCriticalOrigins(t *testing.T) {
+	nopLogger := zerolog.Nop()
+	tracker := tunnelstate.NewConnTracker(&nopLogger)
+	tracker.OnTunnelEvent(connection.Event{
+		Index:     0,
+		EventType: connection.Connected,
+	})
+	criticalOrigins := &mockCriticalOrigins{}
+	rs := metrics.NewReadyServer(uuid.Nil, tracker, criticalOrigins)
+
+	rec := httptest.NewRecorder()
+	rs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Not ready while a critical origin is down, even with connections to the edge
+	criticalOrigins.err = errors.New("origin http://localhost:8000 of critical rule #0 is unreachable")
+	rec = httptest.NewRecorder()
+	rs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var body struct {
+		Status           int    `json:"status"`
+		ReadyConnections uint   `json:"readyConnections"`
+		Error            string `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, http.StatusServiceUnavailable, body.Status)
+	assert.EqualValues(t, 1, body.ReadyConnections)
+	assert.Equal(t, criticalOrigins.err.Error(), body.Error)
+}
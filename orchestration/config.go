@@ -23,6 +23,8 @@ type Config struct {
 	Ingress      *ingress.Ingress
 	WarpRouting  ingress.WarpRoutingConfig
 	WriteTimeout time.Duration
+	// SlowRequestThreshold is the duration above which proxied HTTP requests are logged as slow. 0 disables it.
+	SlowRequestThreshold time.Duration
 
 	// Extra settings used to configure this instance but that are not eligible for remotely management
 	// ie. (--protocol, --loglevel, ...)
@@ -30,6 +32,14 @@ type Config struct {
 }
 
 func (rc *newLocalConfig) MarshalJSON() ([]byte, error) {
+	ingressRules := convertToUnvalidatedIngressRules(rc.RemoteConfig.Ingress)
+	// The edge doesn't need the credentials of connectProxy
+	for i := range ingressRules {
+		if connectProxy := ingressRules[i].OriginRequest.ConnectProxy; connectProxy != nil {
+			redacted := ingress.RedactConnectProxy(*connectProxy)
+			ingressRules[i].OriginRequest.ConnectProxy = &redacted
+		}
+	}
 	var r = struct {
 		ConfigurationFlags map[string]string `json:"__configuration_flags,omitempty"`
 		ingress.RemoteConfigJSON
@@ -38,7 +48,7 @@ func (rc *newLocalConfig) MarshalJSON() ([]byte, error) {
 		RemoteConfigJSON: ingress.RemoteConfigJSON{
 			// UI doesn't support top level configs, so we reconcile to individual ingress configs.
 			GlobalOriginRequest: nil,
-			IngressRules:        convertToUnvalidatedIngressRules(rc.RemoteConfig.Ingress),
+			IngressRules:        ingressRules,
 			WarpRouting:         rc.RemoteConfig.WarpRouting.RawConfig(),
 		},
 	}
@@ -46,6 +56,24 @@ func (rc *newLocalConfig) MarshalJSON() ([]byte, error) {
 	return json.Marshal(r)
 }
 
+// dashboardConfig is the body the Cloudflare API takes to configure a remotely-managed tunnel.
+type dashboardConfig struct {
+	Config ingress.RemoteConfigJSON `json:"config"`
+}
+
+// DashboardConfigJSON returns the ingress rules and the warp routing settings as the JSON body the Cloudflare API takes
+// to configure a remotely-managed tunnel, so that a locally-configured tunnel can be migrated without re-entering them.
+func DashboardConfigJSON(ing ingress.Ingress, warpRouting ingress.WarpRoutingConfig) ([]byte, error) {
+	return json.MarshalIndent(dashboardConfig{
+		Config: ingress.RemoteConfigJSON{
+			// UI doesn't support top level configs, so we reconcile to individual ingress configs.
+			GlobalOriginRequest: nil,
+			IngressRules:        convertToUnvalidatedIngressRules(ing),
+			WarpRouting:         warpRouting.RawConfig(),
+		},
+	}, "", "  ")
+}
+
 func convertToUnvalidatedIngressRules(i ingress.Ingress) []config.UnvalidatedIngressRule {
 	result := make([]config.UnvalidatedIngressRule, 0)
 	for _, rule := range i.Rules {
@@ -59,6 +87,8 @@ func convertToUnvalidatedIngressRules(i ingress.Ingress) []config.UnvalidatedIng
 			Path:          path,
 			Service:       rule.Service.String(),
 			OriginRequest: ingress.ConvertToRawOriginConfig(rule.Config),
+			Critical:      rule.Critical,
+			Canary:        rule.Canary.RawConfig(),
 		}
 
 		result = append(result, newRule)
@@ -91,3 +91,47 @@ func TestNewLocalConfig_MarshalJSON(t *testing.T) {
 	})
 	require.Equal(t, remoteConfig.Ingress.Rules, expectedConfig.Ingress.Rules)
 }
+
+func TestDashboardConfigJSON(t *testing.T) {
+	rawConfig := []byte(`
+	{
+		"originRequest": {
+			"connectTimeout": 160
+		},
+		"ingress": [
+			{
+				"hostname": "tun.example.com",
+				"path": "/api",
+				"service": "https://localhost:8000",
+				"originRequest": {
+					"noTLSVerify": true
+				}
+			},
+			{
+				"service": "http_status:404"
+			}
+		],
+		"warp-routing": {
+			"connectTimeout": 1
+		}
+	}
+	`)
+
+	var expectedConfig ingress.RemoteConfig
+	require.NoError(t, json.Unmarshal(rawConfig, &expectedConfig))
+
+	dashboardJSON, err := DashboardConfigJSON(expectedConfig.Ingress, expectedConfig.WarpRouting)
+	require.NoError(t, err)
+
+	var body struct {
+		Config ingress.RemoteConfig `json:"config"`
+	}
+	require.NoError(t, json.Unmarshal(dashboardJSON, &body))
+	require.Equal(t, expectedConfig.Ingress.Rules, body.Config.Ingress.Rules)
+	require.Equal(t, expectedConfig.WarpRouting, body.Config.WarpRouting)
+
+	// The global originRequest is folded into each rule, since the dashboard only has per-rule settings
+	var rawBody map[string]map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(dashboardJSON, &rawBody))
+	require.NotContains(t, rawBody["config"], "originRequest")
+}
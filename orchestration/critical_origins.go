@@ -0,0 +1,58 @@
+package orchestration
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// criticalOriginsCheckInterval is how often the origins of the critical ingress rules are probed
+	criticalOriginsCheckInterval = 10 * time.Second
+	// criticalOriginsTimeout bounds how long a check waits for the critical origins
+	criticalOriginsTimeout = 5 * time.Second
+)
+
+// errCriticalOriginsNotChecked is reported until the critical origins have been probed once.
+var errCriticalOriginsNotChecked = errors.New("critical origins haven't been checked yet")
+
+// CheckCriticalOrigins returns the result of the last check of the origins of the critical ingress rules, which run in
+// the background so that readiness probes neither wait for nor add load to the origins.
+func (o *Orchestrator) CheckCriticalOrigins() error {
+	o.criticalOriginsLock.Lock()
+	defer o.criticalOriginsLock.Unlock()
+	return o.criticalOriginsErr
+}
+
+// watchCriticalOrigins checks the critical origins of the current configuration every interval, until the orchestrator
+// shuts down.
+func (o *Orchestrator) watchCriticalOrigins(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		o.checkCriticalOrigins()
+		select {
+		case <-o.shutdownC:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *Orchestrator) checkCriticalOrigins() {
+	o.lock.RLock()
+	ingressRules := *o.config.Ingress
+	o.lock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), criticalOriginsTimeout)
+	err := ingressRules.CheckCriticalOrigins(ctx, o.log)
+	cancel()
+	if err != nil {
+		o.log.Debug().Err(err).Msg("Critical origin check failed")
+	}
+
+	o.criticalOriginsLock.Lock()
+	o.criticalOriginsErr = err
+	o.criticalOriginsLock.Unlock()
+}
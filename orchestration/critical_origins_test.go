@@ -0,0 +1,53 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestCheckCriticalOriginsIsCached(t *testing.T) {
+	var requests atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer origin.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	orchestrator, err := NewOrchestrator(ctx, &Config{Ingress: &ingress.Ingress{}}, testTags, []ingress.Rule{}, &testLogger)
+	require.NoError(t, err)
+	updateWithValidation(t, orchestrator, 1, []byte(fmt.Sprintf(`
+{
+    "ingress": [
+        {
+            "service": "%s",
+            "critical": true
+        }
+    ],
+    "warp-routing": {}
+}
+`, origin.URL)))
+
+	orchestrator.checkCriticalOrigins()
+	require.NoError(t, orchestrator.CheckCriticalOrigins())
+	checks := requests.Load()
+	require.Positive(t, checks)
+
+	// Reading the result doesn't probe the origin again
+	for i := 0; i < 3; i++ {
+		require.NoError(t, orchestrator.CheckCriticalOrigins())
+	}
+	require.Equal(t, checks, requests.Load())
+
+	origin.Close()
+	orchestrator.checkCriticalOrigins()
+	require.Error(t, orchestrator.CheckCriticalOrigins())
+}
@@ -18,8 +18,16 @@ var (
 			Help:      "Configuration Version",
 		},
 	)
+	configUpdates = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "config_updates_total",
+			Help:      "Number of remote configuration updates applied",
+		},
+	)
 )
 
 func init() {
-	prometheus.MustRegister(configVersion)
+	prometheus.MustRegister(configVersion, configUpdates)
 }
@@ -39,6 +39,10 @@ type Orchestrator struct {
 	shutdownC <-chan struct{}
 	// Closing proxyShutdownC will close the previous proxy
 	proxyShutdownC chan<- struct{}
+
+	// criticalOriginsErr is the result of the last check of the critical origins
+	criticalOriginsLock sync.Mutex
+	criticalOriginsErr  error
 }
 
 func NewOrchestrator(ctx context.Context,
@@ -56,11 +60,15 @@ func NewOrchestrator(ctx context.Context,
 		tags:           tags,
 		log:            log,
 		shutdownC:      ctx.Done(),
+
+		criticalOriginsErr: errCriticalOriginsNotChecked,
 	}
 	if err := o.updateIngress(*config.Ingress, config.WarpRouting); err != nil {
 		return nil, err
 	}
 	go o.waitToCloseLastProxy()
+	go o.watchOriginFiles(originFilesPollInterval)
+	go o.watchCriticalOrigins(criticalOriginsCheckInterval)
 	return o, nil
 }
 
@@ -107,6 +115,7 @@ func (o *Orchestrator) UpdateConfig(version int32, config []byte) *pogs.UpdateCo
 		Str("config", string(config)).
 		Msg("Updated to new configuration")
 	configVersion.Set(float64(version))
+	configUpdates.Inc()
 	return &pogs.UpdateConfigurationResponse{
 		LastAppliedVersion: o.currentVersion,
 	}
@@ -137,6 +146,7 @@ func (o *Orchestrator) updateIngress(ingressRules ingress.Ingress, warpRouting i
 		return errors.Wrap(err, "failed to start origin")
 	}
 	proxy := proxy.NewOriginProxy(ingressRules, warpRouting, o.tags, o.config.WriteTimeout, o.log)
+	proxy.SetSlowRequestThreshold(o.config.SlowRequestThreshold)
 	o.proxy.Store(proxy)
 	o.config.Ingress = &ingressRules
 	o.config.WarpRouting = warpRouting
@@ -0,0 +1,101 @@
+package orchestration
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// originFilesPollInterval is how often the files referenced by the origin configurations are checked for changes
+const originFilesPollInterval = 10 * time.Second
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// ReloadOrigins restarts the origins of the current configuration, so that they load the files they reference again,
+// e.g. a rotated origin CA. Requests in flight finish with the previous origins, and the edge connections are kept.
+func (o *Orchestrator) ReloadOrigins() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	// The origins of the current ingress are running, so it is parsed again to get new ones
+	defaults := ingress.ConvertToRawOriginConfig(o.config.Ingress.Defaults)
+	rawConfig, err := json.Marshal(ingress.RemoteConfigJSON{
+		GlobalOriginRequest: &defaults,
+		IngressRules:        convertToUnvalidatedIngressRules(*o.config.Ingress),
+		WarpRouting:         o.config.WarpRouting.RawConfig(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize the current configuration")
+	}
+	var newConf newRemoteConfig
+	if err := json.Unmarshal(rawConfig, &newConf); err != nil {
+		return errors.Wrap(err, "failed to parse the current configuration")
+	}
+	return o.updateIngress(newConf.Ingress, newConf.WarpRouting)
+}
+
+// watchOriginFiles reloads the origins whenever one of the CA files referenced by the current configuration changes,
+// until the orchestrator shuts down.
+func (o *Orchestrator) watchOriginFiles(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	states := o.originFileStates()
+	for {
+		select {
+		case <-o.shutdownC:
+			return
+		case <-ticker.C:
+		}
+		newStates := o.originFileStates()
+		changed := changedFile(states, newStates)
+		states = newStates
+		if changed == "" {
+			continue
+		}
+		o.log.Info().Str("file", changed).Msg("Origin file changed, reloading the origins")
+		if err := o.ReloadOrigins(); err != nil {
+			o.log.Err(err).Msg("Failed to reload the origins, the previous ones are still used")
+		}
+	}
+}
+
+// originFileStates returns the state of the CA files referenced by the origins of the current configuration. Files
+// that can't be read are recorded with an empty state.
+func (o *Orchestrator) originFileStates() map[string]fileState {
+	o.lock.RLock()
+	rules := o.config.Ingress.Rules
+	o.lock.RUnlock()
+
+	states := make(map[string]fileState)
+	for _, rule := range rules {
+		path := rule.Config.CAPool
+		if path == "" {
+			continue
+		}
+		var state fileState
+		if info, err := os.Stat(path); err == nil {
+			state = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		states[path] = state
+	}
+	return states
+}
+
+// changedFile returns a file whose state differs between before and after, or an empty string if there is none.
+// Files that are only in after belong to a new configuration and were loaded with it.
+func changedFile(before, after map[string]fileState) string {
+	for path, state := range after {
+		if previous, ok := before[path]; ok && (!previous.modTime.Equal(state.modTime) || previous.size != state.size) {
+			return path
+		}
+	}
+	return ""
+}
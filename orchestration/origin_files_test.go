@@ -0,0 +1,111 @@
+package orchestration
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func selfSignedCertPEM(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Unrelated CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestReloadOriginsPicksUpRotatedCA(t *testing.T) {
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	// The handshakes with the wrong CA are expected to fail
+	origin.Config.ErrorLog = log.New(io.Discard, "", 0)
+	origin.StartTLS()
+	defer origin.Close()
+
+	caPath := filepath.Join(t.TempDir(), "origin-ca.pem")
+	require.NoError(t, os.WriteFile(caPath, selfSignedCertPEM(t), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	orchestrator, err := NewOrchestrator(ctx, &Config{Ingress: &ingress.Ingress{}}, testTags, []ingress.Rule{}, &testLogger)
+	require.NoError(t, err)
+	updateWithValidation(t, orchestrator, 1, []byte(fmt.Sprintf(`
+{
+    "ingress": [
+        {
+            "service": "%s",
+            "originRequest": {
+                "caPool": "%s"
+            }
+        }
+    ],
+    "warp-routing": {}
+}
+`, origin.URL, filepath.ToSlash(caPath))))
+
+	// The origin certificate isn't signed by the CA
+	originProxy, err := orchestrator.GetOriginProxy()
+	require.NoError(t, err)
+	_, err = proxyHTTP(originProxy, "app.example.com")
+	require.Error(t, err)
+
+	// The CA is rotated to the one of the origin, which is picked up when the file changes. The file is touched until
+	// then, since the watcher may only look at it for the first time after it was rotated.
+	go orchestrator.watchOriginFiles(10 * time.Millisecond)
+	originCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: origin.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caPath, originCA, 0o600))
+	modTime := time.Now()
+	require.Eventually(t, func() bool {
+		modTime = modTime.Add(time.Second)
+		require.NoError(t, os.Chtimes(caPath, modTime, modTime))
+		originProxy, err := orchestrator.GetOriginProxy()
+		if err != nil {
+			return false
+		}
+		resp, err := proxyHTTP(originProxy, "app.example.com")
+		return err == nil && resp.StatusCode == http.StatusTeapot
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// The configuration itself is unchanged
+	orchestrator.lock.RLock()
+	defer orchestrator.lock.RUnlock()
+	require.Equal(t, int32(1), orchestrator.currentVersion)
+	require.Equal(t, caPath, orchestrator.config.Ingress.Rules[0].Config.CAPool)
+}
+
+func TestChangedFile(t *testing.T) {
+	now := time.Now()
+	before := map[string]fileState{"a.pem": {modTime: now, size: 10}}
+	require.Empty(t, changedFile(before, map[string]fileState{"a.pem": {modTime: now, size: 10}}))
+	require.Empty(t, changedFile(before, map[string]fileState{"b.pem": {modTime: now, size: 10}}))
+	require.Equal(t, "a.pem", changedFile(before, map[string]fileState{"a.pem": {modTime: now.Add(time.Second), size: 10}}))
+	require.Equal(t, "a.pem", changedFile(before, map[string]fileState{"a.pem": {}}))
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// certExpiryWarning is how close to its expiry an origin certificate must be to be logged as a warning.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// observedCertExpiries holds the expiry of the last origin certificate seen by each rule, so that a certificate is only
+// logged the first time it is seen.
+var observedCertExpiries sync.Map
+
+// observeOriginCert reports how long the certificate presented by the origin of a rule is valid for. Responses that
+// didn't come over TLS are ignored.
+func observeOriginCert(ruleNum int, state *tls.ConnectionState, logger *zerolog.Logger) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return
+	}
+	cert := state.PeerCertificates[0]
+	untilExpiry := time.Until(cert.NotAfter)
+	originCertExpiryDays.WithLabelValues(strconv.Itoa(ruleNum)).Set(untilExpiry.Hours() / 24)
+
+	if previous, seen := observedCertExpiries.Swap(ruleNum, cert.NotAfter); seen && previous.(time.Time).Equal(cert.NotAfter) {
+		return
+	}
+	event := logger.Debug()
+	if untilExpiry < certExpiryWarning {
+		event = logger.Warn()
+	}
+	event.Str("subject", cert.Subject.String()).
+		Time("notAfter", cert.NotAfter).
+		Msgf("Origin certificate expires in %s", untilExpiry.Round(time.Hour))
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	// clientCertHeader carries the DER encoded client certificate that the eyeball presented to Cloudflare
+	clientCertHeader = "Cf-Client-Cert-Der-Base64"
+	// forwardedClientCertHeader follows the format used by Envoy and Istio, see
+	// https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_conn_man/headers#x-forwarded-client-cert
+	forwardedClientCertHeader = "X-Forwarded-Client-Cert"
+)
+
+// setForwardedClientCert replaces any X-Forwarded-Client-Cert header sent by the eyeball with the details of the
+// client certificate forwarded by Cloudflare, so that the origin can't be fooled by a spoofed header.
+func setForwardedClientCert(header http.Header) {
+	header.Del(forwardedClientCertHeader)
+
+	encodedCert := header.Get(clientCertHeader)
+	if encodedCert == "" {
+		return
+	}
+	der, err := base64.StdEncoding.DecodeString(encodedCert)
+	if err != nil {
+		return
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return
+	}
+	header.Set(forwardedClientCertHeader, formatForwardedClientCert(cert))
+}
+
+func formatForwardedClientCert(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	elements := []string{
+		"Hash=" + hex.EncodeToString(hash[:]),
+		"Subject=" + quoteForwardedClientCertValue(cert.Subject.String()),
+		"Issuer=" + quoteForwardedClientCertValue(cert.Issuer.String()),
+	}
+	for _, uri := range cert.URIs {
+		elements = append(elements, "URI="+quoteForwardedClientCertValue(uri.String()))
+	}
+	for _, dns := range cert.DNSNames {
+		elements = append(elements, "DNS="+quoteForwardedClientCertValue(dns))
+	}
+	return strings.Join(elements, ";")
+}
+
+// quoteForwardedClientCertValue quotes values containing separators, escaping the double quotes inside them.
+func quoteForwardedClientCertValue(value string) string {
+	if !strings.ContainsAny(value, `,;="`) {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetForwardedClientCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	spiffeID, err := url.Parse("spiffe://example.com/ns/default/sa/app")
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "app", Organization: []string{"Example, Inc"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{spiffeID},
+		DNSNames:     []string{"app.example.com"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	hash := sha256.Sum256(der)
+
+	header := http.Header{}
+	header.Set(clientCertHeader, base64.StdEncoding.EncodeToString(der))
+	header.Set(forwardedClientCertHeader, "Hash=spoofed")
+	setForwardedClientCert(header)
+	require.Equal(t,
+		"Hash="+hex.EncodeToString(hash[:])+
+			`;Subject="CN=app,O=Example\, Inc";Issuer="CN=app,O=Example\, Inc"`+
+			";URI=spiffe://example.com/ns/default/sa/app;DNS=app.example.com",
+		header.Get(forwardedClientCertHeader),
+	)
+
+	// Without a certificate from Cloudflare, a header sent by the eyeball is not trusted
+	header = http.Header{}
+	header.Set(forwardedClientCertHeader, "Hash=spoofed")
+	setForwardedClientCert(header)
+	require.Empty(t, header.Get(forwardedClientCertHeader))
+
+	header = http.Header{}
+	header.Set(clientCertHeader, "not a certificate")
+	setForwardedClientCert(header)
+	require.Empty(t, header.Get(forwardedClientCertHeader))
+}
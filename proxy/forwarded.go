@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+const (
+	xForwardedForHeader   = "X-Forwarded-For"
+	xForwardedProtoHeader = "X-Forwarded-Proto"
+	xForwardedHostHeader  = "X-Forwarded-Host"
+	// cfVisitorHeader holds the scheme used by the eyeball, e.g. {"scheme":"https"}
+	cfVisitorHeader = "Cf-Visitor"
+)
+
+// applyForwardedHeaders sets the X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers of a request to the
+// origin as configured for the rule. The values come from the eyeball IP and scheme reported by Cloudflare and from
+// the Host of the request, which must not have been replaced by httpHostHeader yet.
+func applyForwardedHeaders(req *http.Request, cfg ingress.OriginRequestConfig) {
+	applyForwardedHeader(req.Header, xForwardedForHeader, cfg.XForwardedFor, req.Header.Get(cfConnectingIPHeader))
+	applyForwardedHeader(req.Header, xForwardedProtoHeader, cfg.XForwardedProto, visitorScheme(req.Header))
+	applyForwardedHeader(req.Header, xForwardedHostHeader, cfg.XForwardedHost, req.Host)
+}
+
+// applyForwardedHeader applies mode to the header name. If value is unknown, append leaves the header as is and
+// replace removes it, since what the eyeball sent can't be trusted. append doesn't add value again when it is the last
+// hop already, as Cloudflare usually added it.
+func applyForwardedHeader(header http.Header, name, mode, value string) {
+	switch mode {
+	case ingress.ForwardedHeaderAppend:
+		if value == "" {
+			return
+		}
+		current := strings.Join(header.Values(name), ", ")
+		if current == "" {
+			header.Set(name, value)
+			return
+		}
+		hops := strings.Split(current, ",")
+		if strings.TrimSpace(hops[len(hops)-1]) == value {
+			return
+		}
+		header.Set(name, current+", "+value)
+	case ingress.ForwardedHeaderReplace:
+		if value == "" {
+			header.Del(name)
+			return
+		}
+		header.Set(name, value)
+	case ingress.ForwardedHeaderOmit:
+		header.Del(name)
+	}
+}
+
+// visitorScheme returns the scheme of the Cf-Visitor header, or an empty string if it's missing or malformed.
+func visitorScheme(header http.Header) string {
+	var visitor struct {
+		Scheme string `json:"scheme"`
+	}
+	if err := json.Unmarshal([]byte(header.Get(cfVisitorHeader)), &visitor); err != nil {
+		return ""
+	}
+	return visitor.Scheme
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+func TestApplyForwardedHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          ingress.OriginRequestConfig
+		forwardedFor string
+		expected     http.Header
+	}{
+		{
+			name: "default leaves the headers as received",
+			cfg:  ingress.OriginRequestConfig{},
+			expected: http.Header{
+				"X-Forwarded-For":   {"10.0.0.1, 192.0.2.1"},
+				"X-Forwarded-Proto": {"https"},
+			},
+		},
+		{
+			name: "append when the eyeball IP is the last hop",
+			cfg:  ingress.OriginRequestConfig{XForwardedFor: ingress.ForwardedHeaderAppend},
+			expected: http.Header{
+				"X-Forwarded-For":   {"10.0.0.1, 192.0.2.1"},
+				"X-Forwarded-Proto": {"https"},
+			},
+		},
+		{
+			name:         "append when the eyeball IP is not the last hop",
+			cfg:          ingress.OriginRequestConfig{XForwardedFor: ingress.ForwardedHeaderAppend},
+			forwardedFor: "192.0.2.1, 10.0.0.1",
+			expected: http.Header{
+				"X-Forwarded-For":   {"192.0.2.1, 10.0.0.1, 192.0.2.1"},
+				"X-Forwarded-Proto": {"https"},
+			},
+		},
+		{
+			name: "replace",
+			cfg: ingress.OriginRequestConfig{
+				XForwardedFor:   ingress.ForwardedHeaderReplace,
+				XForwardedProto: ingress.ForwardedHeaderReplace,
+				XForwardedHost:  ingress.ForwardedHeaderReplace,
+			},
+			expected: http.Header{
+				"X-Forwarded-For":   {"192.0.2.1"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"app.example.com"},
+			},
+		},
+		{
+			name: "omit",
+			cfg: ingress.OriginRequestConfig{
+				XForwardedFor:   ingress.ForwardedHeaderOmit,
+				XForwardedProto: ingress.ForwardedHeaderOmit,
+				XForwardedHost:  ingress.ForwardedHeaderOmit,
+			},
+			expected: http.Header{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "https://app.example.com/", nil)
+			assert.NoError(t, err)
+			req.Header.Set(cfConnectingIPHeader, "192.0.2.1")
+			req.Header.Set(cfVisitorHeader, `{"scheme":"https"}`)
+			forwardedFor := test.forwardedFor
+			if forwardedFor == "" {
+				forwardedFor = "10.0.0.1, 192.0.2.1"
+			}
+			req.Header.Set(xForwardedForHeader, forwardedFor)
+			req.Header.Set(xForwardedProtoHeader, "https")
+
+			applyForwardedHeaders(req, test.cfg)
+
+			for _, name := range []string{xForwardedForHeader, xForwardedProtoHeader, xForwardedHostHeader} {
+				assert.Equal(t, test.expected.Values(name), req.Header.Values(name), name)
+			}
+		})
+	}
+}
+
+func TestApplyForwardedHeadersWithoutCloudflareHeaders(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://app.example.com/", nil)
+	assert.NoError(t, err)
+	req.Header.Set(xForwardedForHeader, "10.0.0.1")
+	req.Header.Set(xForwardedProtoHeader, "http")
+
+	applyForwardedHeaders(req, ingress.OriginRequestConfig{
+		XForwardedFor:   ingress.ForwardedHeaderAppend,
+		XForwardedProto: ingress.ForwardedHeaderReplace,
+	})
+
+	// The eyeball IP is unknown so nothing is appended, and the spoofable scheme isn't kept
+	assert.Equal(t, "10.0.0.1", req.Header.Get(xForwardedForHeader))
+	assert.Empty(t, req.Header.Values(xForwardedProtoHeader))
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// originLimiter bounds the number of requests in flight to the origin of a single ingress rule, so that a slow origin
+// can't take all the capacity of the connector.
+type originLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+	inFlight     prometheus.Gauge
+	queued       prometheus.Gauge
+	rejected     prometheus.Counter
+	queueWait    prometheus.Observer
+}
+
+// newOriginLimiters returns the limiters of the rules, indexed by rule number. Rules without maxConcurrentRequests
+// have a nil limiter.
+func newOriginLimiters(rules []ingress.Rule) []*originLimiter {
+	limiters := make([]*originLimiter, len(rules))
+	for i, rule := range rules {
+		if rule.Config.MaxConcurrentRequests <= 0 {
+			continue
+		}
+		ruleLabel := strconv.Itoa(i)
+		limiters[i] = &originLimiter{
+			slots:        make(chan struct{}, rule.Config.MaxConcurrentRequests),
+			queueTimeout: rule.Config.QueueTimeout.Duration,
+			inFlight:     originConcurrentRequests.WithLabelValues(ruleLabel),
+			queued:       originQueuedRequests.WithLabelValues(ruleLabel),
+			rejected:     originLimitedRequests.WithLabelValues(ruleLabel),
+			queueWait:    originQueueWait.WithLabelValues(ruleLabel),
+		}
+	}
+	return limiters
+}
+
+// acquire reserves a slot for a request to the origin. If all of them are taken, it waits up to the queue timeout for
+// one to be released, and returns false if none was or if ctx is done first. Each successful call must be paired with
+// a call to release.
+func (l *originLimiter) acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		l.inFlight.Inc()
+		return true
+	default:
+	}
+
+	if l.queueTimeout > 0 {
+		start := time.Now()
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		l.queued.Inc()
+		select {
+		case l.slots <- struct{}{}:
+			l.queued.Dec()
+			l.queueWait.Observe(time.Since(start).Seconds())
+			l.inFlight.Inc()
+			return true
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		l.queued.Dec()
+		l.queueWait.Observe(time.Since(start).Seconds())
+	}
+	l.rejected.Inc()
+	return false
+}
+
+func (l *originLimiter) release() {
+	<-l.slots
+	l.inFlight.Dec()
+}
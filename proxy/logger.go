@@ -3,7 +3,9 @@ package proxy
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 
 	"github.com/cloudflare/cloudflared/connection"
@@ -18,10 +20,19 @@ const (
 	logFieldOriginService = "originService"
 	logFieldConnIndex     = "connIndex"
 	logFieldDestAddr      = "destAddr"
+	logFieldRequestID     = "requestID"
+	logFieldCanary        = "canary"
+	logFieldDuration      = "duration"
+
+	requestIDHeader = "X-Request-ID"
 )
 
 var (
 	LogFieldFlowID = "flowID"
+
+	// defaultRedactedHeaders are the headers logged without their values by debugHeaders, unless the rule has its
+	// own list.
+	defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
 )
 
 // newHTTPLogger creates a child zerolog.Logger from the provided with added context from the HTTP request, ingress
@@ -44,6 +55,17 @@ func newHTTPLogger(logger *zerolog.Logger, connIndex uint8, req *http.Request, r
 		Logger()
 }
 
+// withRequestID makes sure the request has an X-Request-ID header, generating one if the eyeball didn't send it, and
+// returns a logger that logs it with every message.
+func withRequestID(logger zerolog.Logger, req *http.Request) zerolog.Logger {
+	requestID := req.Header.Get(requestIDHeader)
+	if requestID == "" {
+		requestID = uuid.NewString()
+		req.Header.Set(requestIDHeader, requestID)
+	}
+	return logger.With().Str(logFieldRequestID, requestID).Logger()
+}
+
 // newTCPLogger creates a child zerolog.Logger from the provided with added context from the TCPRequest.
 func newTCPLogger(logger *zerolog.Logger, req *connection.TCPRequest) zerolog.Logger {
 	return logger.With().
@@ -66,6 +88,39 @@ func logHTTPRequest(logger *zerolog.Logger, r *http.Request) {
 		Msgf("%s %s %s", r.Method, r.URL, r.Proto)
 }
 
+// logMatchedRule logs a Debug message with the ingress rule that matched the request. The rule number and its
+// service are already part of the logger context.
+func logMatchedRule(logger *zerolog.Logger, rule *ingress.Rule) {
+	event := logger.Debug().Str("ruleHostname", rule.Hostname)
+	if rule.Path != nil && rule.Path.Regexp != nil {
+		event.Str("rulePath", rule.Path.String())
+	}
+	event.Msg("Matched ingress rule")
+}
+
+// logDebugHeaders logs a Debug message with headers if the rule has debugHeaders, replacing the values of the
+// redacted ones.
+func logDebugHeaders(logger *zerolog.Logger, cfg ingress.OriginRequestConfig, headers http.Header, msg string) {
+	if !cfg.DebugHeaders {
+		return
+	}
+	event := logger.Debug()
+	if !event.Enabled() {
+		return
+	}
+	redacted := cfg.DebugHeadersRedact
+	if len(redacted) == 0 {
+		redacted = defaultRedactedHeaders
+	}
+	logged := headers.Clone()
+	for _, name := range redacted {
+		if values := logged.Values(name); len(values) > 0 {
+			logged[http.CanonicalHeaderKey(name)] = []string{"REDACTED"}
+		}
+	}
+	event.Interface("headers", logged).Msg(msg)
+}
+
 // logOriginHTTPResponse logs a Debug message of the origin response.
 func logOriginHTTPResponse(logger *zerolog.Logger, resp *http.Response) {
 	responseByCode.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
@@ -74,6 +129,20 @@ func logOriginHTTPResponse(logger *zerolog.Logger, resp *http.Response) {
 		Msgf("%s", resp.Status)
 }
 
+// logSlowRequest logs a Warn message for the request if it took at least threshold since start to be proxied.
+func logSlowRequest(logger *zerolog.Logger, r *http.Request, start time.Time, threshold time.Duration) {
+	duration := time.Since(start)
+	if duration < threshold {
+		return
+	}
+	logger.Warn().
+		Str("method", r.Method).
+		Str("host", r.Host).
+		Str("path", r.URL.Path).
+		Str(logFieldDuration, duration.String()).
+		Msgf("Request took longer than %s", threshold)
+}
+
 // logRequestError logs an error for the proxied request.
 func logRequestError(logger *zerolog.Logger, err error) {
 	requestErrors.Inc()
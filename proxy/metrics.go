@@ -1,9 +1,13 @@
 package proxy
 
 import (
+	"strconv"
+	"sync/atomic"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/ingress"
 )
 
 // Metrics uses connection.MetricsNamespace(aka cloudflared) as namespace and connection.TunnelSubsystem
@@ -35,6 +39,15 @@ var (
 		},
 		[]string{"status_code"},
 	)
+	responsesByRule = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: connection.TunnelSubsystem,
+			Name:      "responses_total",
+			Help:      "Count of origin responses by HTTP status code and by hostname of the ingress rule",
+		},
+		[]string{"code", "rule"},
+	)
 	requestErrors = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: connection.MetricsNamespace,
@@ -76,6 +89,52 @@ var (
 			Help:      "Total count of failure to establish and acknowledge connections",
 		},
 	)
+	originConcurrentRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: "proxy",
+			Name:      "origin_concurrent_requests",
+			Help:      "Concurrent requests to the origin of each ingress rule that limits them with maxConcurrentRequests",
+		},
+		[]string{"ingress_rule"},
+	)
+	originQueuedRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: "proxy",
+			Name:      "origin_queued_requests",
+			Help:      "Requests waiting for a free slot to the origin of each ingress rule that queues them with queueTimeout",
+		},
+		[]string{"ingress_rule"},
+	)
+	originLimitedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: "proxy",
+			Name:      "origin_limited_requests",
+			Help:      "Count of requests rejected because the origin of their ingress rule reached maxConcurrentRequests",
+		},
+		[]string{"ingress_rule"},
+	)
+	originQueueWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: "proxy",
+			Name:      "origin_queue_wait_seconds",
+			Help:      "Time requests waited for a free slot to the origin of each ingress rule that queues them with queueTimeout",
+			Buckets:   []float64{0.001, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+		},
+		[]string{"ingress_rule"},
+	)
+	originCertExpiryDays = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: "proxy",
+			Name:      "origin_cert_expiry_days",
+			Help:      "Days until the certificate last presented by the TLS origin of each ingress rule expires",
+		},
+		[]string{"ingress_rule"},
+	)
 )
 
 func init() {
@@ -83,21 +142,57 @@ func init() {
 		totalRequests,
 		concurrentRequests,
 		responseByCode,
+		responsesByRule,
 		requestErrors,
 		activeTCPSessions,
 		totalTCPSessions,
 		connectLatency,
 		connectStreamErrors,
+		originConcurrentRequests,
+		originQueuedRequests,
+		originLimitedRequests,
+		originQueueWait,
+		originCertExpiryDays,
 	)
 }
 
+// activeRequests mirrors concurrentRequests so it can be read without going through prometheus
+var activeRequests atomic.Int64
+
+// ActiveRequests returns the number of requests and TCP sessions being proxied at the moment.
+func ActiveRequests() int64 {
+	return activeRequests.Load()
+}
+
+// requestCountdown is closed after a number of requests and TCP sessions finished, see NotifyAfterRequests.
+type requestCountdown struct {
+	remaining atomic.Int64
+	doneC     chan struct{}
+}
+
+var countdown atomic.Pointer[requestCountdown]
+
+// NotifyAfterRequests returns a channel that is closed once n more requests and TCP sessions have finished being
+// proxied. Only the last call is notified.
+func NotifyAfterRequests(n int64) <-chan struct{} {
+	c := &requestCountdown{doneC: make(chan struct{})}
+	c.remaining.Store(n)
+	countdown.Store(c)
+	return c.doneC
+}
+
 func incrementRequests() {
 	totalRequests.Inc()
 	concurrentRequests.Inc()
+	activeRequests.Add(1)
 }
 
 func decrementConcurrentRequests() {
 	concurrentRequests.Dec()
+	activeRequests.Add(-1)
+	if c := countdown.Load(); c != nil && c.remaining.Add(-1) == 0 {
+		close(c.doneC)
+	}
 }
 
 func incrementTCPRequests() {
@@ -110,3 +205,13 @@ func decrementTCPConcurrentRequests() {
 	decrementConcurrentRequests()
 	activeTCPSessions.Dec()
 }
+
+// countResponse counts a response of the origin of rule. Rules are labeled by their hostname, which is bounded by the
+// configuration unlike the hostnames of the requests, so rules that only differ by path share their counts.
+func countResponse(rule *ingress.Rule, statusCode int) {
+	hostname := rule.Hostname
+	if hostname == "" {
+		hostname = "*"
+	}
+	responsesByRule.WithLabelValues(strconv.Itoa(statusCode), hostname).Inc()
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -17,6 +19,7 @@ import (
 	"github.com/cloudflare/cloudflared/cfio"
 	"github.com/cloudflare/cloudflared/connection"
 	"github.com/cloudflare/cloudflared/ingress"
+	"github.com/cloudflare/cloudflared/logger"
 	"github.com/cloudflare/cloudflared/stream"
 	"github.com/cloudflare/cloudflared/tracing"
 	"github.com/cloudflare/cloudflared/tunnelrpc/pogs"
@@ -26,15 +29,21 @@ const (
 	// TagHeaderNamePrefix indicates a Cloudflared Warp Tag prefix that gets appended for warp traffic stream headers.
 	TagHeaderNamePrefix = "Cf-Warp-Tag-"
 	trailerHeaderName   = "Trailer"
+
+	cloudflareHeaderPrefix = "Cf-"
+	cfConnectingIPHeader   = "Cf-Connecting-Ip"
 )
 
 // Proxy represents a means to Proxy between cloudflared and the origin services.
 type Proxy struct {
-	ingressRules ingress.Ingress
-	warpRouting  *ingress.WarpRoutingService
-	management   *ingress.ManagementService
-	tags         []pogs.Tag
-	log          *zerolog.Logger
+	ingressRules   ingress.Ingress
+	originLimiters []*originLimiter
+	warpRouting    *ingress.WarpRoutingService
+	management     *ingress.ManagementService
+	tags           []pogs.Tag
+	log            *zerolog.Logger
+
+	slowRequestThreshold time.Duration
 }
 
 // NewOriginProxy returns a new instance of the Proxy struct.
@@ -46,9 +55,10 @@ func NewOriginProxy(
 	log *zerolog.Logger,
 ) *Proxy {
 	proxy := &Proxy{
-		ingressRules: ingressRules,
-		tags:         tags,
-		log:          log,
+		ingressRules:   ingressRules,
+		originLimiters: newOriginLimiters(ingressRules.Rules),
+		tags:           tags,
+		log:            log,
 	}
 
 	proxy.warpRouting = ingress.NewWarpRoutingService(warpRouting, writeTimeout)
@@ -56,6 +66,13 @@ func NewOriginProxy(
 	return proxy
 }
 
+// SetSlowRequestThreshold makes the proxy log a warning for every HTTP request that takes at least threshold to be
+// proxied. Websockets are excluded since they last as long as the connection. It must be called before the proxy
+// serves requests. 0 disables it.
+func (p *Proxy) SetSlowRequestThreshold(threshold time.Duration) {
+	p.slowRequestThreshold = threshold
+}
+
 func (p *Proxy) applyIngressMiddleware(rule *ingress.Rule, r *http.Request, w connection.ResponseWriter) (error, bool) {
 	for _, handler := range rule.Handlers {
 		result, err := handler.Handle(r.Context(), r)
@@ -64,6 +81,16 @@ func (p *Proxy) applyIngressMiddleware(rule *ingress.Rule, r *http.Request, w co
 		}
 
 		if result.ShouldFilterRequest {
+			logger.AuditEvent(logger.AuditEventAccessDenied).
+				Str("handler", handler.Name()).
+				Str("host", r.Host).
+				Str("path", r.URL.Path).
+				Str("method", r.Method).
+				Str("clientIP", r.Header.Get(cfConnectingIPHeader)).
+				Str("ray", r.Header.Get("Cf-Ray")).
+				Int("status", result.StatusCode).
+				Str("reason", result.Reason).
+				Msg("Request denied")
 			w.WriteRespHeaders(result.StatusCode, nil)
 			return fmt.Errorf("request filtered by middleware handler (%s) due to: %s", handler.Name(), result.Reason), true
 		}
@@ -89,8 +116,23 @@ func (p *Proxy) ProxyHTTP(
 	rule, ruleNum := p.ingressRules.FindMatchingRule(req.Host, req.URL.Path)
 	ruleSpan.SetAttributes(attribute.Int("rule-num", ruleNum))
 	ruleSpan.End()
-	logger := newHTTPLogger(p.log, tr.ConnIndex, req, ruleNum, rule.Service.String())
+	service := rule.Service
+	isCanary := rule.Canary != nil && rule.Canary.Selects(req)
+	if isCanary {
+		service = rule.Canary.Service
+	}
+	logger := newHTTPLogger(p.log, tr.ConnIndex, req, ruleNum, service.String())
+	if isCanary {
+		logger = logger.With().Bool(logFieldCanary, true).Logger()
+	}
+	if rule.Config.RequestID {
+		logger = withRequestID(logger, req)
+	}
+	if p.slowRequestThreshold > 0 && !isWebsocket {
+		defer logSlowRequest(&logger, req, time.Now(), p.slowRequestThreshold)
+	}
 	logHTTPRequest(&logger, req)
+	logMatchedRule(&logger, rule)
 	if err, applied := p.applyIngressMiddleware(rule, req, w); err != nil {
 		if applied {
 			logRequestError(&logger, err)
@@ -99,14 +141,33 @@ func (p *Proxy) ProxyHTTP(
 		return err
 	}
 
-	switch originProxy := rule.Service.(type) {
+	if rule.Config.LocalHealthPath != "" && req.URL.Path == rule.Config.LocalHealthPath {
+		writeLocalHealth(w, rule.Config, &logger)
+		return nil
+	}
+
+	if rule.Config.MaintenanceFile != "" && writeMaintenancePage(w, rule.Config.MaintenanceFile, &logger) {
+		return nil
+	}
+
+	if limiter := p.originLimiter(ruleNum); limiter != nil {
+		if !limiter.acquire(req.Context()) {
+			w.WriteRespHeaders(http.StatusServiceUnavailable, nil)
+			logRequestError(&logger, fmt.Errorf("origin reached its limit of %d concurrent requests", rule.Config.MaxConcurrentRequests))
+			return nil
+		}
+		defer limiter.release()
+	}
+
+	switch originProxy := service.(type) {
 	case ingress.HTTPOriginProxy:
 		if err := p.proxyHTTPRequest(
 			w,
 			tr,
 			originProxy,
 			isWebsocket,
-			rule.Config.DisableChunkedEncoding,
+			rule,
+			ruleNum,
 			&logger,
 		); err != nil {
 			logRequestError(&logger, err)
@@ -133,8 +194,16 @@ func (p *Proxy) ProxyHTTP(
 		p.proxyLocalRequest(originProxy, w, req, isWebsocket)
 		return nil
 	default:
-		return fmt.Errorf("Unrecognized service: %s, %t", rule.Service, originProxy)
+		return fmt.Errorf("Unrecognized service: %s, %t", service, originProxy)
+	}
+}
+
+// originLimiter returns the limiter of concurrent requests of a user-defined rule, if it has one.
+func (p *Proxy) originLimiter(ruleNum int) *originLimiter {
+	if ruleNum < 0 || ruleNum >= len(p.originLimiters) {
+		return nil
 	}
+	return p.originLimiters[ruleNum]
 }
 
 // ProxyTCP proxies to a TCP connection between the origin service and cloudflared.
@@ -159,7 +228,7 @@ func (p *Proxy) ProxyTCP(
 	tracedCtx := tracing.NewTracedContext(serveCtx, req.CfTraceID, &logger)
 	logger.Debug().Msg("tcp proxy stream started")
 
-	if err := p.proxyStream(tracedCtx, rwa, req.Dest, p.warpRouting.Proxy, &logger); err != nil {
+	if err := p.proxyStream(tracedCtx, rwa, p.warpRouting.OriginDest(req.Dest), p.warpRouting.Proxy, &logger); err != nil {
 		logRequestError(&logger, err)
 		return err
 	}
@@ -175,9 +244,17 @@ func (p *Proxy) proxyHTTPRequest(
 	tr *tracing.TracedHTTPRequest,
 	httpService ingress.HTTPOriginProxy,
 	isWebsocket bool,
-	disableChunkedEncoding bool,
+	rule *ingress.Rule,
+	ruleNum int,
 	logger *zerolog.Logger,
 ) error {
+	cfg := rule.Config
+	var rewriter *hostRewriter
+	if cfg.RewriteOriginHost && !isWebsocket {
+		// httpHostHeader replaces the Host of the request during the round trip, so the public hostname is read first
+		rewriter = newHostRewriter(httpService, cfg, tr.Request.Host)
+	}
+
 	roundTripReq := tr.Request
 	if isWebsocket {
 		roundTripReq = tr.Clone(tr.Request.Context())
@@ -188,7 +265,7 @@ func (p *Proxy) proxyHTTPRequest(
 		roundTripReq.Body = nil
 	} else {
 		// Support for WSGI Servers by switching transfer encoding from chunked to gzip/deflate
-		if disableChunkedEncoding {
+		if cfg.DisableChunkedEncoding {
 			roundTripReq.TransferEncoding = []string{"gzip", "deflate"}
 			cLength, err := strconv.Atoi(tr.Request.Header.Get("Content-Length"))
 			if err == nil {
@@ -199,29 +276,97 @@ func (p *Proxy) proxyHTTPRequest(
 		roundTripReq.Header.Set("Connection", "keep-alive")
 	}
 
+	if cfg.ForwardClientCert {
+		setForwardedClientCert(roundTripReq.Header)
+	}
+
+	// Applied before the Cloudflare headers it reads from are stripped
+	applyForwardedHeaders(roundTripReq, cfg)
+
+	if cfg.StripCloudflareHeaders {
+		stripCloudflareHeaders(roundTripReq.Header, cfg.PreserveCFConnectingIP)
+	}
+
+	if rewriter != nil {
+		// HTML is only rewritten uncompressed
+		roundTripReq.Header.Set("Accept-Encoding", "identity")
+	}
+
 	// Set the User-Agent as an empty string if not provided to avoid inserting golang default UA
 	if roundTripReq.Header.Get("User-Agent") == "" {
 		roundTripReq.Header.Set("User-Agent", "")
 	}
 
+	if cfg.RequestTimeout.Duration > 0 && !isWebsocket {
+		ctx, cancel := context.WithTimeout(roundTripReq.Context(), cfg.RequestTimeout.Duration)
+		defer cancel()
+		roundTripReq = roundTripReq.WithContext(ctx)
+	}
+
+	logDebugHeaders(logger, cfg, roundTripReq.Header, "Request headers to origin")
+
 	_, ttfbSpan := tr.Tracer().Start(tr.Context(), "ttfb_origin")
 	resp, err := httpService.RoundTrip(roundTripReq)
+	// Nothing was sent to the eyeball yet, so the request can be retried without delivering the response twice
+	for retry := uint(1); err != nil && retry <= cfg.RetryOnDisconnect && !isWebsocket && isRetryable(roundTripReq, err); retry++ {
+		logger.Debug().Err(err).Uint("retry", retry).Msg("Origin closed the connection, retrying the request")
+		resp, err = httpService.RoundTrip(roundTripReq)
+	}
 	if err != nil {
 		tracing.EndWithErrorStatus(ttfbSpan, err)
-		if err := roundTripReq.Context().Err(); err != nil {
+		connection.ReportFDExhausted(err, "origin", logger)
+		if err := tr.Request.Context().Err(); err != nil {
 			return errors.Wrap(err, "Incoming request ended abruptly")
 		}
-		return errors.Wrap(err, "Unable to reach the origin service. The service may be down or it may not be responding to traffic from cloudflared")
+		if roundTripReq.Context().Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("origin didn't answer within the request timeout of %s", cfg.RequestTimeout.Duration)
+			if page := cfg.ErrorPageContent(); page != nil {
+				writeErrorPage(w, http.StatusGatewayTimeout, page, logger)
+			} else {
+				w.WriteRespHeaders(http.StatusGatewayTimeout, nil)
+			}
+			logRequestError(logger, err)
+			return nil
+		}
+		err = errors.Wrap(err, "Unable to reach the origin service. The service may be down or it may not be responding to traffic from cloudflared")
+		if page := cfg.ErrorPageContent(); page != nil {
+			writeErrorPage(w, http.StatusBadGateway, page, logger)
+			logRequestError(logger, err)
+			return nil
+		}
+		return err
 	}
 
 	tracing.EndWithStatusCode(ttfbSpan, resp.StatusCode)
 	defer resp.Body.Close()
+	observeOriginCert(ruleNum, resp.TLS, logger)
+	remapStatus(resp, cfg.StatusMap, logger)
+	countResponse(rule, resp.StatusCode)
+	logDebugHeaders(logger, cfg, resp.Header, "Response headers from origin")
+	if rewriter != nil {
+		rewriter.rewriteResponse(resp)
+	}
+
+	if page := cfg.ErrorPageContent(); page != nil && isErrorPageStatus(resp.StatusCode) {
+		writeErrorPage(w, resp.StatusCode, page, logger)
+		logOriginHTTPResponse(logger, resp)
+		return nil
+	}
 
 	headers := make(http.Header, len(resp.Header))
 	// copy headers
 	for k, v := range resp.Header {
 		headers[k] = v
 	}
+	removeHeaders(headers, cfg.RemoveResponseHeaders)
+
+	if isCloseDelimited(resp) {
+		// The origin signals the end of the body by closing its connection (typical of HTTP/1.0 servers). The
+		// body is read until EOF below and the edge frames it on its own, so the origin's hop-by-hop connection
+		// management headers must not be relayed to the eyeball.
+		headers.Del("Connection")
+		headers.Del("Keep-Alive")
+	}
 
 	// Add spans to response header (if available)
 	tr.AddSpans(headers)
@@ -275,6 +420,7 @@ func (p *Proxy) proxyStream(
 	originConn, err := connectionProxy.EstablishConnection(ctx, dest, logger)
 	if err != nil {
 		connectStreamErrors.Inc()
+		connection.ReportFDExhausted(err, "origin", logger)
 		tracing.EndWithErrorStatus(connectSpan, err)
 		return err
 	}
@@ -326,6 +472,125 @@ func (p *Proxy) appendTagHeaders(r *http.Request) {
 	}
 }
 
+// isCloseDelimited returns true if the origin response has neither a Content-Length nor a chunked
+// Transfer-Encoding, meaning its body ends when the origin closes the connection.
+func isCloseDelimited(resp *http.Response) bool {
+	return resp.ContentLength < 0 && len(resp.TransferEncoding) == 0 && resp.Close
+}
+
+// remapStatus replaces the status of the origin response according to the statusMap of the rule.
+func remapStatus(resp *http.Response, statusMap map[int]int, logger *zerolog.Logger) {
+	status, ok := statusMap[resp.StatusCode]
+	if !ok {
+		return
+	}
+	logger.Debug().Msgf("Remapped origin response status %d to %d", resp.StatusCode, status)
+	resp.StatusCode = status
+	resp.Status = fmt.Sprintf("%d %s", status, http.StatusText(status))
+}
+
+// isErrorPageStatus returns true for the origin response status codes that are replaced by the custom error page.
+func isErrorPageStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// writeErrorPage responds with the custom error page and the given status.
+func writeErrorPage(w connection.ResponseWriter, status int, page []byte, logger *zerolog.Logger) {
+	headers := http.Header{
+		"Content-Type":   []string{"text/html; charset=utf-8"},
+		"Content-Length": []string{strconv.Itoa(len(page))},
+	}
+	if err := w.WriteRespHeaders(status, headers); err != nil {
+		logger.Err(err).Msg("Error writing custom error page header")
+		return
+	}
+	if _, err := w.Write(page); err != nil {
+		logger.Err(err).Msg("Error writing custom error page")
+	}
+}
+
+// writeLocalHealth answers a request to the localHealthPath of a rule, without sending it to the origin.
+func writeLocalHealth(w connection.ResponseWriter, cfg ingress.OriginRequestConfig, logger *zerolog.Logger) {
+	status := cfg.LocalHealthStatus
+	if status == 0 {
+		status = http.StatusOK
+	}
+	headers := http.Header{
+		"Content-Type":   []string{"text/plain; charset=utf-8"},
+		"Content-Length": []string{strconv.Itoa(len(cfg.LocalHealthBody))},
+	}
+	if err := w.WriteRespHeaders(status, headers); err != nil {
+		logger.Err(err).Msg("Error writing local health check response header")
+		return
+	}
+	if _, err := w.Write([]byte(cfg.LocalHealthBody)); err != nil {
+		logger.Err(err).Msg("Error writing local health check response")
+	}
+	logger.Debug().Msgf("Answered local health check with status %d", status)
+}
+
+// writeMaintenancePage answers the request with a 503 and the contents of maintenanceFile if that file exists. It
+// returns false, without writing anything, if the file doesn't exist and the request should be proxied.
+func writeMaintenancePage(w connection.ResponseWriter, maintenanceFile string, logger *zerolog.Logger) bool {
+	page, err := os.ReadFile(maintenanceFile)
+	if os.IsNotExist(err) {
+		return false
+	}
+	if err != nil {
+		// The file exists, so maintenance is on even if it can't be served
+		logger.Err(err).Msgf("Unable to read maintenance file %s", maintenanceFile)
+		page = nil
+	}
+	headers := http.Header{
+		"Content-Type":   []string{http.DetectContentType(page)},
+		"Content-Length": []string{strconv.Itoa(len(page))},
+		"Cache-Control":  []string{"no-store"},
+	}
+	if err := w.WriteRespHeaders(http.StatusServiceUnavailable, headers); err != nil {
+		logger.Err(err).Msg("Error writing maintenance response header")
+		return true
+	}
+	if _, err := w.Write(page); err != nil {
+		logger.Err(err).Msg("Error writing maintenance response")
+	}
+	logger.Debug().Msgf("Answered with the maintenance page %s", maintenanceFile)
+	return true
+}
+
+// removeHeaders removes the headers named in names from header. A name ending with * removes every header with
+// that prefix.
+func removeHeaders(header http.Header, names []string) {
+	for _, name := range names {
+		prefix, isPrefix := strings.CutSuffix(name, "*")
+		if !isPrefix {
+			header.Del(name)
+			continue
+		}
+		prefix = strings.ToLower(prefix)
+		for key := range header {
+			if strings.HasPrefix(strings.ToLower(key), prefix) {
+				delete(header, key)
+			}
+		}
+	}
+}
+
+// stripCloudflareHeaders removes the CF-* headers added by Cloudflare to the eyeball request, optionally keeping
+// CF-Connecting-IP for origins that need the client IP. The Cf-Warp-Tag-* headers come from this connector's own
+// --tag configuration, so they are kept.
+func stripCloudflareHeaders(header http.Header, preserveConnectingIP bool) {
+	for name := range header {
+		canonicalName := http.CanonicalHeaderKey(name)
+		if !strings.HasPrefix(canonicalName, cloudflareHeaderPrefix) || strings.HasPrefix(canonicalName, TagHeaderNamePrefix) {
+			continue
+		}
+		if preserveConnectingIP && strings.EqualFold(name, cfConnectingIPHeader) {
+			continue
+		}
+		header.Del(name)
+	}
+}
+
 func copyTrailers(w connection.ResponseWriter, response *http.Response) {
 	for trailerHeader, trailerValues := range response.Trailer {
 		for _, trailerValue := range trailerValues {
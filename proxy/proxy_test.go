@@ -3,20 +3,29 @@ package proxy
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gobwas/ws/wsutil"
 	gorillaWS "github.com/gorilla/websocket"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -386,6 +395,188 @@ func runIngressTestScenarios(t *testing.T, unvalidatedIngress []config.Unvalidat
 	cancel()
 }
 
+// Regression test to guarantee that bodies of HTTP/1.0 origins, which end when the origin closes the connection,
+// are relayed in full.
+func TestProxyHTTP10CloseDelimitedBody(t *testing.T) {
+	body := strings.Repeat("embedded device response body\n", 1024)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+					return
+				}
+				_, _ = io.WriteString(conn, "HTTP/1.0 200 OK\r\nContent-Type: text/plain\r\nConnection: close\r\n\r\n")
+				_, _ = io.WriteString(conn, body)
+			}(conn)
+		}
+	}()
+
+	unvalidatedIngress := []config.UnvalidatedIngressRule{
+		{
+			Hostname: "*",
+			Service:  fmt.Sprintf("http://%s", listener.Addr()),
+		},
+	}
+
+	ingress, err := ingress.ParseIngress(&config.Configuration{
+		TunnelID: t.Name(),
+		Ingress:  unvalidatedIngress,
+	})
+	require.NoError(t, err)
+
+	log := zerolog.Nop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, ingress.StartOrigins(&log, ctx.Done()))
+
+	proxy := NewOriginProxy(ingress, noWarpRouting, testTags, time.Duration(0), &log)
+
+	for i := 0; i < 3; i++ {
+		responseWriter := newMockHTTPRespWriter()
+		req, err := http.NewRequest(http.MethodGet, "http://device.example.com", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, body, responseWriter.Body.String())
+		assert.Empty(t, responseWriter.Header().Get("Connection"))
+	}
+}
+
+func TestProxyStripCloudflareHeaders(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range r.Header {
+			w.Header()["Echo-"+name] = values
+		}
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name            string
+		originRequest   config.OriginRequestConfig
+		expectedPresent []string
+		expectedAbsent  []string
+	}{
+		{
+			name:            "default keeps headers",
+			expectedPresent: []string{"Cf-Connecting-Ip", "Cf-Ray", "Cf-Visitor", "X-Custom"},
+		},
+		{
+			name:            "strip",
+			originRequest:   config.OriginRequestConfig{StripCloudflareHeaders: boolPtr(true)},
+			expectedPresent: []string{"X-Custom", "Cf-Warp-Tag-Name"},
+			expectedAbsent:  []string{"Cf-Connecting-Ip", "Cf-Ray", "Cf-Visitor"},
+		},
+		{
+			name: "strip preserving connecting ip",
+			originRequest: config.OriginRequestConfig{
+				StripCloudflareHeaders: boolPtr(true),
+				PreserveCFConnectingIP: boolPtr(true),
+			},
+			expectedPresent: []string{"Cf-Connecting-Ip", "X-Custom"},
+			expectedAbsent:  []string{"Cf-Ray", "Cf-Visitor"},
+		},
+	}
+
+	log := zerolog.Nop()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ing, err := ingress.ParseIngress(&config.Configuration{
+				TunnelID: t.Name(),
+				Ingress: []config.UnvalidatedIngressRule{
+					{
+						Service:       origin.URL,
+						OriginRequest: test.originRequest,
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			require.NoError(t, ing.StartOrigins(&log, ctx.Done()))
+			proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+			req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+			require.NoError(t, err)
+			req.Header.Set("Cf-Connecting-Ip", "203.0.113.1")
+			req.Header.Set("Cf-Ray", "8a1b2c3d4e5f6a7b-LHR")
+			req.Header.Set("Cf-Visitor", `{"scheme":"https"}`)
+			req.Header.Set("X-Custom", "value")
+
+			responseWriter := newMockHTTPRespWriter()
+			require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+			require.Equal(t, http.StatusOK, responseWriter.Code)
+
+			for _, header := range test.expectedPresent {
+				assert.NotEmpty(t, responseWriter.Header().Get("Echo-"+header), header)
+			}
+			for _, header := range test.expectedAbsent {
+				assert.Empty(t, responseWriter.Header().Get("Echo-"+header), header)
+			}
+		})
+	}
+}
+
+func TestProxyKeepsEyeballAcceptEncoding(t *testing.T) {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	_, err := gz.Write([]byte("compressed body"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip, br", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(body.Bytes())
+	}))
+	defer origin.Close()
+
+	log := zerolog.Nop()
+	for _, disableCompression := range []bool{false, true} {
+		ing, err := ingress.ParseIngress(&config.Configuration{
+			TunnelID: t.Name(),
+			Ingress: []config.UnvalidatedIngressRule{
+				{
+					Service:       origin.URL,
+					OriginRequest: config.OriginRequestConfig{DisableCompression: boolPtr(disableCompression)},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, ing.StartOrigins(&log, ctx.Done()))
+		proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", "gzip, br")
+
+		// The eyeball asked for an encoding itself, so the response reaches it still compressed either way
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+		require.Equal(t, http.StatusOK, responseWriter.Code)
+		require.Equal(t, "gzip", responseWriter.Header().Get("Content-Encoding"))
+		require.Equal(t, body.Bytes(), responseWriter.Body.Bytes())
+		cancel()
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 type mockAPI struct{}
 
 func (ma mockAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -423,6 +614,436 @@ func TestProxyError(t *testing.T) {
 	assert.Error(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
 }
 
+func TestProxyErrorPage(t *testing.T) {
+	errorPage := []byte("<html><body>We'll be right back</body></html>")
+	errorPagePath := filepath.Join(t.TempDir(), "error.html")
+	require.NoError(t, os.WriteFile(errorPagePath, errorPage, 0o600))
+
+	unavailableOrigin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("origin body"))
+	}))
+	defer unavailableOrigin.Close()
+
+	tests := []struct {
+		name           string
+		service        ingress.OriginService
+		errorPage      string
+		expectErr      bool
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{
+			name:      "unreachable origin without error page",
+			service:   ingress.MockOriginHTTPService{Transport: errorOriginTransport{}},
+			expectErr: true,
+		},
+		{
+			name:           "unreachable origin with error page",
+			service:        ingress.MockOriginHTTPService{Transport: errorOriginTransport{}},
+			errorPage:      errorPagePath,
+			expectedStatus: http.StatusBadGateway,
+			expectedBody:   errorPage,
+		},
+		{
+			name:           "origin error without error page",
+			service:        ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   []byte("origin body"),
+		},
+		{
+			name:           "origin error with error page",
+			service:        ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+			errorPage:      errorPagePath,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   errorPage,
+		},
+	}
+
+	log := zerolog.Nop()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := ingress.OriginRequestConfig{ErrorPage: test.errorPage}
+			require.NoError(t, cfg.LoadErrorPage())
+			ing := ingress.Ingress{
+				Rules: []ingress.Rule{
+					{
+						Hostname: "*",
+						Service:  test.service,
+						Config:   cfg,
+					},
+				},
+			}
+			proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+			responseWriter := newMockHTTPRespWriter()
+			req, err := http.NewRequest(http.MethodGet, unavailableOrigin.URL, nil)
+			require.NoError(t, err)
+
+			err = proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false)
+			if test.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedStatus, responseWriter.Code)
+			assert.Equal(t, test.expectedBody, responseWriter.Body.Bytes())
+		})
+	}
+}
+
+// blockingOriginTransport holds requests until release is closed.
+type blockingOriginTransport struct {
+	received chan struct{}
+	release  chan struct{}
+}
+
+func (t blockingOriginTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	t.received <- struct{}{}
+	<-t.release
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("ok")),
+	}, nil
+}
+
+func TestProxyMaxConcurrentRequests(t *testing.T) {
+	transport := blockingOriginTransport{
+		received: make(chan struct{}, 2),
+		release:  make(chan struct{}),
+	}
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: transport},
+				Config:   ingress.OriginRequestConfig{MaxConcurrentRequests: 1},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	proxyRequest := func() *mockHTTPRespWriter {
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+		return responseWriter
+	}
+
+	firstDone := make(chan *mockHTTPRespWriter)
+	go func() {
+		firstDone <- proxyRequest()
+	}()
+	<-transport.received
+
+	// The only slot is taken by the first request
+	assert.Equal(t, http.StatusServiceUnavailable, proxyRequest().Code)
+
+	close(transport.release)
+	assert.Equal(t, http.StatusOK, (<-firstDone).Code)
+
+	// The slot is available again
+	assert.Equal(t, http.StatusOK, proxyRequest().Code)
+}
+
+func TestProxyQueueTimeout(t *testing.T) {
+	transport := blockingOriginTransport{
+		received: make(chan struct{}, 2),
+		release:  make(chan struct{}),
+	}
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: transport},
+				Config: ingress.OriginRequestConfig{
+					MaxConcurrentRequests: 1,
+					QueueTimeout:          config.CustomDuration{Duration: 100 * time.Millisecond},
+				},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	proxyRequest := func() *mockHTTPRespWriter {
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+		return responseWriter
+	}
+
+	firstDone := make(chan *mockHTTPRespWriter)
+	go func() {
+		firstDone <- proxyRequest()
+	}()
+	<-transport.received
+
+	// The slot isn't released within the queue timeout
+	start := time.Now()
+	assert.Equal(t, http.StatusServiceUnavailable, proxyRequest().Code)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	queued := func() float64 {
+		var metric dto.Metric
+		require.NoError(t, originQueuedRequests.WithLabelValues("0").Write(&metric))
+		return metric.GetGauge().GetValue()
+	}
+	assert.Equal(t, float64(0), queued())
+
+	// The queued request gets the slot once the first request releases it
+	queuedDone := make(chan *mockHTTPRespWriter)
+	go func() {
+		queuedDone <- proxyRequest()
+	}()
+	require.Eventually(t, func() bool { return queued() == 1 }, time.Second, time.Millisecond)
+	close(transport.release)
+	assert.Equal(t, http.StatusOK, (<-firstDone).Code)
+	assert.Equal(t, http.StatusOK, (<-queuedDone).Code)
+	assert.Equal(t, float64(0), queued())
+}
+
+func TestNotifyAfterRequests(t *testing.T) {
+	defer countdown.Store(nil)
+
+	transport := blockingOriginTransport{
+		received: make(chan struct{}, 3),
+		release:  make(chan struct{}),
+	}
+	close(transport.release)
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: transport},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	proxyRequest := func() {
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+		require.NoError(t, err)
+		require.NoError(t, proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false))
+	}
+
+	doneC := NotifyAfterRequests(2)
+	proxyRequest()
+	select {
+	case <-doneC:
+		t.Fatal("notified after a single request")
+	default:
+	}
+	proxyRequest()
+	select {
+	case <-doneC:
+	default:
+		t.Fatal("not notified after two requests")
+	}
+	// Further requests don't close the channel again
+	proxyRequest()
+}
+
+// hangingOriginTransport never answers, until the request is cancelled.
+type hangingOriginTransport struct{}
+
+func (hangingOriginTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestProxyRequestTimeout(t *testing.T) {
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: hangingOriginTransport{}},
+				Config: ingress.OriginRequestConfig{
+					RequestTimeout: config.CustomDuration{Duration: 50 * time.Millisecond},
+				},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	start := time.Now()
+	require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+	assert.Equal(t, http.StatusGatewayTimeout, responseWriter.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// The request of the eyeball ending isn't a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, "http://app.example.com", nil)
+	require.NoError(t, err)
+	err = proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false)
+	assert.ErrorContains(t, err, "Incoming request ended abruptly")
+}
+
+func TestProxyLogsMatchedRule(t *testing.T) {
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "app.example.com",
+				Path:     &ingress.Regexp{Regexp: regexp.MustCompile("^/api")},
+				Service:  ingress.MockOriginHTTPService{Transport: errorOriginTransport{}},
+			},
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: errorOriginTransport{}},
+			},
+		},
+	}
+	var logs bytes.Buffer
+	log := zerolog.New(&logs).Level(zerolog.DebugLevel)
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	req, err := http.NewRequest(http.MethodGet, "http://app.example.com/api/users", nil)
+	require.NoError(t, err)
+	_ = proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false)
+
+	var matched map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry["message"] == "Matched ingress rule" {
+			matched = entry
+		}
+	}
+	require.NotNil(t, matched)
+	assert.Equal(t, float64(0), matched[logFieldRule])
+	assert.Equal(t, "app.example.com", matched["ruleHostname"])
+	assert.Equal(t, "^/api", matched["rulePath"])
+	assert.NotEmpty(t, matched[logFieldOriginService])
+}
+
+func TestProxyDebugHeaders(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Origin", "origin")
+	}))
+	defer origin.Close()
+
+	readLogs := func(logs *bytes.Buffer) map[string]map[string]interface{} {
+		headers := make(map[string]map[string]interface{})
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			if msg, _ := entry["message"].(string); strings.HasSuffix(msg, "headers to origin") || strings.HasSuffix(msg, "headers from origin") {
+				headers[msg] = entry["headers"].(map[string]interface{})
+			}
+		}
+		return headers
+	}
+
+	tests := []struct {
+		name   string
+		cfg    ingress.OriginRequestConfig
+		logged bool
+		redact []string
+	}{
+		{name: "disabled", cfg: ingress.OriginRequestConfig{}},
+		{name: "default redaction", cfg: ingress.OriginRequestConfig{DebugHeaders: true}, logged: true, redact: []string{"Authorization", "Set-Cookie"}},
+		{name: "custom redaction", cfg: ingress.OriginRequestConfig{DebugHeaders: true, DebugHeadersRedact: []string{"x-origin", "X-Eyeball"}}, logged: true, redact: []string{"X-Origin", "X-Eyeball"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ing := ingress.Ingress{
+				Rules: []ingress.Rule{
+					{
+						Hostname: "*",
+						Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+						Config:   test.cfg,
+					},
+				},
+			}
+			var logs bytes.Buffer
+			log := zerolog.New(&logs).Level(zerolog.DebugLevel)
+			proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+			req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("X-Eyeball", "eyeball")
+			require.NoError(t, proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false))
+
+			headers := readLogs(&logs)
+			if !test.logged {
+				require.Empty(t, headers)
+				return
+			}
+			require.Len(t, headers, 2)
+			all := make(map[string]interface{})
+			for _, logged := range headers {
+				for name, values := range logged {
+					all[name] = values
+				}
+			}
+			for _, name := range []string{"Authorization", "X-Eyeball", "Set-Cookie", "X-Origin"} {
+				require.Contains(t, all, name)
+				values := fmt.Sprint(all[name])
+				if slices.Contains(test.redact, name) {
+					assert.Equal(t, "[REDACTED]", values, name)
+				} else {
+					assert.NotContains(t, values, "REDACTED", name)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyStatusMap(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bad-gateway":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte("origin body"))
+	}))
+	defer origin.Close()
+
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+				Config: ingress.OriginRequestConfig{
+					StatusMap: map[int]int{http.StatusBadGateway: http.StatusServiceUnavailable},
+				},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{path: "/bad-gateway", expectedStatus: http.StatusServiceUnavailable},
+		{path: "/not-found", expectedStatus: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, origin.URL+test.path, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+		assert.Equal(t, test.expectedStatus, responseWriter.Code, test.path)
+		assert.Equal(t, "origin body", responseWriter.Body.String(), test.path)
+	}
+}
+
 type replayer struct {
 	sync.RWMutex
 	writeDone chan struct{}
@@ -713,6 +1334,28 @@ func TestConnections(t *testing.T) {
 	}
 }
 
+func TestProxyTCPPortOverride(t *testing.T) {
+	log := zerolog.Nop()
+	replayer := &replayer{rw: &bytes.Buffer{}}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	runEchoTCPService(t, ln)
+	originPort := uint16(ln.Addr().(*net.TCPAddr).Port)
+
+	warpRouting := testWarpRouting
+	warpRouting.PortOverrides = []ingress.PortOverride{
+		{Network: netip.MustParsePrefix("127.0.0.1/32"), Port: 1, OriginPort: originPort},
+	}
+	proxy := NewOriginProxy(ingress.Ingress{}, warpRouting, testTags, time.Duration(0), &log)
+
+	req, err := http.NewRequest(http.MethodGet, "tcp://127.0.0.1:1", newTCPRequestBody([]byte("test")))
+	require.NoError(t, err)
+	respWriter := newTCPRespWriter(replayer)
+	rwa := connection.NewHTTPResponseReadWriterAcker(respWriter, respWriter, req)
+	require.NoError(t, proxy.ProxyTCP(context.Background(), rwa, &connection.TCPRequest{Dest: "127.0.0.1:1"}))
+	require.Equal(t, []byte("echo-test"), replayer.Bytes())
+}
+
 type requestBody struct {
 	pw *io.PipeWriter
 	pr *io.PipeReader
@@ -980,3 +1623,400 @@ func runEchoWSService(t *testing.T, l net.Listener) {
 		require.NoError(t, err)
 	}()
 }
+
+func TestProxyOriginCertExpiry(t *testing.T) {
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: origin.Client().Transport},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+	require.NoError(t, err)
+	require.NoError(t, proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false))
+
+	var metric dto.Metric
+	require.NoError(t, originCertExpiryDays.WithLabelValues("0").Write(&metric))
+	expectedDays := time.Until(origin.Certificate().NotAfter).Hours() / 24
+	assert.InDelta(t, expectedDays, metric.GetGauge().GetValue(), 1)
+}
+
+func TestProxyRetryOnDisconnect(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		body             io.Reader
+		retries          uint
+		drops            int32
+		expectErr        bool
+		expectedAttempts int32
+	}{
+		{name: "retried", method: http.MethodGet, retries: 2, drops: 2, expectedAttempts: 3},
+		{name: "out of retries", method: http.MethodGet, retries: 1, drops: 2, expectErr: true, expectedAttempts: 2},
+		{name: "disabled", method: http.MethodGet, drops: 1, expectErr: true, expectedAttempts: 1},
+		{name: "not idempotent", method: http.MethodPost, retries: 2, drops: 1, expectErr: true, expectedAttempts: 1},
+		{name: "with body", method: http.MethodPut, body: strings.NewReader("body"), retries: 2, drops: 1, expectErr: true, expectedAttempts: 1},
+		{name: "with empty body", method: http.MethodGet, body: io.NopCloser(strings.NewReader("")), retries: 2, drops: 1, expectedAttempts: 2},
+	}
+
+	log := zerolog.Nop()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) <= test.drops {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					require.NoError(t, err)
+					conn.Close()
+					return
+				}
+				_, _ = w.Write([]byte("origin body"))
+			}))
+			defer origin.Close()
+
+			ing := ingress.Ingress{
+				Rules: []ingress.Rule{
+					{
+						Hostname: "*",
+						Service:  ingress.MockOriginHTTPService{Transport: &http.Transport{DisableKeepAlives: true}},
+						Config:   ingress.OriginRequestConfig{RetryOnDisconnect: test.retries},
+					},
+				},
+			}
+			proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+			req, err := http.NewRequest(test.method, origin.URL, test.body)
+			require.NoError(t, err)
+			responseWriter := newMockHTTPRespWriter()
+			err = proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false)
+			if test.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "origin body", responseWriter.Body.String())
+			}
+			assert.Equal(t, test.expectedAttempts, attempts.Load())
+		})
+	}
+}
+
+func TestProxyLocalHealth(t *testing.T) {
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: errorOriginTransport{}},
+				Config: ingress.OriginRequestConfig{
+					LocalHealthPath:   "/cf-health",
+					LocalHealthStatus: http.StatusAccepted,
+					LocalHealthBody:   "healthy",
+				},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost/cf-health", nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+	assert.Equal(t, http.StatusAccepted, responseWriter.Code)
+	assert.Equal(t, "healthy", responseWriter.Body.String())
+
+	// Other paths go to the origin
+	req, err = http.NewRequest(http.MethodGet, "http://localhost/cf-health/other", nil)
+	require.NoError(t, err)
+	require.Error(t, proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false))
+}
+
+func TestProxyMaintenanceFile(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("origin"))
+	}))
+	defer origin.Close()
+
+	maintenanceFile := filepath.Join(t.TempDir(), "maintenance.html")
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+				Config:   ingress.OriginRequestConfig{MaintenanceFile: maintenanceFile},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+	proxyRequest := func() *mockHTTPRespWriter {
+		req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+		require.NoError(t, err)
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+		return responseWriter
+	}
+
+	responseWriter := proxyRequest()
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+	assert.Equal(t, "origin", responseWriter.Body.String())
+
+	// Creating the file turns maintenance on
+	page := "<html><body>Down for maintenance</body></html>"
+	require.NoError(t, os.WriteFile(maintenanceFile, []byte(page), 0o600))
+	responseWriter = proxyRequest()
+	assert.Equal(t, http.StatusServiceUnavailable, responseWriter.Code)
+	assert.Equal(t, page, responseWriter.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", responseWriter.Header().Get("Content-Type"))
+
+	// Removing it turns maintenance off
+	require.NoError(t, os.Remove(maintenanceFile))
+	responseWriter = proxyRequest()
+	assert.Equal(t, http.StatusOK, responseWriter.Code)
+}
+
+func TestProxySlowRequestThreshold(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+			},
+		},
+	}
+	var logs bytes.Buffer
+	log := zerolog.New(&logs).Level(zerolog.WarnLevel)
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+	proxy.SetSlowRequestThreshold(50 * time.Millisecond)
+
+	for _, path := range []string{"/fast", "/slow"} {
+		req, err := http.NewRequest(http.MethodPost, origin.URL+path, nil)
+		require.NoError(t, err)
+		require.NoError(t, proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false))
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 1, "only the slow request should be logged")
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, http.MethodPost, entry["method"])
+	assert.Equal(t, "/slow", entry["path"])
+	assert.Equal(t, strings.TrimPrefix(origin.URL, "http://"), entry["host"])
+	duration, err := time.ParseDuration(entry[logFieldDuration].(string))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, duration, 100*time.Millisecond)
+}
+
+func TestProxyResponsesByRule(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "responses.example.com",
+				Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+			},
+			{
+				Hostname: "",
+				Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+	count := func(code, rule string) float64 {
+		var metric dto.Metric
+		require.NoError(t, responsesByRule.WithLabelValues(code, rule).Write(&metric))
+		return metric.GetCounter().GetValue()
+	}
+	before404, before200, beforeCatchAll := count("404", "responses.example.com"), count("200", "responses.example.com"), count("200", "*")
+
+	for _, request := range []struct{ host, path string }{
+		{"responses.example.com", "/missing"},
+		{"responses.example.com", "/"},
+		{"responses.example.com", "/"},
+		{"other.example.com", "/"},
+	} {
+		req, err := http.NewRequest(http.MethodGet, origin.URL+request.path, nil)
+		require.NoError(t, err)
+		req.Host = request.host
+		require.NoError(t, proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false))
+	}
+
+	assert.Equal(t, before404+1, count("404", "responses.example.com"))
+	assert.Equal(t, before200+2, count("200", "responses.example.com"))
+	assert.Equal(t, beforeCatchAll+1, count("200", "*"))
+}
+
+func TestProxyRequestID(t *testing.T) {
+	var originRequestIDs []string
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originRequestIDs = append(originRequestIDs, r.Header.Get(requestIDHeader))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+				Config:   ingress.OriginRequestConfig{RequestID: true},
+			},
+		},
+	}
+	var logs bytes.Buffer
+	log := zerolog.New(&logs).Level(zerolog.DebugLevel)
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	for _, requestID := range []string{"", "eyeball-id"} {
+		req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+		require.NoError(t, err)
+		if requestID != "" {
+			req.Header.Set(requestIDHeader, requestID)
+		}
+		require.NoError(t, proxy.ProxyHTTP(newMockHTTPRespWriter(), tracing.NewTracedHTTPRequest(req, 0, &log), false))
+	}
+	require.Len(t, originRequestIDs, 2)
+	assert.NotEmpty(t, originRequestIDs[0])
+	assert.Equal(t, "eyeball-id", originRequestIDs[1])
+
+	loggedRequestIDs := make(map[interface{}]bool)
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		loggedRequestIDs[entry[logFieldRequestID]] = true
+	}
+	assert.Equal(t, map[interface{}]bool{originRequestIDs[0]: true, "eyeball-id": true}, loggedRequestIDs)
+}
+
+func TestProxyRemoveResponseHeaders(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "internal/1.0")
+		w.Header().Set("X-Debug-Backend", "10.0.0.12")
+		w.Header().Set("X-Debug-Query-Time", "12ms")
+		w.Header().Set("X-Debugger", "kept")
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("origin body"))
+	}))
+	defer origin.Close()
+
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+				Config:   ingress.OriginRequestConfig{RemoveResponseHeaders: []string{"server", "x-debug-*"}},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+	assert.Empty(t, responseWriter.Header().Get("Server"))
+	assert.Empty(t, responseWriter.Header().Get("X-Debug-Backend"))
+	assert.Empty(t, responseWriter.Header().Get("X-Debug-Query-Time"))
+	assert.Equal(t, "kept", responseWriter.Header().Get("X-Debugger"))
+	assert.Equal(t, "text/plain", responseWriter.Header().Get("Content-Type"))
+	assert.Equal(t, "origin body", responseWriter.Body.String())
+}
+
+// Redirects of the origin are relayed to the eyeball, cloudflared never follows them, not even to hosts it can reach.
+func TestProxyRelaysRedirects(t *testing.T) {
+	var followed atomic.Bool
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+	}))
+	defer internal.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/admin", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	ing := ingress.Ingress{
+		Rules: []ingress.Rule{
+			{
+				Hostname: "*",
+				Service:  ingress.MockOriginHTTPService{Transport: http.DefaultTransport},
+			},
+		},
+	}
+	log := zerolog.Nop()
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	req, err := http.NewRequest(http.MethodGet, origin.URL, nil)
+	require.NoError(t, err)
+	responseWriter := newMockHTTPRespWriter()
+	require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+	assert.Equal(t, http.StatusFound, responseWriter.Code)
+	assert.Equal(t, internal.URL+"/admin", responseWriter.Header().Get("Location"))
+	assert.False(t, followed.Load())
+}
+
+func TestProxyCanary(t *testing.T) {
+	newOrigin := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name))
+		}))
+	}
+	stable := newOrigin("stable")
+	defer stable.Close()
+	canary := newOrigin("canary")
+	defer canary.Close()
+
+	ing, err := ingress.ParseIngress(&config.Configuration{
+		TunnelID: t.Name(),
+		Ingress: []config.UnvalidatedIngressRule{
+			{
+				Service: stable.URL,
+				Canary:  &config.CanaryConfig{Service: canary.URL, MatchCookie: "beta=1"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, ing.StartOrigins(&log, ctx.Done()))
+	proxy := NewOriginProxy(ing, noWarpRouting, testTags, time.Duration(0), &log)
+
+	for cookie, expectedOrigin := range map[string]string{"": "stable", "beta=0": "stable", "beta=1": "canary"} {
+		req, err := http.NewRequest(http.MethodGet, "http://app.example.com", nil)
+		require.NoError(t, err)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		responseWriter := newMockHTTPRespWriter()
+		require.NoError(t, proxy.ProxyHTTP(responseWriter, tracing.NewTracedHTTPRequest(req, 0, &log), false))
+		assert.Equal(t, expectedOrigin, responseWriter.Body.String(), cookie)
+	}
+}
@@ -0,0 +1,31 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// isRetryable returns whether a request that failed with err can be sent to the origin again without risking that
+// it is processed twice with different outcomes: it must be idempotent, have no body to replay, and the origin must
+// have dropped the connection rather than refused it or timed out. The body of incoming requests is never nil, so an
+// empty one is recognized by its length.
+func isRetryable(req *http.Request, err error) bool {
+	if req.ContentLength != 0 || len(req.TransferEncoding) != 0 {
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, net.ErrClosed)
+}
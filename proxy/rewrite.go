@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// hostRewriter rewrites absolute URLs to the origin into URLs to the hostname requested by the eyeball, for rules
+// with rewriteOriginHost.
+type hostRewriter struct {
+	originHosts []string
+	publicHost  string
+}
+
+// newHostRewriter returns nil if the origin has no host to rewrite, like unix socket origins without httpHostHeader.
+func newHostRewriter(service ingress.HTTPOriginProxy, cfg ingress.OriginRequestConfig, publicHost string) *hostRewriter {
+	var originHosts []string
+	if stringer, ok := service.(fmt.Stringer); ok {
+		if serviceURL, err := url.Parse(stringer.String()); err == nil && serviceURL.Host != "" {
+			originHosts = append(originHosts, serviceURL.Host)
+		}
+	}
+	if cfg.HTTPHostHeader != "" {
+		originHosts = append(originHosts, cfg.HTTPHostHeader)
+	}
+	if len(originHosts) == 0 || publicHost == "" {
+		return nil
+	}
+	return &hostRewriter{originHosts: originHosts, publicHost: publicHost}
+}
+
+func (r *hostRewriter) isOriginHost(host string) bool {
+	for _, originHost := range r.originHosts {
+		if strings.EqualFold(host, originHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteResponse rewrites the Location and Content-Location headers of resp, and its body if it is HTML.
+func (r *hostRewriter) rewriteResponse(resp *http.Response) {
+	for _, header := range []string{"Location", "Content-Location"} {
+		if value := resp.Header.Get(header); value != "" {
+			resp.Header.Set(header, r.rewriteURL(value))
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" || resp.Header.Get("Content-Encoding") != "" || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	var patterns [][]byte
+	for _, originHost := range r.originHosts {
+		patterns = append(patterns, []byte("http://"+originHost), []byte("https://"+originHost))
+	}
+	resp.Body = &replacingBody{
+		source:      resp.Body,
+		patterns:    patterns,
+		replacement: []byte("https://" + r.publicHost),
+	}
+	// The length of the body changes
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+}
+
+// rewriteURL returns rawURL pointing to the public hostname if it is an absolute URL to the origin.
+func (r *hostRewriter) rewriteURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() || !r.isOriginHost(u.Host) {
+		return rawURL
+	}
+	u.Scheme = "https"
+	u.Host = r.publicHost
+	return u.String()
+}
+
+// replacingBody replaces every occurrence of the patterns in source with replacement, as the body is read. Matches
+// that span two reads from source are replaced too.
+type replacingBody struct {
+	source      io.ReadCloser
+	patterns    [][]byte
+	replacement []byte
+
+	// buf is reused for every read from source
+	buf []byte
+	// pending is read from source but not processed yet, because it may be the start of a pattern
+	pending []byte
+	// output is processed and ready to be returned by Read
+	output []byte
+	err    error
+	// matches holds the position in pending of the next match of each pattern while pending is processed
+	matches []int
+}
+
+func (b *replacingBody) Read(p []byte) (int, error) {
+	for len(b.output) == 0 && b.err == nil {
+		if b.buf == nil {
+			b.buf = make([]byte, 32*1024)
+		}
+		n, err := b.source.Read(b.buf)
+		b.pending = append(b.pending, b.buf[:n]...)
+		b.err = err
+		b.process(err != nil)
+	}
+	if len(b.output) > 0 {
+		n := copy(p, b.output)
+		b.output = b.output[n:]
+		return n, nil
+	}
+	return 0, b.err
+}
+
+// process moves what can be replaced from pending to output. Unless final, the end of pending that could be the
+// start of a pattern is kept for the next read. pending is scanned once: the next match of each pattern is only
+// searched again when a replacement went past it.
+func (b *replacingBody) process(final bool) {
+	b.matches = b.matches[:0]
+	for _, pattern := range b.patterns {
+		b.matches = append(b.matches, b.matchFrom(pattern, 0, final))
+	}
+	processed := 0
+	for {
+		first := -1
+		for i, index := range b.matches {
+			if index >= 0 && (first < 0 || index < b.matches[first]) {
+				first = i
+			}
+		}
+		if first < 0 {
+			break
+		}
+		index := b.matches[first]
+		b.output = append(b.output, b.pending[processed:index]...)
+		b.output = append(b.output, b.replacement...)
+		processed = index + len(b.patterns[first])
+		for i, pattern := range b.patterns {
+			if b.matches[i] >= 0 && b.matches[i] < processed {
+				b.matches[i] = b.matchFrom(pattern, processed, final)
+			}
+		}
+	}
+	keep := 0
+	if !final {
+		// As long as the longest pattern, so that a pattern at the end, which is only matched once the byte after it
+		// is known, or the start of one is processed with the next read
+		keep = min(b.longestPattern(), len(b.pending)-processed)
+	}
+	b.output = append(b.output, b.pending[processed:len(b.pending)-keep]...)
+	b.pending = append(b.pending[:0], b.pending[len(b.pending)-keep:]...)
+}
+
+// matchFrom returns the position of the first occurrence of pattern in pending from offset, or -1 if there is none.
+// Occurrences followed by more of a host, like http://origin.internal.example.com for http://origin.internal, don't
+// match. Unless final, an occurrence at the very end of pending is left for when the next byte is known.
+func (b *replacingBody) matchFrom(pattern []byte, offset int, final bool) int {
+	for offset < len(b.pending) {
+		index := bytes.Index(b.pending[offset:], pattern)
+		if index < 0 {
+			return -1
+		}
+		index += offset
+		end := index + len(pattern)
+		if end == len(b.pending) && !final {
+			return -1
+		}
+		if end < len(b.pending) && isHostByte(b.pending[end]) {
+			offset = index + 1
+			continue
+		}
+		return index
+	}
+	return -1
+}
+
+func isHostByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == ':'
+}
+
+func (b *replacingBody) longestPattern() int {
+	longest := 0
+	for _, pattern := range b.patterns {
+		if len(pattern) > longest {
+			longest = len(pattern)
+		}
+	}
+	return longest
+}
+
+func (b *replacingBody) Close() error {
+	return b.source.Close()
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/ingress"
+)
+
+// urlService is an HTTP origin that is only described by its URL.
+type urlService string
+
+func (urlService) RoundTrip(*http.Request) (*http.Response, error) { return nil, nil }
+func (s urlService) String() string                                { return string(s) }
+
+func TestReplacingBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "links",
+			body:     `<a href="http://origin.internal:8080/a">a</a><a href="https://origin.internal:8080/b">b</a>`,
+			expected: `<a href="https://app.example.com/a">a</a><a href="https://app.example.com/b">b</a>`,
+		},
+		{
+			name:     "other hosts",
+			body:     `<a href="http://origin.internal:80801/">a</a><a href="http://other.internal/">b</a>`,
+			expected: `<a href="http://origin.internal:80801/">a</a><a href="http://other.internal/">b</a>`,
+		},
+		{
+			name:     "many links",
+			body:     strings.Repeat(`<a href="https://origin.internal:8080/">a</a>http://origin.internal:8080.example.com `, 1000),
+			expected: strings.Repeat(`<a href="https://app.example.com/">a</a>http://origin.internal:8080.example.com `, 1000),
+		},
+		{
+			name:     "end of body",
+			body:     `see http://origin.internal:8080`,
+			expected: `see https://app.example.com`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Reading one byte at a time splits every pattern across reads
+			for _, source := range []io.Reader{strings.NewReader(test.body), iotest.OneByteReader(strings.NewReader(test.body))} {
+				body := &replacingBody{
+					source:      io.NopCloser(source),
+					patterns:    [][]byte{[]byte("http://origin.internal:8080"), []byte("https://origin.internal:8080")},
+					replacement: []byte("https://app.example.com"),
+				}
+				content, err := io.ReadAll(body)
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, string(content))
+			}
+		})
+	}
+}
+
+func TestHostRewriterRewriteResponse(t *testing.T) {
+	rewriter := newHostRewriter(urlService("http://origin.internal:8080"), ingress.OriginRequestConfig{HTTPHostHeader: "intranet"}, "app.example.com")
+	require.NotNil(t, rewriter)
+
+	newResponse := func(header http.Header, body string) *http.Response {
+		return &http.Response{Header: header, Body: io.NopCloser(strings.NewReader(body)), ContentLength: int64(len(body))}
+	}
+
+	resp := newResponse(http.Header{
+		"Location":         {"http://origin.internal:8080/login?next=%2F"},
+		"Content-Location": {"https://intranet/page"},
+		"Content-Type":     {"text/html; charset=utf-8"},
+		"Content-Length":   {"27"},
+	}, `<a href="http://intranet/">`)
+	rewriter.rewriteResponse(resp)
+	assert.Equal(t, "https://app.example.com/login?next=%2F", resp.Header.Get("Location"))
+	assert.Equal(t, "https://app.example.com/page", resp.Header.Get("Content-Location"))
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+	assert.Equal(t, int64(-1), resp.ContentLength)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `<a href="https://app.example.com/">`, string(body))
+
+	// Relative URLs, other hosts and bodies that aren't uncompressed HTML are left as is
+	for _, header := range []http.Header{
+		{"Location": {"/login"}, "Content-Type": {"application/json"}},
+		{"Location": {"https://other.example.com/"}, "Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}},
+	} {
+		expectedLocation := header.Get("Location")
+		resp := newResponse(header, `"http://intranet/"`)
+		rewriter.rewriteResponse(resp)
+		assert.Equal(t, expectedLocation, resp.Header.Get("Location"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `"http://intranet/"`, string(body))
+	}
+
+	// Unix socket origins have no host
+	unixURL := &url.URL{Scheme: "unix", Path: "/run/app.sock"}
+	assert.Nil(t, newHostRewriter(urlService(unixURL.String()), ingress.OriginRequestConfig{}, "app.example.com"))
+}
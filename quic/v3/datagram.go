@@ -27,6 +27,15 @@ const (
 	maxDatagramPayloadLen = 1280
 )
 
+const (
+	// MaxDatagramPayloadLen is the largest origin UDP payload that can be proxied to the edge, which is also the
+	// default limit of a [SessionManager].
+	MaxDatagramPayloadLen = maxDatagramPayloadLen
+	// MinDatagramPayloadLen is the smallest limit that can be configured for the origin UDP payloads. It is the
+	// largest UDP payload that every IPv4 path has to deliver without fragmentation.
+	MinDatagramPayloadLen = 508
+)
+
 func ParseDatagramType(data []byte) (DatagramType, error) {
 	if len(data) < datagramTypeLen {
 		return 0, ErrDatagramHeaderTooSmall
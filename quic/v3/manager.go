@@ -35,19 +35,27 @@ type SessionManager interface {
 type DialUDP func(dest netip.AddrPort) (*net.UDPConn, error)
 
 type sessionManager struct {
-	sessions     map[RequestID]Session
-	mutex        sync.RWMutex
-	originDialer DialUDP
-	metrics      Metrics
-	log          *zerolog.Logger
+	sessions      map[RequestID]Session
+	mutex         sync.RWMutex
+	originDialer  DialUDP
+	maxPayloadLen int
+	metrics       Metrics
+	log           *zerolog.Logger
 }
 
-func NewSessionManager(metrics Metrics, log *zerolog.Logger, originDialer DialUDP) SessionManager {
+// NewSessionManager creates a SessionManager whose sessions proxy origin UDP payloads of up to maxPayloadLen bytes
+// to the edge. Larger payloads are dropped and counted as [DropReasonPayloadTooLarge]. A maxPayloadLen of 0 uses
+// [MaxDatagramPayloadLen]; other values must be between [MinDatagramPayloadLen] and [MaxDatagramPayloadLen].
+func NewSessionManager(metrics Metrics, log *zerolog.Logger, originDialer DialUDP, maxPayloadLen int) SessionManager {
+	if maxPayloadLen <= 0 || maxPayloadLen > maxDatagramPayloadLen {
+		maxPayloadLen = maxDatagramPayloadLen
+	}
 	return &sessionManager{
-		sessions:     make(map[RequestID]Session),
-		originDialer: originDialer,
-		metrics:      metrics,
-		log:          log,
+		sessions:      make(map[RequestID]Session),
+		originDialer:  originDialer,
+		maxPayloadLen: maxPayloadLen,
+		metrics:       metrics,
+		log:           log,
 	}
 }
 
@@ -67,13 +75,14 @@ func (s *sessionManager) RegisterSession(request *UDPSessionRegistrationDatagram
 		return nil, err
 	}
 	// Create and insert the new session in the map
-	session := NewSession(
+	session := newSession(
 		request.RequestID,
 		request.IdleDurationHint,
 		origin,
 		origin.RemoteAddr(),
 		origin.LocalAddr(),
 		conn,
+		s.maxPayloadLen,
 		s.metrics,
 		s.log)
 	s.sessions[request.RequestID] = session
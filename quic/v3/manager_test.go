@@ -1,7 +1,9 @@
 package v3_test
 
 import (
+	"context"
 	"errors"
+	"net"
 	"net/netip"
 	"strings"
 	"testing"
@@ -15,7 +17,7 @@ import (
 
 func TestRegisterSession(t *testing.T) {
 	log := zerolog.Nop()
-	manager := v3.NewSessionManager(&noopMetrics{}, &log, ingress.DialUDPAddrPort)
+	manager := v3.NewSessionManager(&noopMetrics{}, &log, ingress.DialUDPAddrPort, 0)
 
 	request := v3.UDPSessionRegistrationDatagram{
 		RequestID:        testRequestID,
@@ -71,10 +73,73 @@ func TestRegisterSession(t *testing.T) {
 
 func TestGetSession_Empty(t *testing.T) {
 	log := zerolog.Nop()
-	manager := v3.NewSessionManager(&noopMetrics{}, &log, ingress.DialUDPAddrPort)
+	manager := v3.NewSessionManager(&noopMetrics{}, &log, ingress.DialUDPAddrPort, 0)
 
 	_, err := manager.GetSession(testRequestID)
 	if !errors.Is(err, v3.ErrSessionNotFound) {
 		t.Fatalf("get session find no session: %v", err)
 	}
 }
+
+func TestRegisterSession_MaxPayloadLen(t *testing.T) {
+	log := zerolog.Nop()
+	metrics := newDropCountingMetrics()
+	manager := v3.NewSessionManager(metrics, &log, ingress.DialUDPAddrPort, 600)
+
+	origin, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.MustParseAddrPort("127.0.0.1:0")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer origin.Close()
+
+	request := v3.UDPSessionRegistrationDatagram{
+		RequestID:        testRequestID,
+		Dest:             origin.LocalAddr().(*net.UDPAddr).AddrPort(),
+		IdleDurationHint: 5 * time.Second,
+	}
+	eyeball := newMockEyeball()
+	session, err := manager.RegisterSession(&request, &eyeball)
+	if err != nil {
+		t.Fatalf("register session should've succeeded: %v", err)
+	}
+	defer manager.UnregisterSession(request.RequestID)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = session.Serve(ctx)
+	}()
+
+	// Let the origin learn the address of the flow
+	if _, err := session.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	_, flowAddr, err := origin.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := origin.WriteToUDP(makePayload(601), flowAddr); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reason := <-metrics.dropped:
+		if reason != v3.DropReasonPayloadTooLarge {
+			t.Fatalf("unexpected drop reason: %s", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("payload over the limit should have been dropped")
+	}
+
+	if _, err := origin.WriteToUDP(makePayload(600), flowAddr); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-eyeball.recvData:
+		if len(data) != v3.DatagramPayloadHeaderLen+600 {
+			t.Fatalf("unexpected datagram size: %d", len(data))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("payload within the limit should have been proxied")
+	}
+}
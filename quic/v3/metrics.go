@@ -9,12 +9,29 @@ const (
 	subsystem = "udp"
 )
 
+// DropReason describes why a datagram was dropped instead of being proxied.
+type DropReason string
+
+const (
+	// DropReasonMalformed is used for datagrams from the edge that could not be parsed.
+	DropReasonMalformed DropReason = "malformed"
+	// DropReasonFlowNotFound is used for payloads from the edge for a flow that is not registered.
+	DropReasonFlowNotFound DropReason = "flow_not_found"
+	// DropReasonOriginWriteFailed is used for payloads from the edge that could not be written to the origin.
+	DropReasonOriginWriteFailed DropReason = "origin_write_failed"
+	// DropReasonPayloadTooLarge is used for origin payloads that don't fit in a datagram.
+	DropReasonPayloadTooLarge DropReason = "payload_too_large"
+	// DropReasonICMPDisabled is used for ICMP packets received while ICMP proxying is disabled.
+	DropReasonICMPDisabled DropReason = "icmp_disabled"
+)
+
 type Metrics interface {
 	IncrementFlows()
 	DecrementFlows()
 	PayloadTooLarge()
 	RetryFlowResponse()
 	MigrateFlow()
+	DroppedDatagram(reason DropReason)
 }
 
 type metrics struct {
@@ -23,6 +40,7 @@ type metrics struct {
 	payloadTooLarge    prometheus.Counter
 	retryFlowResponses prometheus.Counter
 	migratedFlows      prometheus.Counter
+	droppedDatagrams   *prometheus.CounterVec
 }
 
 func (m *metrics) IncrementFlows() {
@@ -46,6 +64,10 @@ func (m *metrics) MigrateFlow() {
 	m.migratedFlows.Inc()
 }
 
+func (m *metrics) DroppedDatagram(reason DropReason) {
+	m.droppedDatagrams.WithLabelValues(string(reason)).Inc()
+}
+
 func NewMetrics(registerer prometheus.Registerer) Metrics {
 	m := &metrics{
 		activeUDPFlows: prometheus.NewGauge(prometheus.GaugeOpts{
@@ -78,6 +100,12 @@ func NewMetrics(registerer prometheus.Registerer) Metrics {
 			Name:      "migrated_flows",
 			Help:      "Total count of UDP flows have been migrated across local connections",
 		}),
+		droppedDatagrams: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dropped_datagrams_total",
+			Help:      "Total count of datagrams that were dropped instead of being proxied, by reason",
+		}, []string{"reason"}),
 	}
 	registerer.MustRegister(
 		m.activeUDPFlows,
@@ -85,6 +113,7 @@ func NewMetrics(registerer prometheus.Registerer) Metrics {
 		m.payloadTooLarge,
 		m.retryFlowResponses,
 		m.migratedFlows,
+		m.droppedDatagrams,
 	)
 	return m
 }
@@ -1,9 +1,26 @@
 package v3_test
 
+import v3 "github.com/cloudflare/cloudflared/quic/v3"
+
 type noopMetrics struct{}
 
-func (noopMetrics) IncrementFlows()    {}
-func (noopMetrics) DecrementFlows()    {}
-func (noopMetrics) PayloadTooLarge()   {}
-func (noopMetrics) RetryFlowResponse() {}
-func (noopMetrics) MigrateFlow()       {}
+func (noopMetrics) IncrementFlows()                 {}
+func (noopMetrics) DecrementFlows()                 {}
+func (noopMetrics) PayloadTooLarge()                {}
+func (noopMetrics) RetryFlowResponse()              {}
+func (noopMetrics) MigrateFlow()                    {}
+func (noopMetrics) DroppedDatagram(_ v3.DropReason) {}
+
+// dropCountingMetrics records the reasons of dropped datagrams.
+type dropCountingMetrics struct {
+	noopMetrics
+	dropped chan v3.DropReason
+}
+
+func newDropCountingMetrics() *dropCountingMetrics {
+	return &dropCountingMetrics{dropped: make(chan v3.DropReason, 16)}
+}
+
+func (m *dropCountingMetrics) DroppedDatagram(reason v3.DropReason) {
+	m.dropped <- reason
+}
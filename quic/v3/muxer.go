@@ -193,6 +193,7 @@ func (c *datagramConn) Serve(ctx context.Context) error {
 			typ, err := ParseDatagramType(datagram)
 			if err != nil {
 				c.logger.Err(err).Msgf("unable to parse datagram type: %d", typ)
+				c.metrics.DroppedDatagram(DropReasonMalformed)
 				return
 			}
 			switch typ {
@@ -201,6 +202,7 @@ func (c *datagramConn) Serve(ctx context.Context) error {
 				err := reg.UnmarshalBinary(datagram)
 				if err != nil {
 					c.logger.Err(err).Msgf("unable to unmarshal session registration datagram")
+					c.metrics.DroppedDatagram(DropReasonMalformed)
 					return
 				}
 				logger := c.logger.With().Str(logFlowID, reg.RequestID.String()).Logger()
@@ -214,6 +216,7 @@ func (c *datagramConn) Serve(ctx context.Context) error {
 				err := payload.UnmarshalBinary(datagram)
 				if err != nil {
 					c.logger.Err(err).Msgf("unable to unmarshal session payload datagram")
+					c.metrics.DroppedDatagram(DropReasonMalformed)
 					return
 				}
 				logger := c.logger.With().Str(logFlowID, payload.RequestID.String()).Logger()
@@ -223,6 +226,7 @@ func (c *datagramConn) Serve(ctx context.Context) error {
 				err := packet.UnmarshalBinary(datagram)
 				if err != nil {
 					c.logger.Err(err).Msgf("unable to unmarshal icmp datagram")
+					c.metrics.DroppedDatagram(DropReasonMalformed)
 					return
 				}
 				c.handleICMPPacket(packet)
@@ -351,12 +355,14 @@ func (c *datagramConn) handleSessionPayloadDatagram(datagram *UDPSessionPayloadD
 	s, err := c.sessionManager.GetSession(datagram.RequestID)
 	if err != nil {
 		logger.Err(err).Msgf("unable to find flow")
+		c.metrics.DroppedDatagram(DropReasonFlowNotFound)
 		return
 	}
 	// We ignore the bytes written to the socket because any partial write must return an error.
 	_, err = s.Write(datagram.Payload)
 	if err != nil {
 		logger.Err(err).Msgf("unable to write payload for the flow")
+		c.metrics.DroppedDatagram(DropReasonOriginWriteFailed)
 		return
 	}
 }
@@ -365,6 +371,7 @@ func (c *datagramConn) handleSessionPayloadDatagram(datagram *UDPSessionPayloadD
 func (c *datagramConn) handleICMPPacket(datagram *ICMPDatagram) {
 	if c.icmpRouter == nil {
 		// ICMPRouter is disabled so we drop the current packet and ignore all incoming ICMP packets
+		c.metrics.DroppedDatagram(DropReasonICMPDisabled)
 		return
 	}
 
@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/quic-go/quic-go"
 	"github.com/rs/zerolog"
 )
 
@@ -18,8 +19,8 @@ const (
 	defaultCloseIdleAfter = 210 * time.Second
 
 	// The maximum payload from the origin that we will be able to read. However, even though we will
-	// read 1500 bytes from the origin, we limit the amount of bytes to be proxied to less than
-	// this value (maxDatagramPayloadLen).
+	// read 1500 bytes from the origin, we limit the amount of bytes to be proxied to the limit
+	// configured in the session manager, which is at most maxDatagramPayloadLen.
 	maxOriginUDPPacketSize = 1500
 
 	logFlowID        = "flowID"
@@ -67,6 +68,7 @@ type session struct {
 	originAddr     net.Addr
 	localAddr      net.Addr
 	eyeball        atomic.Pointer[DatagramConn]
+	maxPayloadLen  int
 	// activeAtChan is used to communicate the last read/write time
 	activeAtChan chan time.Time
 	closeChan    chan error
@@ -87,6 +89,20 @@ func NewSession(
 	eyeball DatagramConn,
 	metrics Metrics,
 	log *zerolog.Logger,
+) Session {
+	return newSession(id, closeAfterIdle, origin, originAddr, localAddr, eyeball, maxDatagramPayloadLen, metrics, log)
+}
+
+func newSession(
+	id RequestID,
+	closeAfterIdle time.Duration,
+	origin io.ReadWriteCloser,
+	originAddr net.Addr,
+	localAddr net.Addr,
+	eyeball DatagramConn,
+	maxPayloadLen int,
+	metrics Metrics,
+	log *zerolog.Logger,
 ) Session {
 	logger := log.With().Str(logFlowID, id.String()).Logger()
 	// closeChan has two slots to allow for both writers (the closeFn and the Serve routine) to both be able to
@@ -100,6 +116,7 @@ func NewSession(
 		originAddr:     originAddr,
 		localAddr:      localAddr,
 		eyeball:        atomic.Pointer[DatagramConn]{},
+		maxPayloadLen:  maxPayloadLen,
 		// activeAtChan has low capacity. It can be full when there are many concurrent read/write. markActive() will
 		// drop instead of blocking because last active time only needs to be an approximation
 		activeAtChan: make(chan time.Time, 1),
@@ -176,8 +193,9 @@ func (s *session) Serve(ctx context.Context) error {
 				s.log.Warn().Int(logPacketSizeKey, n).Msg("flow (origin) packet read was negative and was dropped")
 				continue
 			}
-			if n > maxDatagramPayloadLen {
+			if n > s.maxPayloadLen {
 				s.metrics.PayloadTooLarge()
+				s.metrics.DroppedDatagram(DropReasonPayloadTooLarge)
 				s.log.Error().Int(logPacketSizeKey, n).Msg("flow (origin) packet read was too large and was dropped")
 				continue
 			}
@@ -187,6 +205,14 @@ func (s *session) Serve(ctx context.Context) error {
 			// Sending a packet to the session does block on the [quic.Connection], however, this is okay because it
 			// will cause back-pressure to the kernel buffer if the writes are not fast enough to the edge.
 			err = eyeball.SendUDPSessionDatagram(readBuffer[:DatagramPayloadHeaderLen+n])
+			if errors.Is(err, &quic.DatagramTooLargeError{}) {
+				// The QUIC packets to the edge are too small for this payload, e.g. because path MTU discovery is
+				// disabled. Dropping it keeps the flow open for the payloads that fit.
+				s.metrics.PayloadTooLarge()
+				s.metrics.DroppedDatagram(DropReasonPayloadTooLarge)
+				s.log.Error().Err(err).Int(logPacketSizeKey, n).Msg("flow (origin) packet doesn't fit in a QUIC datagram and was dropped")
+				continue
+			}
 			if err != nil {
 				s.closeChan <- err
 				return
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/fortytw2/leaktest"
+	"github.com/quic-go/quic-go"
 	"github.com/rs/zerolog"
 
 	v3 "github.com/cloudflare/cloudflared/quic/v3"
@@ -392,3 +393,65 @@ func (o *testErrOrigin) Write(p []byte) (n int, err error) {
 func (o *testErrOrigin) Close() error {
 	return nil
 }
+
+// smallDatagramEyeball rejects the datagrams that are larger than maxDatagramLen like a QUIC connection does when its
+// packets are too small for them.
+type smallDatagramEyeball struct {
+	mockEyeball
+	maxDatagramLen int
+}
+
+func (e *smallDatagramEyeball) SendUDPSessionDatagram(datagram []byte) error {
+	if len(datagram) > e.maxDatagramLen {
+		return &quic.DatagramTooLargeError{MaxDatagramPayloadSize: int64(e.maxDatagramLen)}
+	}
+	return e.mockEyeball.SendUDPSessionDatagram(datagram)
+}
+
+func TestSessionServe_DatagramTooLargeForQUIC(t *testing.T) {
+	defer leaktest.Check(t)()
+	log := zerolog.Nop()
+	eyeball := &smallDatagramEyeball{mockEyeball: newMockEyeball(), maxDatagramLen: v3.DatagramPayloadHeaderLen + 1000}
+	metrics := newDropCountingMetrics()
+	origin, server := net.Pipe()
+	defer origin.Close()
+	defer server.Close()
+	session := v3.NewSession(testRequestID, 2*time.Second, origin, testOriginAddr, testLocalAddr, eyeball, metrics, &log)
+	defer session.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- session.Serve(ctx)
+	}()
+
+	// The payload is accepted by the session but doesn't fit in a QUIC datagram, so it's dropped without closing the flow
+	if _, err := server.Write(makePayload(1200)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reason := <-metrics.dropped:
+		if reason != v3.DropReasonPayloadTooLarge {
+			t.Fatalf("unexpected drop reason: %s", reason)
+		}
+	case err := <-done:
+		t.Fatalf("flow should still be open: %v", err)
+	}
+
+	if _, err := server.Write(makePayload(100)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-eyeball.recvData:
+		if len(data) != v3.DatagramPayloadHeaderLen+100 {
+			t.Fatalf("unexpected datagram size: %d", len(data))
+		}
+	case err := <-done:
+		t.Fatalf("flow should still be open: %v", err)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+}
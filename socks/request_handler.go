@@ -9,6 +9,7 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/cloudflare/cloudflared/ipaccess"
+	"github.com/cloudflare/cloudflared/logger"
 )
 
 // RequestHandler is the functions needed to handle a SOCKS5 command
@@ -61,6 +62,13 @@ func (h *StandardRequestHandler) handleConnect(conn io.ReadWriter, req *Request)
 			req.DestAddr.IP = addr.IP
 		}
 		if allowed, rule := h.accessPolicy.Allowed(req.DestAddr.IP, req.DestAddr.Port); !allowed {
+			auditEvent := logger.AuditEvent(logger.AuditEventAccessDenied).
+				Str("handler", "socks").
+				Str("dest", req.DestAddr.Address())
+			if rule != nil {
+				auditEvent = auditEvent.Str("ipRule", rule.String())
+			}
+			auditEvent.Msg("Connection denied")
 			_ = sendReply(conn, ruleFailure, req.DestAddr)
 			if rule != nil {
 				return fmt.Errorf("Connect to %v denied due to iprule: %s", req.DestAddr, rule.String())
@@ -141,8 +149,7 @@ func StreamHandler(tunnelConn io.ReadWriter, originConn net.Conn, log *zerolog.L
 	}
 }
 
-func StreamNetHandler(tunnelConn io.ReadWriter, accessPolicy *ipaccess.Policy, log *zerolog.Logger) {
-	dialer := NewNetDialer()
+func StreamNetHandler(tunnelConn io.ReadWriter, dialer Dialer, accessPolicy *ipaccess.Policy, log *zerolog.Logger) {
 	requestHandler := NewRequestHandler(dialer, accessPolicy)
 	socksServer := NewConnectionHandler(requestHandler)
 
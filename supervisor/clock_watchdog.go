@@ -0,0 +1,81 @@
+package supervisor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// clockWatchdogInterval is how often the clock is checked for jumps.
+	clockWatchdogInterval = 5 * time.Second
+	// clockJumpThreshold is how much later than expected a check may happen before the host is considered to have
+	// been suspended.
+	clockJumpThreshold = 30 * time.Second
+)
+
+// clockJumps tells the connections that are up when the clock jumps to reconnect. Each jump closes the channel the
+// connections that were up at that time wait on, so every one of them reconnects exactly once and the connections
+// established afterwards aren't affected.
+type clockJumps struct {
+	lock   sync.Mutex
+	jumped chan struct{}
+}
+
+func newClockJumps() *clockJumps {
+	return &clockJumps{jumped: make(chan struct{})}
+}
+
+// wait returns a channel that is closed at the next clock jump. A nil clockJumps never jumps.
+func (c *clockJumps) wait() <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.jumped
+}
+
+func (c *clockJumps) notify() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	close(c.jumped)
+	c.jumped = make(chan struct{})
+}
+
+// watchClockJumps asks all the connections to reconnect when the host resumes from sleep or suspend. Their edge
+// connections are most likely dead by then, and waiting for the keepalives to time out leaves the tunnel down for
+// longer than needed.
+func watchClockJumps(ctx context.Context, jumps *clockJumps, log *zerolog.Logger) {
+	ticker := time.NewTicker(clockWatchdogInterval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			jump := clockJump(last, now, clockWatchdogInterval)
+			last = now
+			if jump < clockJumpThreshold {
+				continue
+			}
+			log.Warn().Msgf("The clock jumped by %s, the host was probably suspended. Reconnecting to Cloudflare's edge",
+				jump.Round(time.Second))
+			jumps.notify()
+		}
+	}
+}
+
+// clockJump returns how much later than interval now came after last. The monotonic clock stops during suspend on
+// some platforms while it keeps going on others, so the largest of the monotonic and the wall clock elapsed times is
+// used.
+func clockJump(last, now time.Time, interval time.Duration) time.Duration {
+	elapsed := now.Sub(last)
+	if wallElapsed := now.Round(0).Sub(last.Round(0)); wallElapsed > elapsed {
+		elapsed = wallElapsed
+	}
+	return elapsed - interval
+}
@@ -0,0 +1,51 @@
+package supervisor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockJump(t *testing.T) {
+	last := time.Now()
+	assert.Equal(t, time.Duration(0), clockJump(last, last.Add(5*time.Second), 5*time.Second))
+	assert.Equal(t, time.Minute, clockJump(last, last.Add(time.Minute+5*time.Second), 5*time.Second))
+
+	// Without monotonic readings, as when the monotonic clock stopped during suspend, the wall clock is used
+	wallLast := last.Round(0)
+	assert.Equal(t, time.Hour, clockJump(wallLast, wallLast.Add(time.Hour+5*time.Second), 5*time.Second))
+}
+
+func TestWatchClockJumpsStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	log := zerolog.Nop()
+	done := make(chan struct{})
+	go func() {
+		watchClockJumps(ctx, newClockJumps(), &log)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchClockJumps didn't return once the context was cancelled")
+	}
+}
+
+func TestClockJumpsReconnectOnce(t *testing.T) {
+	jumps := newClockJumps()
+	upBefore := jumps.wait()
+	jumps.notify()
+
+	// The connection that was up reconnects
+	err := listenReconnect(context.Background(), nil, upBefore, nil)
+	assert.Equal(t, ReconnectSignal{}, err)
+
+	// The connection established after the jump stays up
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, listenReconnect(ctx, nil, jumps.wait(), nil))
+}
@@ -18,10 +18,31 @@ var (
 			Help:      "Number of active ha connections",
 		},
 	)
+
+	fallbackProtocol = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: connection.TunnelSubsystem,
+			Name:      "fallback_protocol",
+			Help:      "Whether the connection connected with the fallback protocol (1) or the preferred one (0)",
+		},
+		[]string{"conn_index"},
+	)
+
+	protocolFallbacks = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: connection.MetricsNamespace,
+			Subsystem: connection.TunnelSubsystem,
+			Name:      "protocol_fallbacks_total",
+			Help:      "Number of times a connection switched to the fallback protocol",
+		},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(
 		haConnections,
+		fallbackProtocol,
+		protocolFallbacks,
 	)
 }
@@ -3,6 +3,7 @@ package supervisor
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"strings"
 	"time"
@@ -23,10 +24,7 @@ import (
 
 const (
 	// Waiting time before retrying a failed tunnel connection
-	tunnelRetryDuration = time.Second * 10
-	// Interval between registering new tunnels
-	registrationInterval = time.Second
-
+	tunnelRetryDuration  = time.Second * 10
 	subsystemRefreshAuth = "refresh_auth"
 	// Maximum exponent for 'Authenticate' exponential backoff
 	refreshAuthMaxBackoff = 10
@@ -53,6 +51,7 @@ type Supervisor struct {
 	logTransport *zerolog.Logger
 
 	reconnectCh       chan ReconnectSignal
+	clockJumps        *clockJumps
 	gracefulShutdownC <-chan struct{}
 }
 
@@ -84,8 +83,9 @@ func NewSupervisor(config *TunnelConfig, orchestrator *orchestration.Orchestrato
 	edgeBindAddr := config.EdgeBindAddr
 
 	datagramMetrics := v3.NewMetrics(prometheus.DefaultRegisterer)
-	sessionManager := v3.NewSessionManager(datagramMetrics, config.Log, ingress.DialUDPAddrPort)
+	sessionManager := v3.NewSessionManager(datagramMetrics, config.Log, ingress.DialUDPAddrPort, config.QUICMaxUDPPayloadSize)
 
+	clockJumps := newClockJumps()
 	edgeTunnelServer := EdgeTunnelServer{
 		config:            config,
 		orchestrator:      orchestrator,
@@ -96,6 +96,7 @@ func NewSupervisor(config *TunnelConfig, orchestrator *orchestration.Orchestrato
 		edgeBindAddr:      edgeBindAddr,
 		tracker:           tracker,
 		reconnectCh:       reconnectCh,
+		clockJumps:        clockJumps,
 		gracefulShutdownC: gracefulShutdownC,
 		connAwareLogger:   log,
 	}
@@ -111,6 +112,7 @@ func NewSupervisor(config *TunnelConfig, orchestrator *orchestration.Orchestrato
 		log:                     log,
 		logTransport:            config.LogTransport,
 		reconnectCh:             reconnectCh,
+		clockJumps:              clockJumps,
 		gracefulShutdownC:       gracefulShutdownC,
 	}, nil
 }
@@ -137,6 +139,7 @@ func (s *Supervisor) Run(
 		}
 		return err
 	}
+	go watchClockJumps(ctx, s.clockJumps, s.log.Logger())
 	var tunnelsWaiting []int
 	tunnelsActive := s.config.HAConnections
 
@@ -242,7 +245,12 @@ func (s *Supervisor) initialize(
 			false,
 		}
 		go s.startTunnel(ctx, i, s.newConnectedTunnelSignal(i))
-		time.Sleep(registrationInterval)
+		// Stagger the connections so that they don't all compete for the link at the same time
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.config.HAConnectionsInterval):
+		}
 	}
 	return nil
 }
@@ -263,7 +271,7 @@ func (s *Supervisor) startFirstTunnel(
 	}()
 
 	// If the first tunnel disconnects, keep restarting it.
-	for {
+	for attempts := uint(1); ; attempts++ {
 		err = s.edgeTunnelServer.Serve(ctx, firstConnIndex, s.tunnelsProtocolFallback[firstConnIndex], connectedSignal)
 		if ctx.Err() != nil {
 			return
@@ -271,6 +279,10 @@ func (s *Supervisor) startFirstTunnel(
 		if err == nil {
 			return
 		}
+		if s.initialConnectRetriesExhausted(attempts, connectedSignal) {
+			err = fmt.Errorf("giving up after %d initial connection attempts: %w", attempts, err)
+			return
+		}
 		// Make sure we don't continue if there is no more fallback allowed
 		if _, retry := s.tunnelsProtocolFallback[firstConnIndex].GetMaxBackoffDuration(ctx); !retry {
 			return
@@ -299,6 +311,20 @@ func (s *Supervisor) startFirstTunnel(
 	}
 }
 
+// initialConnectRetriesExhausted returns true if the first tunnel has used up its initial retries without ever
+// registering.
+func (s *Supervisor) initialConnectRetriesExhausted(attempts uint, connectedSignal *signal.Signal) bool {
+	if s.config.InitialConnectRetries == 0 {
+		return false
+	}
+	select {
+	case <-connectedSignal.Wait():
+		return false
+	default:
+	}
+	return attempts > s.config.InitialConnectRetries
+}
+
 // startTunnel starts a new tunnel connection. The resulting error will be sent on
 // s.tunnelError as this is expected to run in a goroutine.
 func (s *Supervisor) startTunnel(
@@ -0,0 +1,164 @@
+package supervisor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudflare/cloudflared/connection"
+	"github.com/cloudflare/cloudflared/edgediscovery"
+	"github.com/cloudflare/cloudflared/signal"
+	"github.com/cloudflare/cloudflared/tunnelstate"
+)
+
+// requiredProtocolTunnelServer fails every connection as if QUIC was blocked with --require-protocol set.
+type requiredProtocolTunnelServer struct {
+	calls int
+}
+
+func (s *requiredProtocolTunnelServer) Serve(context.Context, uint8, *protocolFallback, *signal.Signal) error {
+	s.calls++
+	return requiredProtocolError{protocol: connection.QUIC, cause: &quic.IdleTimeoutError{}}
+}
+
+// unreachableEdgeTunnelServer fails every connection as if the edge couldn't be dialed. With connectAt set, that
+// attempt registers before failing, and the connection is closed cleanly after closeAt attempts.
+type unreachableEdgeTunnelServer struct {
+	calls     uint
+	connectAt uint
+	closeAt   uint
+}
+
+func (s *unreachableEdgeTunnelServer) Serve(_ context.Context, _ uint8, _ *protocolFallback, connectedSignal *signal.Signal) error {
+	s.calls++
+	if s.calls == s.closeAt {
+		return nil
+	}
+	if s.calls == s.connectAt {
+		connectedSignal.Notify()
+	}
+	return &connection.EdgeQuicDialError{Cause: &quic.IdleTimeoutError{}}
+}
+
+// connectingTunnelServer registers every connection and records when it was started, until the context is done.
+type connectingTunnelServer struct {
+	mu        sync.Mutex
+	startedAt map[uint8]time.Time
+}
+
+func (s *connectingTunnelServer) Serve(ctx context.Context, connIndex uint8, _ *protocolFallback, connectedSignal *signal.Signal) error {
+	s.mu.Lock()
+	s.startedAt[connIndex] = time.Now()
+	s.mu.Unlock()
+	connectedSignal.Notify()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *connectingTunnelServer) started() map[uint8]time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	started := make(map[uint8]time.Time, len(s.startedAt))
+	for index, at := range s.startedAt {
+		started[index] = at
+	}
+	return started
+}
+
+func newTestSupervisor(t *testing.T, config *TunnelConfig, tunnelServer TunnelServer) *Supervisor {
+	return newTestSupervisorWithEdge(t, config, tunnelServer, []string{"127.0.0.1:7844"})
+}
+
+func newTestSupervisorWithEdge(t *testing.T, config *TunnelConfig, tunnelServer TunnelServer, edgeAddrs []string) *Supervisor {
+	log := zerolog.Nop()
+	edgeIPs, err := edgediscovery.StaticEdge(&log, edgeAddrs)
+	require.NoError(t, err)
+	mockFetcher := dynamicMockFetcher{}
+	selector, err := connection.NewProtocolSelector(connection.QUIC.String(), "", false, false, mockFetcher.fetch(), 0, &log)
+	require.NoError(t, err)
+
+	config.ProtocolSelector = selector
+	config.Log = &log
+	return &Supervisor{
+		config:                  config,
+		edgeIPs:                 edgeIPs,
+		edgeTunnelServer:        tunnelServer,
+		tunnelErrors:            make(chan tunnelError),
+		tunnelsConnecting:       map[int]chan struct{}{},
+		tunnelsProtocolFallback: map[int]*protocolFallback{},
+		log:                     NewConnAwareLogger(&log, tunnelstate.NewConnTracker(&log), connection.NewObserver(&log, &log)),
+	}
+}
+
+func TestSupervisorExitsWhenRequiredProtocolUnavailable(t *testing.T) {
+	tunnelServer := &requiredProtocolTunnelServer{}
+	s := newTestSupervisor(t, &TunnelConfig{
+		HAConnections:   4,
+		Retries:         5,
+		RequireProtocol: true,
+	}, tunnelServer)
+
+	err := s.Run(context.Background(), signal.New(make(chan struct{})))
+	var requiredErr requiredProtocolError
+	require.ErrorAs(t, err, &requiredErr)
+	require.Equal(t, 1, tunnelServer.calls)
+}
+
+func TestSupervisorInitialConnectRetries(t *testing.T) {
+	tunnelServer := &unreachableEdgeTunnelServer{}
+	s := newTestSupervisor(t, &TunnelConfig{
+		HAConnections:         1,
+		Retries:               5,
+		InitialConnectRetries: 2,
+	}, tunnelServer)
+
+	err := s.Run(context.Background(), signal.New(make(chan struct{})))
+	var dialErr *connection.EdgeQuicDialError
+	require.ErrorAs(t, err, &dialErr)
+	require.Equal(t, uint(3), tunnelServer.calls)
+}
+
+func TestSupervisorInitialConnectRetriesAfterRegistration(t *testing.T) {
+	// Once the connection has registered, it is retried past the initial retries
+	tunnelServer := &unreachableEdgeTunnelServer{connectAt: 1, closeAt: 4}
+	s := newTestSupervisor(t, &TunnelConfig{
+		HAConnections:         1,
+		Retries:               5,
+		InitialConnectRetries: 1,
+	}, tunnelServer)
+
+	err := s.Run(context.Background(), signal.New(make(chan struct{})))
+	require.NoError(t, err)
+	require.Equal(t, uint(4), tunnelServer.calls)
+}
+
+func TestSupervisorHAConnectionsInterval(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	tunnelServer := &connectingTunnelServer{startedAt: map[uint8]time.Time{}}
+	s := newTestSupervisorWithEdge(t, &TunnelConfig{
+		HAConnections:         3,
+		HAConnectionsInterval: interval,
+		Retries:               5,
+	}, tunnelServer, []string{"127.0.0.1:7844", "127.0.0.2:7844", "127.0.0.3:7844"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	connectedSignal := signal.New(make(chan struct{}))
+	require.NoError(t, s.initialize(ctx, connectedSignal))
+	require.Eventually(t, func() bool {
+		return len(tunnelServer.started()) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	started := tunnelServer.started()
+	require.GreaterOrEqual(t, started[2].Sub(started[1]), interval)
+
+	// Stop the connections and wait for all of them to return
+	cancel()
+	for i := 0; i < 3; i++ {
+		<-s.tunnelErrors
+	}
+}
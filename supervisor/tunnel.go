@@ -4,12 +4,15 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"math/rand"
 	"net"
 	"net/netip"
 	"runtime/debug"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -34,6 +37,15 @@ import (
 
 const (
 	dialTimeout = 15 * time.Second
+
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+	udpHeaderSize  = 8
+
+	// MinQUICInitialMTU and MaxQUICInitialMTU bound the path MTU that can be configured for QUIC connections, such that
+	// the resulting packet size is accepted by quic-go for both IPv4 and IPv6 edge addresses.
+	MinQUICInitialMTU = 1200 + ipv6HeaderSize + udpHeaderSize
+	MaxQUICInitialMTU = 1452 + ipv4HeaderSize + udpHeaderSize
 )
 
 type TunnelConfig struct {
@@ -56,7 +68,10 @@ type TunnelConfig struct {
 	ReportedVersion    string
 	Retries            uint
 	MaxEdgeAddrRetries uint8
-	RunFromTerminal    bool
+	// InitialConnectRetries, if set, bounds how many times the first connection is retried before it ever
+	// registers. Once it has registered, Retries applies as usual.
+	InitialConnectRetries uint
+	RunFromTerminal       bool
 
 	NeedPQ bool
 
@@ -68,7 +83,23 @@ type TunnelConfig struct {
 	RPCTimeout         time.Duration
 	WriteStreamTimeout time.Duration
 
-	DisableQUICPathMTUDiscovery         bool
+	// HAConnectionsInterval is how long to wait before starting each connection after the first one has registered.
+	HAConnectionsInterval time.Duration
+
+	// ConnectionMaxLifetime, if set, recycles each edge connection once it has been up for that long, so that
+	// long-running connectors get a chance to land on a better data center.
+	ConnectionMaxLifetime time.Duration
+
+	// RequireProtocol makes the connection give up instead of falling back when the protocol from the
+	// ProtocolSelector can't be used.
+	RequireProtocol bool
+
+	DisableQUICPathMTUDiscovery bool
+	QUICInitialMTU              uint16
+	// QUICMaxUDPPayloadSize limits the origin UDP payloads proxied in QUIC datagrams. 0 uses v3.MaxDatagramPayloadLen.
+	QUICMaxUDPPayloadSize int
+	// QUICMaxIdleTimeout is how long a QUIC connection may be idle before it is closed. 0 uses quic.MaxIdleTimeout.
+	QUICMaxIdleTimeout                  time.Duration
 	QUICConnectionLevelFlowControlLimit uint64
 	QUICStreamLevelFlowControlLimit     uint64
 
@@ -181,6 +212,7 @@ type EdgeTunnelServer struct {
 	edgeAddrs         *edgediscovery.Edge
 	edgeBindAddr      net.IP
 	reconnectCh       chan ReconnectSignal
+	clockJumps        *clockJumps
 	gracefulShutdownC <-chan struct{}
 	tracker           *tunnelstate.ConnTracker
 
@@ -234,6 +266,7 @@ func (e *EdgeTunnelServer) Serve(ctx context.Context, connIndex uint8, protocolF
 		protocolFallback,
 		protocolFallback.protocol,
 	)
+	connection.ReportFDExhausted(err, "edge", connLog.Logger())
 
 	// Check if the connection error was from an IP issue with the host or
 	// establishing a connection to the edge and if so, rotate the IP address.
@@ -252,6 +285,12 @@ func (e *EdgeTunnelServer) Serve(ctx context.Context, connIndex uint8, protocolF
 		}
 	}
 
+	if shouldFallbackProtocol {
+		if err := e.checkRequiredProtocol(protocolFallback.protocol, err); err != nil {
+			return err
+		}
+	}
+
 	// set connection has re-connecting and log the next retrying backoff
 	duration, ok := protocolFallback.GetMaxBackoffDuration(ctx)
 	if !ok {
@@ -282,7 +321,11 @@ func (e *EdgeTunnelServer) Serve(ctx context.Context, connIndex uint8, protocolF
 			protocolFallback,
 			e.config.ProtocolSelector,
 			err,
+			e.config.RequireProtocol,
 		) {
+			if e.config.RequireProtocol {
+				return requiredProtocolError{protocol: protocolFallback.protocol, cause: err}
+			}
 			return err
 		}
 	}
@@ -310,12 +353,14 @@ func (pf *protocolFallback) fallback(fallback connection.Protocol) {
 }
 
 // selectNextProtocol picks connection protocol for the next retry iteration,
-// returns true if it was able to pick the protocol, false if we are out of options and should stop retrying
+// returns true if it was able to pick the protocol, false if we are out of options and should stop retrying.
+// With requireProtocol, switching to the fallback protocol counts as being out of options.
 func selectNextProtocol(
 	connLog *zerolog.Logger,
 	protocolBackoff *protocolFallback,
 	selector connection.ProtocolSelector,
 	cause error,
+	requireProtocol bool,
 ) bool {
 	isQuicBroken := isQuicBroken(cause)
 	_, hasFallback := selector.Fallback()
@@ -338,8 +383,13 @@ func selectNextProtocol(
 		if protocolBackoff.protocol == fallback {
 			return false
 		}
+		if requireProtocol {
+			connLog.Error().Msgf("Not switching to fallback protocol %s because --require-protocol is set", fallback)
+			return false
+		}
 		connLog.Info().Msgf("Switching to fallback protocol %s", fallback)
 		protocolBackoff.fallback(fallback)
+		protocolFallbacks.Inc()
 	} else if !protocolBackoff.inFallback {
 		current := selector.Current()
 		if protocolBackoff.protocol != current {
@@ -364,6 +414,44 @@ func isQuicBroken(cause error) bool {
 	return false
 }
 
+// connectionErrorReason maps the error a connection ended with to one of a few reasons that are safe to use as a
+// metric label. It returns "" for errors that aren't failures, like a reconnect signal or a shutdown.
+func connectionErrorReason(err error) string {
+	var (
+		registerErr      connection.ServerRegisterTunnelError
+		quicDialErr      *connection.EdgeQuicDialError
+		dialErr          edgediscovery.DialError
+		idleTimeoutErr   *quic.IdleTimeoutError
+		handshakeErr     *quic.HandshakeTimeoutError
+		applicationErr   *quic.ApplicationError
+		transportErr     *quic.TransportError
+		certificateErr   *tls.CertificateVerificationError
+		tlsAlertErr      tls.AlertError
+		reconnectSignal  ReconnectSignal
+		unrecoverableErr unrecoverableError
+	)
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.As(err, &reconnectSignal):
+		return ""
+	case errors.Is(err, connection.DupConnRegisterTunnelError{}):
+		return "duplicate_connection"
+	case errors.As(err, &registerErr):
+		return "registration"
+	case errors.As(err, &certificateErr), errors.As(err, &tlsAlertErr):
+		return "tls"
+	case errors.As(err, &idleTimeoutErr), errors.As(err, &handshakeErr):
+		return "timeout"
+	case errors.As(err, &quicDialErr), errors.As(err, &dialErr):
+		return "dial"
+	case errors.As(err, &applicationErr), errors.As(err, &transportErr):
+		return "quic"
+	case errors.As(err, &unrecoverableErr):
+		return "unrecoverable"
+	default:
+		return "other"
+	}
+}
+
 // ServeTunnel runs a single tunnel connection, returns nil on graceful shutdown,
 // on error returns a flag indicating if error can be retried
 func (e *EdgeTunnelServer) serveTunnel(
@@ -399,6 +487,10 @@ func (e *EdgeTunnelServer) serveTunnel(
 		protocol,
 	)
 
+	if reason := connectionErrorReason(err); reason != "" {
+		e.config.Observer.RecordConnectionError(connIndex, reason)
+	}
+
 	if err != nil {
 		switch err := err.(type) {
 		case connection.DupConnRegisterTunnelError:
@@ -442,9 +534,23 @@ func (e *EdgeTunnelServer) serveConnection(
 	protocol connection.Protocol,
 ) (err error, recoverable bool) {
 	connectedFuse := &connectedFuse{
-		fuse:    fuse,
-		backoff: backoff,
+		fuse:      fuse,
+		backoff:   backoff,
+		observer:  e.config.Observer,
+		protocol:  protocol,
+		start:     time.Now(),
+		connIndex: connIndex,
+		preferred: e.config.ProtocolSelector.Current(),
+		log:       connLog.Logger(),
 	}
+	shutdownC, recycled, stopShutdownC := e.connectionShutdownC(connLog)
+	defer stopShutdownC()
+	defer func() {
+		// The connection drained gracefully because it reached its max lifetime, so start a new one right away
+		if err == nil && recycled() {
+			err, recoverable = ReconnectSignal{}, true
+		}
+	}()
 	controlStream := connection.NewControlStream(
 		e.config.Observer,
 		connectedFuse,
@@ -453,7 +559,7 @@ func (e *EdgeTunnelServer) serveConnection(
 		addr.UDP.IP,
 		nil,
 		e.config.RPCTimeout,
-		e.gracefulShutdownC,
+		shutdownC,
 		e.config.GracePeriod,
 		protocol,
 	)
@@ -466,7 +572,8 @@ func (e *EdgeTunnelServer) serveConnection(
 			connLog,
 			connOptions,
 			controlStream,
-			connIndex)
+			connIndex,
+			shutdownC)
 
 	case connection.HTTP2:
 		edgeConn, err := edgediscovery.DialEdge(ctx, dialTimeout, e.config.EdgeTLSConfigs[protocol], addr.TCP, e.edgeBindAddr)
@@ -483,6 +590,7 @@ func (e *EdgeTunnelServer) serveConnection(
 			connOptions,
 			controlStream,
 			connIndex,
+			shutdownC,
 		); err != nil {
 			return err, false
 		}
@@ -493,6 +601,30 @@ func (e *EdgeTunnelServer) serveConnection(
 	return
 }
 
+// checkRequiredProtocol returns a requiredProtocolError if the protocol is required, has never connected and cause
+// shows that it can't be used from this network, so that cloudflared exits right away rather than after retrying.
+func (e *EdgeTunnelServer) checkRequiredProtocol(protocol connection.Protocol, cause error) error {
+	if !e.config.RequireProtocol || !isQuicBroken(cause) || e.tracker.HasConnectedWith(protocol) {
+		return nil
+	}
+	return requiredProtocolError{protocol: protocol, cause: cause}
+}
+
+// requiredProtocolError is returned instead of falling back to another protocol when TunnelConfig.RequireProtocol
+// is set.
+type requiredProtocolError struct {
+	protocol connection.Protocol
+	cause    error
+}
+
+func (e requiredProtocolError) Error() string {
+	return fmt.Sprintf("%s is required but can't be used: %v", e.protocol, e.cause)
+}
+
+func (e requiredProtocolError) Unwrap() error {
+	return e.cause
+}
+
 type unrecoverableError struct {
 	err error
 }
@@ -508,6 +640,7 @@ func (e *EdgeTunnelServer) serveHTTP2(
 	connOptions *pogs.ConnectionOptions,
 	controlStreamHandler connection.ControlStreamHandler,
 	connIndex uint8,
+	shutdownC <-chan struct{},
 ) error {
 	pqMode := e.config.FeatureSelector.PostQuantumMode()
 	if pqMode == features.PostQuantumStrict {
@@ -525,13 +658,15 @@ func (e *EdgeTunnelServer) serveHTTP2(
 		e.config.Log,
 	)
 
+	// Only the clock jumps from now on concern this connection
+	clockJumped := e.clockJumps.wait()
 	errGroup, serveCtx := errgroup.WithContext(ctx)
 	errGroup.Go(func() error {
 		return h2conn.Serve(serveCtx)
 	})
 
 	errGroup.Go(func() error {
-		err := listenReconnect(serveCtx, e.reconnectCh, e.gracefulShutdownC)
+		err := listenReconnect(serveCtx, e.reconnectCh, clockJumped, shutdownC)
 		if err != nil {
 			// forcefully break the connection (this is only used for testing)
 			// errgroup will return context canceled for the h2conn.Serve
@@ -550,6 +685,7 @@ func (e *EdgeTunnelServer) serveQUIC(
 	connOptions *pogs.ConnectionOptions,
 	controlStreamHandler connection.ControlStreamHandler,
 	connIndex uint8,
+	shutdownC <-chan struct{},
 ) (err error, recoverable bool) {
 	tlsConfig := e.config.EdgeTLSConfigs[connection.QUIC]
 
@@ -565,16 +701,15 @@ func (e *EdgeTunnelServer) serveQUIC(
 
 	tlsConfig.CurvePreferences = curvePref
 
-	// quic-go 0.44 increases the initial packet size to 1280 by default. That breaks anyone running tunnel through WARP
-	// because WARP MTU is 1280.
-	var initialPacketSize uint16 = 1252
-	if edgeAddr.Addr().Is4() {
-		initialPacketSize = 1232
+	initialPacketSize := quicInitialPacketSize(edgeAddr, e.config.QUICInitialMTU)
+	maxIdleTimeout := e.config.QUICMaxIdleTimeout
+	if maxIdleTimeout == 0 {
+		maxIdleTimeout = quicpogs.MaxIdleTimeout
 	}
 
 	quicConfig := &quic.Config{
 		HandshakeIdleTimeout:       quicpogs.HandshakeIdleTimeout,
-		MaxIdleTimeout:             quicpogs.MaxIdleTimeout,
+		MaxIdleTimeout:             maxIdleTimeout,
 		KeepAlivePeriod:            quicpogs.MaxIdlePingPeriod,
 		MaxIncomingStreams:         quicpogs.MaxIncomingStreams,
 		MaxIncomingUniStreams:      quicpogs.MaxIncomingStreams,
@@ -644,6 +779,8 @@ func (e *EdgeTunnelServer) serveQUIC(
 	}
 
 	// Serve the TunnelConnection
+	// Only the clock jumps from now on concern this connection
+	clockJumped := e.clockJumps.wait()
 	errGroup, serveCtx := errgroup.WithContext(ctx)
 	errGroup.Go(func() error {
 		err := tunnelConn.Serve(serveCtx)
@@ -654,7 +791,7 @@ func (e *EdgeTunnelServer) serveQUIC(
 	})
 
 	errGroup.Go(func() error {
-		err := listenReconnect(serveCtx, e.reconnectCh, e.gracefulShutdownC)
+		err := listenReconnect(serveCtx, e.reconnectCh, clockJumped, shutdownC)
 		if err != nil {
 			// forcefully break the connection (this is only used for testing)
 			// errgroup will return context canceled for the tunnelConn.Serve
@@ -666,10 +803,55 @@ func (e *EdgeTunnelServer) serveQUIC(
 	return errGroup.Wait(), false
 }
 
-func listenReconnect(ctx context.Context, reconnectCh <-chan ReconnectSignal, gracefulShutdownCh <-chan struct{}) error {
+// connectionShutdownC returns the channel that starts the graceful shutdown of a connection: it is closed when
+// cloudflared shuts down or, with a max lifetime, when the connection is due to be recycled. Either way the connection
+// is unregistered so that the edge stops sending it new requests, and in-flight ones get the grace period to finish.
+// recycled tells whether the max lifetime was the reason. The returned stop func must be called once the connection
+// is done.
+func (e *EdgeTunnelServer) connectionShutdownC(connLog *ConnAwareLogger) (shutdownC <-chan struct{}, recycled func() bool, stop func()) {
+	if e.config.ConnectionMaxLifetime <= 0 {
+		return e.gracefulShutdownC, func() bool { return false }, func() {}
+	}
+	lifetime := connectionLifetime(e.config.ConnectionMaxLifetime)
+	connLog.Logger().Debug().Msgf("Connection will be recycled in %s", lifetime.Round(time.Second))
+	return recycleAfter(e.gracefulShutdownC, time.NewTimer(lifetime), connLog)
+}
+
+func recycleAfter(gracefulShutdownC <-chan struct{}, timer *time.Timer, connLog *ConnAwareLogger) (<-chan struct{}, func() bool, func()) {
+	shutdownC := make(chan struct{})
+	done := make(chan struct{})
+	var lifetimeReached atomic.Bool
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			connLog.Logger().Info().Msg("Connection reached its max lifetime, draining it before reconnecting")
+			lifetimeReached.Store(true)
+			close(shutdownC)
+		case <-gracefulShutdownC:
+			close(shutdownC)
+		case <-done:
+		}
+	}()
+	return shutdownC, lifetimeReached.Load, func() { close(done) }
+}
+
+// connectionLifetime staggers the lifetime of connections by up to a quarter of maxLifetime, so that the HA connections,
+// which usually connect within seconds of each other, are not all recycled at once.
+func connectionLifetime(maxLifetime time.Duration) time.Duration {
+	jitter := maxLifetime / 4
+	if jitter <= 0 {
+		return maxLifetime
+	}
+	return maxLifetime - time.Duration(rand.Int63n(int64(jitter)))
+}
+
+func listenReconnect(ctx context.Context, reconnectCh <-chan ReconnectSignal, clockJumped <-chan struct{}, gracefulShutdownCh <-chan struct{}) error {
 	select {
 	case reconnect := <-reconnectCh:
 		return reconnect
+	case <-clockJumped:
+		return ReconnectSignal{}
 	case <-gracefulShutdownCh:
 		return nil
 	case <-ctx.Done():
@@ -680,13 +862,58 @@ func listenReconnect(ctx context.Context, reconnectCh <-chan ReconnectSignal, gr
 type connectedFuse struct {
 	fuse    *booleanFuse
 	backoff *protocolFallback
+	// observer records how long the connection took to get ready, from start
+	observer *connection.Observer
+	protocol connection.Protocol
+	start    time.Time
+
+	// connIndex and preferred are used to report whether the connection is using the fallback protocol
+	connIndex uint8
+	preferred connection.Protocol
+	log       *zerolog.Logger
 }
 
 func (cf *connectedFuse) Connected() {
 	cf.fuse.Fuse(true)
 	cf.backoff.reset()
+	if cf.observer != nil {
+		cf.observer.RecordConnectDuration(cf.protocol, time.Since(cf.start))
+	}
+	cf.reportFallbackProtocol()
+}
+
+// reportFallbackProtocol records whether the connection connected with another protocol than the preferred one, e.g.
+// http2 because QUIC is blocked on the network.
+func (cf *connectedFuse) reportFallbackProtocol() {
+	onFallback := cf.preferred != cf.protocol
+	gauge := fallbackProtocol.WithLabelValues(strconv.Itoa(int(cf.connIndex)))
+	if !onFallback {
+		gauge.Set(0)
+		return
+	}
+	gauge.Set(1)
+	if cf.log != nil {
+		cf.log.Warn().Msgf("Connection is using the fallback protocol %s instead of %s", cf.protocol, cf.preferred)
+	}
 }
 
 func (cf *connectedFuse) IsConnected() bool {
 	return cf.fuse.Value()
 }
+
+// quicInitialPacketSize returns the size of the first QUIC packets sent to the edge. If mtu is set, it is the path MTU
+// configured by the user and the packet size is what's left once the IP and UDP headers are accounted for.
+func quicInitialPacketSize(edgeAddr netip.AddrPort, mtu uint16) uint16 {
+	if mtu == 0 {
+		// quic-go 0.44 increases the initial packet size to 1280 by default. That breaks anyone running tunnel through WARP
+		// because WARP MTU is 1280.
+		if edgeAddr.Addr().Is4() {
+			return 1232
+		}
+		return 1252
+	}
+	if edgeAddr.Addr().Is4() {
+		return mtu - ipv4HeaderSize - udpHeaderSize
+	}
+	return mtu - ipv6HeaderSize - udpHeaderSize
+}
@@ -1,9 +1,14 @@
 package supervisor
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/netip"
 	"testing"
 	"time"
 
+	dto "github.com/prometheus/client_model/go"
 	"github.com/quic-go/quic-go"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -11,6 +16,7 @@ import (
 	"github.com/cloudflare/cloudflared/connection"
 	"github.com/cloudflare/cloudflared/edgediscovery"
 	"github.com/cloudflare/cloudflared/retry"
+	"github.com/cloudflare/cloudflared/tunnelstate"
 )
 
 type dynamicMockFetcher struct {
@@ -62,14 +68,14 @@ func TestWaitForBackoffFallback(t *testing.T) {
 	// Retry #0 and #1. At retry #2, we switch protocol, so the fallback loop has one more retry than this
 	for i := 0; i < int(maxRetries-1); i++ {
 		protoFallback.BackoffTimer() // simulate retry
-		ok := selectNextProtocol(&log, protoFallback, protocolSelector, nil)
+		ok := selectNextProtocol(&log, protoFallback, protocolSelector, nil, false)
 		assert.True(t, ok)
 		assert.Equal(t, initProtocol, protoFallback.protocol)
 	}
 
 	// Retry fallback protocol
 	protoFallback.BackoffTimer() // simulate retry
-	ok := selectNextProtocol(&log, protoFallback, protocolSelector, nil)
+	ok := selectNextProtocol(&log, protoFallback, protocolSelector, nil, false)
 	assert.True(t, ok)
 	fallback, ok := protocolSelector.Fallback()
 	assert.True(t, ok)
@@ -84,18 +90,18 @@ func TestWaitForBackoffFallback(t *testing.T) {
 		protoFallback.BackoffTimer()
 	}
 	// No protocol to fallback, return error
-	ok = selectNextProtocol(&log, protoFallback, protocolSelector, nil)
+	ok = selectNextProtocol(&log, protoFallback, protocolSelector, nil, false)
 	assert.False(t, ok)
 
 	protoFallback.reset()
 	protoFallback.BackoffTimer() // simulate retry
-	ok = selectNextProtocol(&log, protoFallback, protocolSelector, nil)
+	ok = selectNextProtocol(&log, protoFallback, protocolSelector, nil, false)
 	assert.True(t, ok)
 	assert.Equal(t, initProtocol, protoFallback.protocol)
 
 	protoFallback.reset()
 	protoFallback.BackoffTimer() // simulate retry
-	ok = selectNextProtocol(&log, protoFallback, protocolSelector, &quic.IdleTimeoutError{})
+	ok = selectNextProtocol(&log, protoFallback, protocolSelector, &quic.IdleTimeoutError{}, false)
 	// Check that we get a true after the first try itself when this flag is true. This allows us to immediately
 	// switch protocols when there is a fallback.
 	assert.True(t, ok)
@@ -115,12 +121,171 @@ func TestWaitForBackoffFallback(t *testing.T) {
 	protoFallback = &protocolFallback{backoff, protocolSelector.Current(), false}
 	for i := 0; i < int(maxRetries-1); i++ {
 		protoFallback.BackoffTimer() // simulate retry
-		ok := selectNextProtocol(&log, protoFallback, protocolSelector, &quic.IdleTimeoutError{})
+		ok := selectNextProtocol(&log, protoFallback, protocolSelector, &quic.IdleTimeoutError{}, false)
 		assert.True(t, ok)
 		assert.Equal(t, connection.QUIC, protoFallback.protocol)
 	}
 	// And finally it fails as it should, with no fallback.
 	protoFallback.BackoffTimer()
-	ok = selectNextProtocol(&log, protoFallback, protocolSelector, &quic.IdleTimeoutError{})
+	ok = selectNextProtocol(&log, protoFallback, protocolSelector, &quic.IdleTimeoutError{}, false)
 	assert.False(t, ok)
 }
+
+func TestSelectNextProtocolRequired(t *testing.T) {
+	maxRetries := uint(3)
+	backoff := retry.NewBackoff(maxRetries, 40*time.Millisecond, false)
+	backoff.Clock.After = immediateTimeAfter
+	log := zerolog.Nop()
+	mockFetcher := dynamicMockFetcher{
+		protocolPercents: edgediscovery.ProtocolPercents{edgediscovery.ProtocolPercent{Protocol: "quic", Percentage: 100}},
+	}
+	protocolSelector, err := connection.NewProtocolSelector("auto", "", false, false, mockFetcher.fetch(), 10*time.Second, &log)
+	assert.NoError(t, err)
+	protoFallback := &protocolFallback{backoff, protocolSelector.Current(), false}
+
+	// Broken QUIC would switch to http2 right away, but the protocol is required
+	protoFallback.BackoffTimer()
+	assert.False(t, selectNextProtocol(&log, protoFallback, protocolSelector, &quic.IdleTimeoutError{}, true))
+	assert.Equal(t, connection.QUIC, protoFallback.protocol)
+
+	// Same once retries are exhausted
+	for i := 0; i < int(maxRetries); i++ {
+		protoFallback.BackoffTimer()
+	}
+	assert.False(t, selectNextProtocol(&log, protoFallback, protocolSelector, nil, true))
+	assert.Equal(t, connection.QUIC, protoFallback.protocol)
+}
+
+func TestCheckRequiredProtocol(t *testing.T) {
+	log := zerolog.Nop()
+	quicBroken := &quic.IdleTimeoutError{}
+	server := &EdgeTunnelServer{
+		config:  &TunnelConfig{RequireProtocol: true},
+		tracker: tunnelstate.NewConnTracker(&log),
+	}
+
+	err := server.checkRequiredProtocol(connection.QUIC, quicBroken)
+	var requiredErr requiredProtocolError
+	assert.ErrorAs(t, err, &requiredErr)
+	assert.ErrorIs(t, err, quicBroken)
+
+	// Other errors are retried as usual
+	assert.NoError(t, server.checkRequiredProtocol(connection.QUIC, errors.New("connection reset")))
+
+	// Once the protocol has worked, the network is known to allow it
+	server.tracker.OnTunnelEvent(connection.Event{Index: 0, EventType: connection.Connected, Protocol: connection.QUIC})
+	assert.NoError(t, server.checkRequiredProtocol(connection.QUIC, quicBroken))
+
+	server = &EdgeTunnelServer{
+		config:  &TunnelConfig{},
+		tracker: tunnelstate.NewConnTracker(&log),
+	}
+	assert.NoError(t, server.checkRequiredProtocol(connection.QUIC, quicBroken))
+}
+
+func TestQUICInitialPacketSize(t *testing.T) {
+	ipv4Edge := netip.MustParseAddrPort("198.41.192.7:7844")
+	ipv6Edge := netip.MustParseAddrPort("[2606:4700:a0::1]:7844")
+
+	assert.Equal(t, uint16(1232), quicInitialPacketSize(ipv4Edge, 0))
+	assert.Equal(t, uint16(1252), quicInitialPacketSize(ipv6Edge, 0))
+
+	assert.Equal(t, uint16(1372), quicInitialPacketSize(ipv4Edge, 1400))
+	assert.Equal(t, uint16(1352), quicInitialPacketSize(ipv6Edge, 1400))
+
+	assert.Equal(t, uint16(1200), quicInitialPacketSize(ipv6Edge, MinQUICInitialMTU))
+	assert.Equal(t, uint16(1452), quicInitialPacketSize(ipv4Edge, MaxQUICInitialMTU))
+}
+
+func TestConnectionLifetime(t *testing.T) {
+	maxLifetime := time.Hour
+	for i := 0; i < 100; i++ {
+		lifetime := connectionLifetime(maxLifetime)
+		assert.LessOrEqual(t, lifetime, maxLifetime)
+		assert.Greater(t, lifetime, maxLifetime*3/4)
+	}
+	assert.Equal(t, time.Duration(1), connectionLifetime(1))
+}
+
+func TestConnectionShutdownC(t *testing.T) {
+	log := zerolog.Nop()
+	connLog := NewConnAwareLogger(&log, tunnelstate.NewConnTracker(&log), connection.NewObserver(&log, &log))
+
+	// Reaching the max lifetime starts a graceful shutdown of the connection and marks it as recycled
+	gracefulShutdownC := make(chan struct{})
+	shutdownC, recycled, stop := recycleAfter(gracefulShutdownC, time.NewTimer(time.Millisecond), connLog)
+	defer stop()
+	select {
+	case <-shutdownC:
+	case <-time.After(time.Second):
+		t.Fatal("connection was not shut down after reaching its max lifetime")
+	}
+	assert.True(t, recycled())
+
+	// Shutting down cloudflared drains the connection without recycling it
+	shutdownC, recycled, stop = recycleAfter(gracefulShutdownC, time.NewTimer(time.Hour), connLog)
+	defer stop()
+	close(gracefulShutdownC)
+	select {
+	case <-shutdownC:
+	case <-time.After(time.Second):
+		t.Fatal("connection was not shut down with cloudflared")
+	}
+	assert.False(t, recycled())
+
+	// Without a max lifetime, connections only follow cloudflared's graceful shutdown
+	e := &EdgeTunnelServer{config: &TunnelConfig{}, gracefulShutdownC: gracefulShutdownC}
+	shutdownC, recycled, stop = e.connectionShutdownC(connLog)
+	defer stop()
+	assert.Equal(t, (<-chan struct{})(gracefulShutdownC), shutdownC)
+	assert.False(t, recycled())
+}
+
+func TestConnectionErrorReason(t *testing.T) {
+	tests := []struct {
+		err    error
+		reason string
+	}{
+		{err: nil, reason: ""},
+		{err: context.Canceled, reason: ""},
+		{err: ReconnectSignal{}, reason: ""},
+		{err: connection.DupConnRegisterTunnelError{}, reason: "duplicate_connection"},
+		{err: connection.ServerRegisterTunnelError{Cause: errors.New("bad tunnel")}, reason: "registration"},
+		{err: &connection.EdgeQuicDialError{Cause: &tls.CertificateVerificationError{Err: errors.New("expired")}}, reason: "tls"},
+		{err: &connection.EdgeQuicDialError{Cause: &quic.HandshakeTimeoutError{}}, reason: "timeout"},
+		{err: &connection.EdgeQuicDialError{Cause: errors.New("network is unreachable")}, reason: "dial"},
+		{err: &quic.ApplicationError{ErrorCode: 1}, reason: "quic"},
+		{err: unrecoverableError{err: errors.New("bad config")}, reason: "unrecoverable"},
+		{err: errors.New("something else"), reason: "other"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.reason, connectionErrorReason(test.err), "%v", test.err)
+	}
+}
+
+func TestConnectedFuseFallbackProtocol(t *testing.T) {
+	log := zerolog.Nop()
+	fallbackValue := func(connIndex string) float64 {
+		var metric dto.Metric
+		assert.NoError(t, fallbackProtocol.WithLabelValues(connIndex).Write(&metric))
+		return metric.GetGauge().GetValue()
+	}
+	newFuse := func(connIndex uint8, protocol connection.Protocol) *connectedFuse {
+		return &connectedFuse{
+			fuse:      newBooleanFuse(),
+			backoff:   &protocolFallback{retry.NewBackoff(1, time.Millisecond, false), protocol, false},
+			protocol:  protocol,
+			start:     time.Now(),
+			connIndex: connIndex,
+			preferred: connection.QUIC,
+			log:       &log,
+		}
+	}
+
+	newFuse(200, connection.HTTP2).Connected()
+	assert.Equal(t, float64(1), fallbackValue("200"))
+
+	// Reconnecting with the preferred protocol clears it
+	newFuse(200, connection.QUIC).Connected()
+	assert.Equal(t, float64(0), fallbackValue("200"))
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 
@@ -15,8 +16,12 @@ import (
 )
 
 const (
-	OriginCAPoolFlag = "origin-ca-pool"
-	CaCertFlag       = "cacert"
+	OriginCAPoolFlag    = "origin-ca-pool"
+	OriginCAPoolPEMFlag = "origin-ca-pool-pem"
+	CaCertFlag          = "cacert"
+	// EdgeInsecureSkipVerifyFlag disables the verification of the edge certificate. It's only meant for testing
+	// against a non-production edge, since it allows anyone on the network path to impersonate the edge.
+	EdgeInsecureSkipVerifyFlag = "edge-insecure-skip-verify"
 )
 
 // CertReloader can load and reload a TLS certificate from a particular filepath.
@@ -72,16 +77,22 @@ func (cr *CertReloader) LoadCert() error {
 	return nil
 }
 
-func LoadOriginCA(originCAPoolFilename string, log *zerolog.Logger) (*x509.CertPool, error) {
+// LoadOriginCA returns the pool of CAs trusted for origin certificates. originCAPool is either a PEM file or a
+// directory, in which case all of its *.pem files are loaded. originCAPoolPEM holds PEM encoded certificates.
+func LoadOriginCA(originCAPool, originCAPoolPEM string, log *zerolog.Logger) (*x509.CertPool, error) {
 	var originCustomCAPool []byte
 
-	if originCAPoolFilename != "" {
+	if originCAPool != "" {
 		var err error
-		originCustomCAPool, err = os.ReadFile(originCAPoolFilename)
+		originCustomCAPool, err = readOriginCAPool(originCAPool)
 		if err != nil {
-			return nil, errors.Wrap(err, fmt.Sprintf("unable to read the file %s for --%s", originCAPoolFilename, OriginCAPoolFlag))
+			return nil, err
 		}
 	}
+	if originCAPoolPEM != "" {
+		originCustomCAPool = append(originCustomCAPool, '\n')
+		originCustomCAPool = append(originCustomCAPool, originCAPoolPEM...)
+	}
 
 	originCertPool, err := loadOriginCertPool(originCustomCAPool, log)
 	if err != nil {
@@ -89,13 +100,45 @@ func LoadOriginCA(originCAPoolFilename string, log *zerolog.Logger) (*x509.CertP
 	}
 
 	// Windows users should be notified that they can use the flag
-	if runtime.GOOS == "windows" && originCAPoolFilename == "" {
+	if runtime.GOOS == "windows" && originCAPool == "" && originCAPoolPEM == "" {
 		log.Info().Msgf("cloudflared does not support loading the system root certificate pool on Windows. Please use --%s <PATH> to specify the path to the certificate pool", OriginCAPoolFlag)
 	}
 
 	return originCertPool, nil
 }
 
+func readOriginCAPool(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("unable to read the file %s for --%s", path, OriginCAPoolFlag))
+	}
+	if !info.IsDir() {
+		pool, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to read the file %s for --%s", path, OriginCAPoolFlag))
+		}
+		return pool, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.pem"))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("unable to list the directory %s for --%s", path, OriginCAPoolFlag))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .pem file found in the directory %s for --%s", path, OriginCAPoolFlag)
+	}
+	var pool []byte
+	for _, file := range files {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unable to read the file %s for --%s", file, OriginCAPoolFlag))
+		}
+		pool = append(pool, pem...)
+		pool = append(pool, '\n')
+	}
+	return pool, nil
+}
+
 func LoadCustomOriginCA(originCAFilename string) (*x509.CertPool, error) {
 	// First, obtain the system certificate pool
 	certPool, err := x509.SystemCertPool()
@@ -138,6 +181,7 @@ func CreateTunnelConfig(c *cli.Context, serverName string) (*tls.Config, error)
 	if err != nil {
 		return nil, err
 	}
+	tlsConfig.InsecureSkipVerify = c.Bool(EdgeInsecureSkipVerifyFlag)
 
 	if tlsConfig.RootCAs == nil {
 		rootCAPool, err := x509.SystemCertPool()
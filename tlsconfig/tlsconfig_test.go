@@ -2,9 +2,15 @@ package tlsconfig
 
 import (
 	"crypto/tls"
+	"flag"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
 )
 
 // testcert.pem and testcert2.pem are Generated using `openssl req -newkey rsa:512 -nodes -x509 -days 3650`
@@ -58,6 +64,36 @@ func TestGetConfig(t *testing.T) {
 	assert.Equal(t, tls.CurveP384, tlsConfig.CurvePreferences[0])
 }
 
+func TestCreateTunnelConfig(t *testing.T) {
+	newContext := func(args ...string) *cli.Context {
+		flagSet := flag.NewFlagSet(t.Name(), flag.PanicOnError)
+		flagSet.String(CaCertFlag, "", "")
+		flagSet.Bool(EdgeInsecureSkipVerifyFlag, false, "")
+		require.NoError(t, flagSet.Parse(args))
+		return cli.NewContext(cli.NewApp(), flagSet, nil)
+	}
+
+	tlsConfig, err := CreateTunnelConfig(newContext(), "edge.example.com")
+	require.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Equal(t, "edge.example.com", tlsConfig.ServerName)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	tlsConfig, err = CreateTunnelConfig(newContext("--"+CaCertFlag, "testcert.pem"), "edge.example.com")
+	require.NoError(t, err)
+	expected, err := LoadCert([]string{"testcert.pem"})
+	require.NoError(t, err)
+	assert.True(t, expected.Equal(tlsConfig.RootCAs), "only the CAs of the bundle should be trusted")
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = CreateTunnelConfig(newContext("--"+CaCertFlag, "missing.pem"), "edge.example.com")
+	assert.Error(t, err)
+
+	tlsConfig, err = CreateTunnelConfig(newContext("--"+EdgeInsecureSkipVerifyFlag), "edge.example.com")
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+}
+
 func TestCertReloader(t *testing.T) {
 	expectedCert, err := tls.LoadX509KeyPair("testcert.pem", "testkey.pem")
 	assert.NoError(t, err)
@@ -80,3 +116,34 @@ func TestCertReloader(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectedCert, *cert)
 }
+
+func TestLoadOriginCA(t *testing.T) {
+	log := zerolog.Nop()
+	cert1, err := os.ReadFile("testcert.pem")
+	require.NoError(t, err)
+	cert2, err := os.ReadFile("testcert2.pem")
+	require.NoError(t, err)
+	expected, err := loadOriginCertPool(cert1, &log)
+	require.NoError(t, err)
+	require.True(t, expected.AppendCertsFromPEM(cert2))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca1.pem"), cert1, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca2.pem"), cert2, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not a certificate"), 0600))
+
+	pool, err := LoadOriginCA(dir, "", &log)
+	require.NoError(t, err)
+	assert.True(t, expected.Equal(pool))
+
+	pool, err = LoadOriginCA("testcert.pem", string(cert2), &log)
+	require.NoError(t, err)
+	assert.True(t, expected.Equal(pool))
+
+	pool, err = LoadOriginCA("", string(cert1)+"\n"+string(cert2), &log)
+	require.NoError(t, err)
+	assert.True(t, expected.Equal(pool))
+
+	_, err = LoadOriginCA(t.TempDir(), "", &log)
+	assert.Error(t, err)
+}
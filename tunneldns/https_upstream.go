@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/miekg/dns"
@@ -25,17 +26,33 @@ const (
 type UpstreamHTTPS struct {
 	client     *http.Client
 	endpoint   *url.URL
+	headers    http.Header
 	bootstraps []string
 	log        *zerolog.Logger
 }
 
-// NewUpstreamHTTPS creates a new DNS over HTTPS upstream from endpoint
-func NewUpstreamHTTPS(endpoint string, bootstraps []string, maxConnections int, log *zerolog.Logger) (Upstream, error) {
+// NewUpstreamHTTPS creates a new DNS over HTTPS upstream from endpoint. headers are added to every request to the
+// endpoint, but not to the bootstraps.
+func NewUpstreamHTTPS(endpoint string, headers http.Header, bootstraps []string, maxConnections int, log *zerolog.Logger) (Upstream, error) {
 	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, err
 	}
-	return &UpstreamHTTPS{client: configureClient(u.Hostname(), maxConnections), endpoint: u, bootstraps: bootstraps, log: log}, nil
+	return &UpstreamHTTPS{client: configureClient(u.Hostname(), maxConnections), endpoint: u, headers: headers, bootstraps: bootstraps, log: log}, nil
+}
+
+// ParseUpstreamHeaders parses headers in the "Name: Value" form, e.g. "Authorization: Bearer token".
+func ParseUpstreamHeaders(headers []string) (http.Header, error) {
+	parsed := make(http.Header, len(headers))
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("upstream header %q must be in the form \"Name: Value\"", header)
+		}
+		parsed.Add(name, strings.TrimSpace(value))
+	}
+	return parsed, nil
 }
 
 // Exchange provides an implementation for the Upstream interface
@@ -52,7 +69,7 @@ func (u *UpstreamHTTPS) Exchange(ctx context.Context, query *dns.Msg) (*dns.Msg,
 				u.log.Err(err).Msgf("failed to configure bootstrap upstream %s", bootstrap)
 				continue
 			}
-			msg, err := exchange(queryBuf, query.Id, endpoint, client, u.log)
+			msg, err := exchange(queryBuf, query.Id, endpoint, nil, client, u.log)
 			if err != nil {
 				u.log.Err(err).Msgf("failed to connect to a bootstrap upstream %s", bootstrap)
 				continue
@@ -62,12 +79,12 @@ func (u *UpstreamHTTPS) Exchange(ctx context.Context, query *dns.Msg) (*dns.Msg,
 		return nil, fmt.Errorf("failed to reach any bootstrap upstream: %v", u.bootstraps)
 	}
 
-	return exchange(queryBuf, query.Id, u.endpoint, u.client, u.log)
+	return exchange(queryBuf, query.Id, u.endpoint, u.headers, u.client, u.log)
 }
 
-func exchange(msg []byte, queryID uint16, endpoint *url.URL, client *http.Client, log *zerolog.Logger) (*dns.Msg, error) {
+func exchange(msg []byte, queryID uint16, endpoint *url.URL, headers http.Header, client *http.Client, log *zerolog.Logger) (*dns.Msg, error) {
 	// No content negotiation for now, use DNS wire format
-	buf, backendErr := exchangeWireformat(msg, endpoint, client)
+	buf, backendErr := exchangeWireformat(msg, endpoint, headers, client)
 	if backendErr == nil {
 		response := &dns.Msg{}
 		if err := response.Unpack(buf); err != nil {
@@ -84,13 +101,16 @@ func exchange(msg []byte, queryID uint16, endpoint *url.URL, client *http.Client
 
 // Perform message exchange with the default UDP wireformat defined in current draft
 // https://datatracker.ietf.org/doc/draft-ietf-doh-dns-over-https
-func exchangeWireformat(msg []byte, endpoint *url.URL, client *http.Client) ([]byte, error) {
+func exchangeWireformat(msg []byte, endpoint *url.URL, headers http.Header, client *http.Client) ([]byte, error) {
 	req, err := http.NewRequest("POST", endpoint.String(), bytes.NewBuffer(msg))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create an HTTPS request")
 	}
 
-	req.Header.Add("Content-Type", "application/dns-message")
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
 	req.Host = endpoint.Host
 
 	resp, err := client.Do(req)
@@ -0,0 +1,51 @@
+package tunneldns
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUpstreamHeaders(t *testing.T) {
+	headers, err := ParseUpstreamHeaders([]string{"Authorization: Bearer token", "X-Tag:a", "X-Tag: b"})
+	require.NoError(t, err)
+	assert.Equal(t, http.Header{
+		"Authorization": {"Bearer token"},
+		"X-Tag":         {"a", "b"},
+	}, headers)
+
+	_, err = ParseUpstreamHeaders([]string{"Authorization"})
+	assert.Error(t, err)
+	_, err = ParseUpstreamHeaders([]string{": value"})
+	assert.Error(t, err)
+}
+
+func TestExchangeWireformatHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/custom/resolve" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "application/dns-message", r.Header.Get("Content-Type"))
+		_, _ = w.Write([]byte("response"))
+	}))
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL + "/custom/resolve")
+	require.NoError(t, err)
+	headers := http.Header{
+		"Authorization": {"Bearer token"},
+		// The DNS wire format content type can't be overridden
+		"Content-Type": {"text/plain"},
+	}
+	buf, err := exchangeWireformat([]byte("query"), endpoint, headers, server.Client())
+	require.NoError(t, err)
+	assert.Equal(t, "response", string(buf))
+
+	_, err = exchangeWireformat([]byte("query"), endpoint, nil, server.Client())
+	assert.ErrorContains(t, err, "401")
+}
@@ -10,12 +10,40 @@ import (
 	"github.com/coredns/coredns/plugin/pkg/rcode"
 	"github.com/coredns/coredns/request"
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
 	pluginName = "cloudflared"
+	// otherLabel replaces query types and response codes that dns doesn't know about, to keep the metrics bounded
+	otherLabel = "other"
 )
 
+var (
+	dnsQueries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cloudflared",
+			Subsystem: "dns",
+			Name:      "queries_total",
+			Help:      "Count of DNS queries by query type",
+		},
+		[]string{"qtype"},
+	)
+	dnsResponses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cloudflared",
+			Subsystem: "dns",
+			Name:      "responses_total",
+			Help:      "Count of DNS responses by response code",
+		},
+		[]string{"rcode"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(dnsQueries, dnsResponses)
+}
+
 // MetricsPlugin is an adapter for CoreDNS and built-in metrics
 type MetricsPlugin struct {
 	Next plugin.Handler
@@ -37,8 +65,30 @@ func (p MetricsPlugin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dn
 	server := metrics.WithServer(ctx)
 	vars.Report(server, state, ".", "", rcode.ToString(rw.Rcode), pluginName, rw.Len, rw.Start)
 
+	responseCode := rw.Rcode
+	if !plugin.ClientWrite(status) {
+		// Nothing was written, the server answers with the status instead
+		responseCode = status
+	}
+	dnsQueries.WithLabelValues(queryTypeLabel(state.QType())).Inc()
+	dnsResponses.WithLabelValues(responseCodeLabel(responseCode)).Inc()
+
 	return status, err
 }
 
+func queryTypeLabel(qtype uint16) string {
+	if name, ok := dns.TypeToString[qtype]; ok {
+		return name
+	}
+	return otherLabel
+}
+
+func responseCodeLabel(responseCode int) string {
+	if name, ok := dns.RcodeToString[responseCode]; ok {
+		return name
+	}
+	return otherLabel
+}
+
 // Name implements the CoreDNS plugin interface
 func (p MetricsPlugin) Name() string { return "metrics" }
@@ -0,0 +1,54 @@
+package tunneldns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	var metric dto.Metric
+	require.NoError(t, counter.Write(&metric))
+	return metric.GetCounter().GetValue()
+}
+
+func TestMetricsPluginCountsQueriesAndResponses(t *testing.T) {
+	answer := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		_ = w.WriteMsg(m)
+		return dns.RcodeNameError, nil
+	})
+	fail := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		return dns.RcodeServerFailure, nil
+	})
+
+	aQueries := dnsQueries.WithLabelValues("A")
+	aaaaQueries := dnsQueries.WithLabelValues("AAAA")
+	otherQueries := dnsQueries.WithLabelValues(otherLabel)
+	nxDomains := dnsResponses.WithLabelValues("NXDOMAIN")
+	servFails := dnsResponses.WithLabelValues("SERVFAIL")
+	initialA, initialAAAA, initialOther := counterValue(t, aQueries), counterValue(t, aaaaQueries), counterValue(t, otherQueries)
+	initialNXDomain, initialServFail := counterValue(t, nxDomains), counterValue(t, servFails)
+
+	query := func(handler plugin.Handler, qtype uint16) {
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", qtype)
+		_, _ = NewMetricsPlugin(handler).ServeDNS(context.Background(), &mockResponseWriter{}, req)
+	}
+	query(answer, dns.TypeA)
+	query(answer, dns.TypeAAAA)
+	query(fail, dns.TypeA)
+	query(answer, 65000)
+
+	require.Equal(t, initialA+2, counterValue(t, aQueries))
+	require.Equal(t, initialAAAA+1, counterValue(t, aaaaQueries))
+	require.Equal(t, initialOther+1, counterValue(t, otherQueries))
+	require.Equal(t, initialNXDomain+3, counterValue(t, nxDomains))
+	require.Equal(t, initialServFail+1, counterValue(t, servFails))
+}
@@ -0,0 +1,130 @@
+package tunneldns
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// clientIdleTimeout is how long a client's bucket is kept around after its last query
+	clientIdleTimeout = 5 * time.Minute
+)
+
+var rateLimitedQueries = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "cloudflared",
+		Subsystem: "dns",
+		Name:      "rate_limited_queries_total",
+		Help:      "Count of DNS queries refused because the client exceeded the max queries per second",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(rateLimitedQueries)
+}
+
+// tokenBucket allows up to burst queries at once, refilled at rate tokens per second.
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimitPlugin is a CoreDNS plugin that refuses queries from clients exceeding a queries-per-second budget.
+type RateLimitPlugin struct {
+	Next plugin.Handler
+
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	lock        sync.Mutex
+	clients     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+// NewRateLimitPlugin creates a plugin that allows maxQPS queries per second per client IP, with bursts of up to
+// burst queries. If burst is lower than maxQPS, maxQPS is used as the burst.
+func NewRateLimitPlugin(next plugin.Handler, maxQPS, burst int) *RateLimitPlugin {
+	if burst < maxQPS {
+		burst = maxQPS
+	}
+	return &RateLimitPlugin{
+		Next:    next,
+		rate:    float64(maxQPS),
+		burst:   float64(burst),
+		now:     time.Now,
+		clients: make(map[string]*tokenBucket),
+	}
+}
+
+// ServeDNS implements the CoreDNS plugin interface
+func (p *RateLimitPlugin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	if !p.allow(clientIP(w.RemoteAddr())) {
+		rateLimitedQueries.Inc()
+		// The server writes the REFUSED response for us, see plugin.ClientWrite
+		return dns.RcodeRefused, nil
+	}
+	return plugin.NextOrFailure(p.Name(), p.Next, ctx, w, r)
+}
+
+// Name implements the CoreDNS plugin interface
+func (p *RateLimitPlugin) Name() string { return "ratelimit" }
+
+func (p *RateLimitPlugin) allow(client string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	p.removeIdleClients(now)
+
+	bucket, ok := p.clients[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: p.burst}
+		p.clients[client] = bucket
+	} else {
+		bucket.tokens += now.Sub(bucket.lastSeen).Seconds() * p.rate
+		if bucket.tokens > p.burst {
+			bucket.tokens = p.burst
+		}
+	}
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// removeIdleClients drops buckets of clients that have not sent queries recently, so that the map doesn't grow
+// unbounded. Must be called with the lock held.
+func (p *RateLimitPlugin) removeIdleClients(now time.Time) {
+	if now.Sub(p.lastCleanup) < clientIdleTimeout {
+		return
+	}
+	for client, bucket := range p.clients {
+		if now.Sub(bucket.lastSeen) >= clientIdleTimeout {
+			delete(p.clients, client)
+		}
+	}
+	p.lastCleanup = now
+}
+
+func clientIP(addr net.Addr) string {
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return addr.IP.String()
+	case *net.TCPAddr:
+		return addr.IP.String()
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
@@ -0,0 +1,88 @@
+package tunneldns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitPluginAllow(t *testing.T) {
+	now := time.Now()
+	p := NewRateLimitPlugin(nil, 2, 4)
+	p.now = func() time.Time { return now }
+
+	// The burst is available right away
+	for i := 0; i < 4; i++ {
+		require.True(t, p.allow("192.0.2.1"))
+	}
+	require.False(t, p.allow("192.0.2.1"))
+
+	// Other clients have their own budget
+	require.True(t, p.allow("192.0.2.2"))
+
+	// Tokens refill at the configured rate
+	now = now.Add(500 * time.Millisecond)
+	require.True(t, p.allow("192.0.2.1"))
+	require.False(t, p.allow("192.0.2.1"))
+
+	// The bucket never holds more than the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 4; i++ {
+		require.True(t, p.allow("192.0.2.1"))
+	}
+	require.False(t, p.allow("192.0.2.1"))
+}
+
+func TestRateLimitPluginRemovesIdleClients(t *testing.T) {
+	now := time.Now()
+	p := NewRateLimitPlugin(nil, 1, 1)
+	p.now = func() time.Time { return now }
+
+	require.True(t, p.allow("192.0.2.1"))
+	now = now.Add(clientIdleTimeout)
+	require.True(t, p.allow("192.0.2.2"))
+	require.Len(t, p.clients, 1)
+}
+
+func TestRateLimitPluginRefuses(t *testing.T) {
+	next := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+	p := NewRateLimitPlugin(next, 1, 1)
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+
+	rcode, err := p.ServeDNS(context.Background(), &mockResponseWriter{}, req)
+	require.NoError(t, err)
+	require.Equal(t, dns.RcodeSuccess, rcode)
+
+	rcode, err = p.ServeDNS(context.Background(), &mockResponseWriter{}, req)
+	require.NoError(t, err)
+	require.Equal(t, dns.RcodeRefused, rcode)
+}
+
+type mockResponseWriter struct {
+	dns.ResponseWriter
+}
+
+func (w *mockResponseWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 40212}
+}
+
+func (w *mockResponseWriter) WriteMsg(*dns.Msg) error {
+	return nil
+}
+
+func TestClientIP(t *testing.T) {
+	require.Equal(t, "10.0.0.1", clientIP(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 53}))
+	require.Equal(t, "2001:db8::1", clientIP(&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 53}))
+}
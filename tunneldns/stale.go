@@ -0,0 +1,178 @@
+package tunneldns
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// staleTTL is the TTL of stale answers, as recommended by RFC 8767, so that clients ask again soon
+	staleTTL = 30
+	// maxStaleEntries bounds the number of answers kept to be served stale
+	maxStaleEntries = 10000
+)
+
+var staleAnswers = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "cloudflared",
+		Subsystem: "dns",
+		Name:      "stale_answers_total",
+		Help:      "Count of expired answers served because none of the upstreams could be reached",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(staleAnswers)
+}
+
+type staleEntry struct {
+	reply   *dns.Msg
+	expires time.Time
+}
+
+// ServeStalePlugin is a CoreDNS plugin that keeps the last answer of each question, and serves it once it expired
+// when none of the upstreams can be reached, instead of failing the query (RFC 8767).
+type ServeStalePlugin struct {
+	Next plugin.Handler
+
+	maxStale time.Duration
+	now      func() time.Time
+	log      *zerolog.Logger
+	// serving is whether the last query was answered stale, to only log when upstreams go down and come back
+	serving atomic.Bool
+
+	lock    sync.Mutex
+	entries map[string]staleEntry
+}
+
+// NewServeStalePlugin creates a plugin that serves answers up to maxStale after they expired.
+func NewServeStalePlugin(next plugin.Handler, maxStale time.Duration, log *zerolog.Logger) *ServeStalePlugin {
+	return &ServeStalePlugin{
+		Next:     next,
+		maxStale: maxStale,
+		now:      time.Now,
+		log:      log,
+		entries:  make(map[string]staleEntry),
+	}
+}
+
+// ServeDNS implements the CoreDNS plugin interface
+func (p *ServeStalePlugin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	if len(r.Question) != 1 {
+		return plugin.NextOrFailure(p.Name(), p.Next, ctx, w, r)
+	}
+	key := staleKey(r.Question[0])
+	recorder := &replyRecorder{ResponseWriter: w}
+	rcode, err := plugin.NextOrFailure(p.Name(), p.Next, ctx, recorder, r)
+	if recorder.reply != nil {
+		if p.serving.CompareAndSwap(true, false) {
+			p.log.Info().Msg("DNS upstreams are reachable again, no longer answering from stale cache")
+		}
+		p.store(key, recorder.reply)
+		return rcode, err
+	}
+
+	reply, ok := p.lookup(key)
+	if !ok {
+		return rcode, err
+	}
+	if p.serving.CompareAndSwap(false, true) {
+		p.log.Warn().Err(err).Msgf("Failed to contact any of the DNS upstreams, answering from cache up to %s after expiry", p.maxStale)
+	}
+	p.log.Debug().Str("question", key).Msg("Answering DNS query with a stale cached answer")
+	staleAnswers.Inc()
+	reply.Id = r.Id
+	_ = w.WriteMsg(reply)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the CoreDNS plugin interface
+func (p *ServeStalePlugin) Name() string { return "servestale" }
+
+func (p *ServeStalePlugin) store(key string, reply *dns.Msg) {
+	if reply.Truncated || (reply.Rcode != dns.RcodeSuccess && reply.Rcode != dns.RcodeNameError) {
+		return
+	}
+	now := p.now()
+	entry := staleEntry{reply: reply.Copy(), expires: now.Add(time.Duration(minTTL(reply)) * time.Second)}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.entries[key]; !ok && len(p.entries) >= maxStaleEntries {
+		p.evict(now)
+	}
+	p.entries[key] = entry
+}
+
+// evict removes the entries that are too old to be served, or an arbitrary one if there are none.
+func (p *ServeStalePlugin) evict(now time.Time) {
+	for key, entry := range p.entries {
+		if now.Sub(entry.expires) > p.maxStale {
+			delete(p.entries, key)
+		}
+	}
+	if len(p.entries) < maxStaleEntries {
+		return
+	}
+	for key := range p.entries {
+		delete(p.entries, key)
+		return
+	}
+}
+
+// lookup returns a copy of the answer to the question of key, with the TTLs set to staleTTL, if it isn't too old.
+func (p *ServeStalePlugin) lookup(key string) (*dns.Msg, bool) {
+	p.lock.Lock()
+	entry, ok := p.entries[key]
+	p.lock.Unlock()
+	if !ok || p.now().Sub(entry.expires) > p.maxStale {
+		return nil, false
+	}
+	reply := entry.reply.Copy()
+	for _, section := range [][]dns.RR{reply.Answer, reply.Ns, reply.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				rr.Header().Ttl = staleTTL
+			}
+		}
+	}
+	return reply, true
+}
+
+func staleKey(q dns.Question) string {
+	return strings.ToLower(q.Name) + " " + dns.Class(q.Qclass).String() + " " + dns.Type(q.Qtype).String()
+}
+
+// minTTL returns the lowest TTL of the records of the reply, 0 if it has none.
+func minTTL(reply *dns.Msg) uint32 {
+	var ttl uint32
+	first := true
+	for _, section := range [][]dns.RR{reply.Answer, reply.Ns} {
+		for _, rr := range section {
+			if first || rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+				first = false
+			}
+		}
+	}
+	return ttl
+}
+
+// replyRecorder is a dns.ResponseWriter that remembers the reply written to the client.
+type replyRecorder struct {
+	dns.ResponseWriter
+	reply *dns.Msg
+}
+
+func (w *replyRecorder) WriteMsg(m *dns.Msg) error {
+	w.reply = m
+	return w.ResponseWriter.WriteMsg(m)
+}
@@ -0,0 +1,80 @@
+package tunneldns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type replyWriter struct {
+	mockResponseWriter
+	reply *dns.Msg
+}
+
+func (w *replyWriter) WriteMsg(m *dns.Msg) error {
+	w.reply = m
+	return nil
+}
+
+func TestServeStalePlugin(t *testing.T) {
+	upstreamDown := false
+	next := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		if upstreamDown {
+			return dns.RcodeServerFailure, errors.New("failed to contact any of the upstreams")
+		}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		rr, err := dns.NewRR("example.com. 60 IN A 192.0.2.1")
+		require.NoError(t, err)
+		m.Answer = append(m.Answer, rr)
+		_ = w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+	log := zerolog.Nop()
+	now := time.Now()
+	p := NewServeStalePlugin(next, time.Hour, &log)
+	p.now = func() time.Time { return now }
+
+	query := func(name string) (int, *dns.Msg, error) {
+		req := new(dns.Msg)
+		req.SetQuestion(name, dns.TypeA)
+		w := &replyWriter{}
+		rcode, err := p.ServeDNS(context.Background(), w, req)
+		if w.reply != nil {
+			require.Equal(t, req.Id, w.reply.Id)
+		}
+		return rcode, w.reply, err
+	}
+
+	rcode, reply, err := query("example.com.")
+	require.NoError(t, err)
+	require.Equal(t, dns.RcodeSuccess, rcode)
+	require.Equal(t, uint32(60), reply.Answer[0].Header().Ttl)
+
+	// The expired answer is served with a short TTL while the upstreams are down
+	upstreamDown = true
+	now = now.Add(30 * time.Minute)
+	rcode, reply, err = query("EXAMPLE.com.")
+	require.NoError(t, err)
+	require.Equal(t, dns.RcodeSuccess, rcode)
+	require.Equal(t, "192.0.2.1", reply.Answer[0].(*dns.A).A.String())
+	require.Equal(t, uint32(staleTTL), reply.Answer[0].Header().Ttl)
+
+	// Questions that were never answered still fail
+	rcode, reply, err = query("other.example.com.")
+	require.Error(t, err)
+	require.Equal(t, dns.RcodeServerFailure, rcode)
+	require.Nil(t, reply)
+
+	// Answers are not served once they are too old
+	now = now.Add(time.Hour)
+	rcode, _, err = query("example.com.")
+	require.Error(t, err)
+	require.Equal(t, dns.RcodeServerFailure, rcode)
+}
@@ -2,8 +2,10 @@ package tunneldns
 
 import (
 	"net"
+	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -77,13 +79,31 @@ func (l *Listener) Stop() error {
 	return nil
 }
 
-// CreateListener configures the server and bound sockets
-func CreateListener(address string, port uint16, upstreams []string, bootstraps []string, maxUpstreamConnections int, log *zerolog.Logger) (*Listener, error) {
+// ListenerConfig configures the DNS over HTTPS proxy server of CreateListener.
+type ListenerConfig struct {
+	Address    string
+	Port       uint16
+	Upstreams  []string
+	Bootstraps []string
+	// UpstreamHeaders are added to every request to the upstreams, e.g. to authenticate with a private resolver.
+	UpstreamHeaders        http.Header
+	MaxUpstreamConnections int
+	// If MaxQPS is greater than 0, queries from each client IP are limited to MaxQPS per second with bursts of up to
+	// MaxQPSBurst, and queries over the limit are answered with REFUSED.
+	MaxQPS      int
+	MaxQPSBurst int
+	// If ServeStale is greater than 0, answers are served up to ServeStale after they expired when none of the
+	// upstreams can be reached.
+	ServeStale time.Duration
+}
+
+// CreateListener configures the server and bound sockets.
+func CreateListener(cfg ListenerConfig, log *zerolog.Logger) (*Listener, error) {
 	// Build the list of upstreams
 	upstreamList := make([]Upstream, 0)
-	for _, url := range upstreams {
+	for _, url := range cfg.Upstreams {
 		log.Info().Str(LogFieldURL, url).Msg("Adding DNS upstream")
-		upstream, err := NewUpstreamHTTPS(url, bootstraps, maxUpstreamConnections, log)
+		upstream, err := NewUpstreamHTTPS(url, cfg.UpstreamHeaders, cfg.Bootstraps, cfg.MaxUpstreamConnections, log)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create HTTPS upstream")
 		}
@@ -95,12 +115,22 @@ func CreateListener(address string, port uint16, upstreams []string, bootstraps
 	chain.Next = ProxyPlugin{
 		Upstreams: upstreamList,
 	}
+	if cfg.ServeStale > 0 {
+		log.Info().Dur("maxStale", cfg.ServeStale).Msg("Serving stale DNS answers when the upstreams are unreachable")
+		chain.Next = NewServeStalePlugin(chain.Next, cfg.ServeStale, log)
+	}
+
+	var handler plugin.Handler = chain
+	if cfg.MaxQPS > 0 {
+		log.Info().Int("maxQPS", cfg.MaxQPS).Int("burst", cfg.MaxQPSBurst).Msg("Limiting DNS queries per client")
+		handler = NewRateLimitPlugin(chain, cfg.MaxQPS, cfg.MaxQPSBurst)
+	}
 
 	// Format an endpoint
-	endpoint := "dns://" + net.JoinHostPort(address, strconv.FormatUint(uint64(port), 10))
+	endpoint := "dns://" + net.JoinHostPort(cfg.Address, strconv.FormatUint(uint64(cfg.Port), 10))
 
 	// Create the actual middleware server
-	server, err := dnsserver.NewServer(endpoint, []*dnsserver.Config{createConfig(address, port, NewMetricsPlugin(chain))})
+	server, err := dnsserver.NewServer(endpoint, []*dnsserver.Config{createConfig(cfg.Address, cfg.Port, NewMetricsPlugin(handler))})
 	if err != nil {
 		return nil, err
 	}